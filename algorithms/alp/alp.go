@@ -0,0 +1,303 @@
+// Package alp 实现 ALP (Adaptive Lossless floating-Point, SIGMOD 2024)。
+//
+// 数据按 1024 个值一个向量编码。十进制来源的浮点数用 ALP：v*10^e*10^-f 取整为整数，
+// 能按位还原的值做 frame-of-reference 位打包，其余作为异常保存原值；
+// 真正的实数（如传感器原始读数）用 ALP_rd：高位查字典，低位直接打包。
+// 每个行组（100 个向量）先抽样选出最常用的几组 (e, f)，每个向量再从中挑选。
+package alp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	vectorSize   = 1024
+	rowGroupSize = 100 * vectorSize
+
+	maxExponent = 18
+	// 每个行组保留的候选 (e, f) 个数
+	maxCombinations = 5
+	// 抽样时每个向量取的值个数，行组内抽样的向量个数
+	samplesPerVector  = 32
+	samplesPerRowGrp  = 8
+	exceptionPosBytes = 2
+)
+
+// 向量编码方式
+const (
+	schemeALP byte = 0
+	schemeRD  byte = 1
+)
+
+// 取整魔数：|x| < 2^51 时 (x + sweet) - sweet 等于 x 四舍五入到整数
+const sweet = float64(1<<52 + 1<<51)
+
+// encodeLimit 超出该范围的缩放值按异常处理
+const encodeLimit = 1 << 51
+
+var exp10 = func() []float64 {
+	p := make([]float64, maxExponent+1)
+	p[0] = 1
+	for i := 1; i < len(p); i++ {
+		p[i] = p[i-1] * 10
+	}
+	return p
+}()
+
+var frac10 = func() []float64 {
+	p := make([]float64, maxExponent+1)
+	for i := range p {
+		p[i] = math.Pow10(-i)
+	}
+	return p
+}()
+
+// combination 指数 e 和因子 f，f <= e
+type combination struct{ e, f int }
+
+func encodeValue(v float64, c combination) (int64, bool) {
+	x := v * exp10[c.e] * frac10[c.f]
+	if !(math.Abs(x) < encodeLimit) {
+		return 0, false
+	}
+	k := int64((x + sweet) - sweet)
+	return k, math.Float64bits(decodeValue(k, c)) == math.Float64bits(v)
+}
+
+func decodeValue(k int64, c combination) float64 {
+	return float64(k) * exp10[c.f] * frac10[c.e]
+}
+
+// estimateSize 用组合 c 编码 values 的估计位数：位打包宽度加异常开销
+func estimateSize(values []float64, c combination) int {
+	lo, hi := int64(math.MaxInt64), int64(math.MinInt64)
+	exceptions := 0
+	for _, v := range values {
+		k, ok := encodeValue(v, c)
+		if !ok {
+			exceptions++
+			continue
+		}
+		lo, hi = min(lo, k), max(hi, k)
+	}
+	width := 0
+	if exceptions < len(values) {
+		width = bits.Len64(uint64(hi - lo))
+	}
+	return width*len(values) + exceptions*(64+8*exceptionPosBytes)
+}
+
+// sample 从 values 中等间隔取不超过 n 个值
+func sample(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	out := make([]float64, n)
+	step := float64(len(values)) / float64(n)
+	for i := range out {
+		out[i] = values[int(float64(i)*step)]
+	}
+	return out
+}
+
+// bestCombination 在 candidates 中找估计大小最小的组合，连续两次变差时提前结束
+func bestCombination(values []float64, candidates []combination) (combination, int) {
+	best, bestSize := candidates[0], math.MaxInt
+	worse := 0
+	for _, c := range candidates {
+		size := estimateSize(values, c)
+		if size < bestSize {
+			best, bestSize, worse = c, size, 0
+		} else if worse++; worse >= 2 {
+			break
+		}
+	}
+	return best, bestSize
+}
+
+// allCombinations 第一级抽样的搜索空间，按 e、f 从大到小排列，估计大小相同时优先较大的指数
+var allCombinations = func() []combination {
+	var cs []combination
+	for e := maxExponent; e >= 0; e-- {
+		for f := e; f >= 0; f-- {
+			cs = append(cs, combination{e, f})
+		}
+	}
+	return cs
+}()
+
+// rowGroupCombinations 第一级抽样：对行组内若干向量的样本穷举 (e, f)，返回出现次数最多的组合
+func rowGroupCombinations(rowGroup []float64) []combination {
+	count := make(map[combination]int)
+	vectors := (len(rowGroup) + vectorSize - 1) / vectorSize
+	step := max(vectors/samplesPerRowGrp, 1)
+	for i := 0; i < vectors; i += step {
+		vector := rowGroup[i*vectorSize : min((i+1)*vectorSize, len(rowGroup))]
+		bestSize := math.MaxInt
+		var best combination
+		for _, c := range allCombinations {
+			if size := estimateSize(sample(vector, samplesPerVector), c); size < bestSize {
+				best, bestSize = c, size
+			}
+		}
+		count[best]++
+	}
+	cs := make([]combination, 0, len(count))
+	for c := range count {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		a, b := cs[i], cs[j]
+		if count[a] != count[b] {
+			return count[a] > count[b]
+		}
+		if a.e != b.e {
+			return a.e > b.e
+		}
+		return a.f > b.f
+	})
+	return cs[:min(len(cs), maxCombinations)]
+}
+
+// CompressFloat 格式：uvarint 值个数，之后每个向量为 1 字节编码方式加对应的数据
+func CompressFloat(dst []byte, src []float64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	for g := 0; g < len(src); g += rowGroupSize {
+		rowGroup := src[g:min(g+rowGroupSize, len(src))]
+		candidates := rowGroupCombinations(rowGroup)
+		for i := 0; i < len(rowGroup); i += vectorSize {
+			dst = appendVector(dst, rowGroup[i:min(i+vectorSize, len(rowGroup))], candidates)
+		}
+	}
+	return dst
+}
+
+// appendVector 用 ALP 编码向量；异常过多时改用 ALP_rd，保留较小的结果
+func appendVector(dst []byte, vector []float64, candidates []combination) []byte {
+	c, _ := bestCombination(sample(vector, samplesPerVector), candidates)
+	alp, exceptions := appendALP([]byte{schemeALP}, vector, c)
+	if exceptions > len(vector)/8 {
+		if rd := appendRD([]byte{schemeRD}, vector); len(rd) < len(alp) {
+			return append(dst, rd...)
+		}
+	}
+	return append(dst, alp...)
+}
+
+// appendALP 格式：e、f、位宽各 1 字节，uvarint(zigzag) 基准值，位打包的差值，
+// uvarint 异常个数，每个异常 2 字节下标和 8 字节原值。返回异常个数
+func appendALP(dst []byte, vector []float64, c combination) ([]byte, int) {
+	encoded := make([]int64, len(vector))
+	var excPos []int
+	fill, filled := int64(0), false
+	for i, v := range vector {
+		k, ok := encodeValue(v, c)
+		if !ok {
+			excPos = append(excPos, i)
+			continue
+		}
+		encoded[i] = k
+		if !filled {
+			fill, filled = k, true
+		}
+	}
+	// 异常位置填入第一个正常值，避免扩大位宽
+	for _, i := range excPos {
+		encoded[i] = fill
+	}
+	lo, hi := fill, fill
+	for _, k := range encoded {
+		lo, hi = min(lo, k), max(hi, k)
+	}
+	width := bits.Len64(uint64(hi - lo))
+	deltas := make([]uint64, len(encoded))
+	for i, k := range encoded {
+		deltas[i] = uint64(k - lo)
+	}
+
+	dst = append(dst, byte(c.e), byte(c.f), byte(width))
+	dst = binary.AppendVarint(dst, lo)
+	dst = appendPacked(dst, deltas, width)
+	dst = binary.AppendUvarint(dst, uint64(len(excPos)))
+	for _, i := range excPos {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(i))
+		dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(vector[i]))
+	}
+	return dst, len(excPos)
+}
+
+// DecompressFloat 还原 CompressFloat
+func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 {
+		return nil, fmt.Errorf("alp: invalid header")
+	}
+	src = src[m:]
+	vector := make([]float64, vectorSize)
+	var err error
+	for remaining := int(n); remaining > 0; remaining -= vectorSize {
+		if len(src) == 0 {
+			return nil, fmt.Errorf("alp: missing vector, %d values remaining", remaining)
+		}
+		vector = vector[:min(remaining, vectorSize)]
+		switch scheme := src[0]; scheme {
+		case schemeALP:
+			src, err = readALP(vector, src[1:])
+		case schemeRD:
+			src, err = readRD(vector, src[1:])
+		default:
+			err = fmt.Errorf("alp: unknown vector scheme %d", scheme)
+		}
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, vector...)
+	}
+	return dst, nil
+}
+
+func readALP(vector []float64, src []byte) ([]byte, error) {
+	if len(src) < 3 {
+		return nil, fmt.Errorf("alp: vector header is truncated")
+	}
+	c := combination{int(src[0]), int(src[1])}
+	width := int(src[2])
+	if c.e > maxExponent || c.f > c.e || width > 64 {
+		return nil, fmt.Errorf("alp: invalid vector header %v width %d", c, width)
+	}
+	lo, m := binary.Varint(src[3:])
+	if m <= 0 {
+		return nil, fmt.Errorf("alp: invalid frame of reference")
+	}
+	deltas := make([]uint64, len(vector))
+	src, err := readPacked(deltas, src[3+m:], width)
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range deltas {
+		vector[i] = decodeValue(lo+int64(d), c)
+	}
+	return readExceptions(vector, src)
+}
+
+// readExceptions 读取异常并写回原值
+func readExceptions(vector []float64, src []byte) ([]byte, error) {
+	count, m := binary.Uvarint(src)
+	if m <= 0 || count > uint64(len(vector)) || uint64(len(src)-m) < count*(exceptionPosBytes+8) {
+		return nil, fmt.Errorf("alp: invalid exceptions")
+	}
+	src = src[m:]
+	for j := uint64(0); j < count; j++ {
+		i := int(binary.LittleEndian.Uint16(src))
+		if i >= len(vector) {
+			return nil, fmt.Errorf("alp: exception position %d out of range", i)
+		}
+		vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(src[exceptionPosBytes:]))
+		src = src[exceptionPosBytes+8:]
+	}
+	return src, nil
+}
@@ -0,0 +1,124 @@
+package alp
+
+import (
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// decimals n 个 digits 位小数的值
+func decimals(n, digits int) []float64 {
+	data := make([]float64, n)
+	scale := math.Pow10(digits)
+	for i := range data {
+		data[i] = math.Round((20+math.Sin(float64(i)/10))*scale) / scale
+	}
+	return data
+}
+
+func TestCompressFloat(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloat, DecompressFloat, c.Data)
+	}
+}
+
+// 最后一个向量不满 1024 个值，或恰好填满
+func TestVectorBoundaries(t *testing.T) {
+	for _, n := range []int{vectorSize - 1, vectorSize, vectorSize + 1, 2*vectorSize + 1} {
+		codectest.RoundTripFloats(t, "decimal", CompressFloat, DecompressFloat, decimals(n, 2))
+	}
+}
+
+// 两位小数的数据用 ALP 编码，每个值不到 2 字节
+func TestCompressFloatDecimal(t *testing.T) {
+	data := make([]float64, vectorSize)
+	for i := range data {
+		data[i] = float64(i%500) / 100
+	}
+	out := codectest.RoundTripFloats(t, "decimal", CompressFloat, DecompressFloat, data)
+	if len(out) >= len(data)*2 {
+		t.Errorf("%d values compressed into %d bytes", len(data), len(out))
+	}
+	// uvarint(1024) 占 2 字节，之后是第一个向量的编码方式
+	if out[2] != schemeALP {
+		t.Errorf("vector scheme %d, expected ALP", out[2])
+	}
+}
+
+// 不能按 (e, f) 还原的值作为异常保存：-0 编码为 0 后还原成 +0，NaN 和 1/3 没有有限小数表示，
+// 缩放后超出 encodeLimit 的值不能取整。异常位于向量首尾时下标和填充值都要正确
+func TestExceptions(t *testing.T) {
+	vector := decimals(vectorSize, 2)
+	// 选一个能无异常编码原数据的组合
+	var c combination
+	for _, c = range allCombinations {
+		if _, n := appendALP(nil, vector, c); n == 0 && c.e >= 2 {
+			break
+		}
+	}
+	planted := map[int]float64{
+		0:              math.Copysign(0, -1),
+		1:              math.NaN(),
+		500:            1.0 / 3,
+		501:            encodeLimit,
+		vectorSize - 1: math.Inf(-1),
+	}
+	for i, v := range planted {
+		vector[i] = v
+	}
+	out, exceptions := appendALP(nil, vector, c)
+	if exceptions != len(planted) {
+		t.Errorf("%d exceptions with %v, expected %d", exceptions, c, len(planted))
+	}
+	got := make([]float64, len(vector))
+	rest, err := readALP(got, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes left after the vector", len(rest))
+	}
+	codectest.EqualFloats(t, "exceptions", got, vector)
+
+	// 全是异常时没有正常值可填充，位宽为 0
+	nans := []float64{math.NaN(), math.NaN(), math.Copysign(0, -1)}
+	out, exceptions = appendALP(nil, nans, c)
+	if exceptions != len(nans) || out[2] != 0 {
+		t.Errorf("%d exceptions, width %d", exceptions, out[2])
+	}
+	got = make([]float64, len(nans))
+	if _, err := readALP(got, out); err != nil {
+		t.Fatal(err)
+	}
+	codectest.EqualFloats(t, "all exceptions", got, nans)
+}
+
+// 每个行组重新选择 (e, f)：第二个行组的小数位数不同也能还原，且不退化成 ALP_rd
+func TestRowGroups(t *testing.T) {
+	data := append(decimals(rowGroupSize, 2), decimals(2*vectorSize, 5)...)
+	out := codectest.RoundTripFloats(t, "row groups", CompressFloat, DecompressFloat, data)
+	if len(out) >= len(data)*4 {
+		t.Errorf("%d values compressed into %d bytes", len(data), len(out))
+	}
+}
+
+// ALP_rd 直接编码随机位模式
+func TestRD(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	vector := make([]float64, vectorSize)
+	for i := range vector {
+		vector[i] = math.Float64frombits(rng.Uint64())
+	}
+	vector[0], vector[1], vector[2] = math.NaN(), math.Inf(-1), math.Copysign(0, -1)
+	out := appendRD(nil, vector)
+	got := make([]float64, len(vector))
+	rest, err := readRD(got, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes left after the vector", len(rest))
+	}
+	codectest.EqualFloats(t, "rd", got, vector)
+}
@@ -0,0 +1,68 @@
+package alp
+
+import "fmt"
+
+// appendPacked 将 src 中每个值的低 width 位依次打包（小端位序），按字节对齐结束
+func appendPacked(dst []byte, src []uint64, width int) []byte {
+	if width == 0 {
+		return dst
+	}
+	var acc uint64
+	nbits := 0
+	for _, v := range src {
+		if width < 64 {
+			v &= 1<<width - 1
+		}
+		acc |= v << nbits
+		if nbits+width >= 64 {
+			// acc 已满 64 位，写出后保留 v 中未写出的高位
+			for i := 0; i < 8; i++ {
+				dst = append(dst, byte(acc>>(8*i)))
+			}
+			used := 64 - nbits
+			if used < 64 {
+				acc = v >> used
+			} else {
+				acc = 0
+			}
+			nbits = nbits + width - 64
+		} else {
+			nbits += width
+		}
+	}
+	for ; nbits > 0; nbits -= 8 {
+		dst = append(dst, byte(acc))
+		acc >>= 8
+	}
+	return dst
+}
+
+// packedLen n 个 width 位的值打包后的字节数
+func packedLen(n, width int) int {
+	return (n*width + 7) / 8
+}
+
+// readPacked 还原 appendPacked，返回剩余的输入
+func readPacked(dst []uint64, src []byte, width int) ([]byte, error) {
+	size := packedLen(len(dst), width)
+	if len(src) < size {
+		return nil, fmt.Errorf("alp: packed data is truncated")
+	}
+	if width == 0 {
+		clear(dst)
+		return src, nil
+	}
+	pos := 0
+	for i := range dst {
+		var v uint64
+		for got := 0; got < width; {
+			b := uint64(src[pos/8]) >> (pos % 8)
+			take := min(8-pos%8, width-got)
+			v |= (b & (1<<take - 1)) << got
+			got += take
+			pos += take
+		}
+		dst[i] = v
+	}
+	return src[size:], nil
+}
@@ -0,0 +1,149 @@
+package alp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// ALP_rd：把位模式切成左右两部分，右边 rightWidth 位直接打包，
+// 左边的高位取值通常很少，用最多 8 项的字典编码，字典外的值作为异常
+const (
+	maxLeftWidth = 16 // 左半部分最多 16 位
+	maxDictSize  = 8
+	dictWidth    = 3
+)
+
+// rdParams 一种切分方式及其字典
+type rdParams struct {
+	rightWidth int
+	dict       []uint16
+}
+
+// chooseRD 在样本上尝试每种切分，选出估计大小最小的
+func chooseRD(values []float64) rdParams {
+	best, bestSize := rdParams{rightWidth: 64 - maxLeftWidth}, math.MaxInt
+	for left := 1; left <= maxLeftWidth; left++ {
+		p := rdParams{rightWidth: 64 - left}
+		count := make(map[uint16]int)
+		for _, v := range values {
+			count[uint16(math.Float64bits(v)>>p.rightWidth)]++
+		}
+		for k := range count {
+			p.dict = append(p.dict, k)
+		}
+		sort.Slice(p.dict, func(i, j int) bool {
+			a, b := p.dict[i], p.dict[j]
+			if count[a] != count[b] {
+				return count[a] > count[b]
+			}
+			return a < b
+		})
+		p.dict = p.dict[:min(len(p.dict), maxDictSize)]
+		exceptions := len(values)
+		for _, k := range p.dict {
+			exceptions -= count[k]
+		}
+		size := len(values)*(p.rightWidth+p.indexWidth()) + exceptions*(16+8*exceptionPosBytes)
+		if size < bestSize {
+			best, bestSize = p, size
+		}
+	}
+	return best
+}
+
+// indexWidth 字典下标的位宽
+func (p rdParams) indexWidth() int {
+	if len(p.dict) <= 1 {
+		return 0
+	}
+	return min(bits.Len(uint(len(p.dict)-1)), dictWidth)
+}
+
+// appendRD 格式：右半部分位宽、字典大小各 1 字节，字典项各 2 字节，位打包的字典下标和右半部分，
+// uvarint 异常个数，每个异常 2 字节下标和 2 字节左半部分
+func appendRD(dst []byte, vector []float64) []byte {
+	p := chooseRD(sample(vector, 4*samplesPerVector))
+	index := make(map[uint16]uint64, len(p.dict))
+	for i, k := range p.dict {
+		index[k] = uint64(i)
+	}
+	idx := make([]uint64, len(vector))
+	right := make([]uint64, len(vector))
+	var excPos []int
+	var excLeft []uint16
+	for i, v := range vector {
+		b := math.Float64bits(v)
+		right[i] = b & (1<<p.rightWidth - 1)
+		left := uint16(b >> p.rightWidth)
+		k, ok := index[left]
+		if !ok {
+			excPos = append(excPos, i)
+			excLeft = append(excLeft, left)
+		}
+		idx[i] = k
+	}
+
+	dst = append(dst, byte(p.rightWidth), byte(len(p.dict)))
+	for _, k := range p.dict {
+		dst = binary.LittleEndian.AppendUint16(dst, k)
+	}
+	dst = appendPacked(dst, idx, p.indexWidth())
+	dst = appendPacked(dst, right, p.rightWidth)
+	dst = binary.AppendUvarint(dst, uint64(len(excPos)))
+	for j, i := range excPos {
+		dst = binary.LittleEndian.AppendUint16(dst, uint16(i))
+		dst = binary.LittleEndian.AppendUint16(dst, excLeft[j])
+	}
+	return dst
+}
+
+func readRD(vector []float64, src []byte) ([]byte, error) {
+	if len(src) < 2 {
+		return nil, fmt.Errorf("alp_rd: vector header is truncated")
+	}
+	p := rdParams{rightWidth: int(src[0])}
+	dictLen := int(src[1])
+	if p.rightWidth < 64-maxLeftWidth || p.rightWidth > 63 || dictLen > maxDictSize || len(src) < 2+2*dictLen {
+		return nil, fmt.Errorf("alp_rd: invalid vector header")
+	}
+	src = src[2:]
+	for i := 0; i < dictLen; i++ {
+		p.dict = append(p.dict, binary.LittleEndian.Uint16(src[2*i:]))
+	}
+	src = src[2*dictLen:]
+	idx := make([]uint64, len(vector))
+	right := make([]uint64, len(vector))
+	var err error
+	if src, err = readPacked(idx, src, p.indexWidth()); err != nil {
+		return nil, err
+	}
+	if src, err = readPacked(right, src, p.rightWidth); err != nil {
+		return nil, err
+	}
+	for i := range vector {
+		var left uint64
+		if idx[i] < uint64(len(p.dict)) {
+			left = uint64(p.dict[idx[i]])
+		}
+		vector[i] = math.Float64frombits(left<<p.rightWidth | right[i])
+	}
+
+	count, m := binary.Uvarint(src)
+	if m <= 0 || count > uint64(len(vector)) || uint64(len(src)-m) < count*(exceptionPosBytes+2) {
+		return nil, fmt.Errorf("alp_rd: invalid exceptions")
+	}
+	src = src[m:]
+	for j := uint64(0); j < count; j++ {
+		i := int(binary.LittleEndian.Uint16(src))
+		if i >= len(vector) {
+			return nil, fmt.Errorf("alp_rd: exception position %d out of range", i)
+		}
+		left := uint64(binary.LittleEndian.Uint16(src[exceptionPosBytes:]))
+		vector[i] = math.Float64frombits(left<<p.rightWidth | math.Float64bits(vector[i])&(1<<p.rightWidth-1))
+		src = src[exceptionPosBytes+2:]
+	}
+	return src, nil
+}
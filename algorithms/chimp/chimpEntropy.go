@@ -0,0 +1,154 @@
+package chimp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/common"
+)
+
+// Chimp 的熵编码变体：判定逻辑与 Compress 相同，2 位控制码、前导零档位和中间位长度交给自适应范围编码，
+// 控制码以上一个控制码为上下文；异或值的有效位仍按原样写入位流。
+// 格式：uvarint 值个数，uvarint 控制流长度，控制流，残差位流（首个值 64 位加各值的有效位）
+
+// 控制码，对应 Compress 中的两位标志
+const (
+	codeSame     = 0 // 00 与前一个值相同
+	codeTrailing = 1 // 01 尾部零多于 6 个，只写中间位
+	codeLeading  = 2 // 10 沿用上一个前导零个数
+	codeNewLead  = 3 // 11 新的前导零个数
+)
+
+// roundLeading 前导零个数截断到 15 以内的偶数
+func roundLeading(xor uint64) int {
+	leading := min(bits.LeadingZeros64(xor), 15)
+	return leading &^ 1
+}
+
+// CompressEntropy 熵编码控制码的 Compress，没有 14 位长度的限制
+func CompressEntropy(dst []byte, src []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst
+	}
+	var residual []byte
+	bs := &common.ByteWrapper{Stream: &residual, Count: 0}
+	bs.AppendBits(src[0], 64)
+
+	enc := rangeCoding.NewEncoder(nil)
+	codes := rangeCoding.NewSymbolModels(4, 2)
+	leadingModel, centerModel := rangeCoding.NewSymbolModel(3), rangeCoding.NewSymbolModel(6)
+	lastLeading, lastValue, prevCode := 0, src[0], codeSame
+	for _, v := range src[1:] {
+		xor := v ^ lastValue
+		leading, trailing := roundLeading(xor), bits.TrailingZeros64(xor)
+		var code int
+		switch {
+		case xor == 0:
+			code = codeSame
+		case trailing > 6:
+			code = codeTrailing
+		case leading == lastLeading:
+			code = codeLeading
+		default:
+			code = codeNewLead
+		}
+		enc.Encode(codes[prevCode], code)
+		prevCode = code
+		switch code {
+		case codeTrailing:
+			center := 64 - leading - trailing
+			enc.Encode(leadingModel, leading/2)
+			enc.Encode(centerModel, center)
+			bs.AppendBits(xor>>trailing, center)
+		case codeLeading:
+			bs.AppendBits(xor, 64-leading)
+		case codeNewLead:
+			enc.Encode(leadingModel, leading/2)
+			bs.AppendBits(xor, 64-leading)
+		}
+		lastLeading, lastValue = leading, v
+	}
+	control := enc.Finish()
+	dst = binary.AppendUvarint(dst, uint64(len(control)))
+	dst = append(dst, control...)
+	return append(dst, residual...)
+}
+
+// DecompressEntropy 还原 CompressEntropy，结果追加到 dst
+func DecompressEntropy(dst []uint64, src []byte) ([]uint64, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 {
+		return nil, fmt.Errorf("chimp: invalid entropy header")
+	}
+	if n == 0 {
+		return dst, nil
+	}
+	size, k := binary.Uvarint(src[m:])
+	if k <= 0 || size > uint64(len(src)-m-k) {
+		return nil, fmt.Errorf("chimp: invalid control stream length")
+	}
+	src = src[m+k:]
+	dec := rangeCoding.NewDecoder(src[:size])
+	// ByteWrapper 读取时会改写输入，先复制残差位流
+	residual := append([]byte(nil), src[size:]...)
+	bs := &common.ByteWrapper{Stream: &residual, Count: 8}
+	lastValue, err := bs.ReadBits(64)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, lastValue)
+
+	codes := rangeCoding.NewSymbolModels(4, 2)
+	leadingModel, centerModel := rangeCoding.NewSymbolModel(3), rangeCoding.NewSymbolModel(6)
+	lastLeading, prevCode := 0, codeSame
+	for i := uint64(1); i < n; i++ {
+		code := dec.Decode(codes[prevCode])
+		prevCode = code
+		var xor uint64
+		switch code {
+		case codeTrailing:
+			leading := dec.Decode(leadingModel) * 2
+			center := dec.Decode(centerModel)
+			if leading+center > 64 {
+				return nil, fmt.Errorf("chimp: invalid center length at value %d", i)
+			}
+			xor, err = bs.ReadBits(center)
+			xor <<= 64 - leading - center
+		case codeLeading:
+			xor, err = bs.ReadBits(64 - lastLeading)
+		case codeNewLead:
+			xor, err = bs.ReadBits(64 - dec.Decode(leadingModel)*2)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lastValue ^= xor
+		lastLeading = roundLeading(xor)
+		dst = append(dst, lastValue)
+	}
+	return dst, nil
+}
+
+// CompressFloatEntropy 熵编码控制码的 CompressFloat
+func CompressFloatEntropy(dst []byte, src []float64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
+	}
+	return CompressEntropy(dst, values)
+}
+
+// DecompressFloatEntropy 还原 CompressFloatEntropy
+func DecompressFloatEntropy(dst []float64, src []byte) ([]float64, error) {
+	values, err := DecompressEntropy(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range values {
+		dst = append(dst, math.Float64frombits(u))
+	}
+	return dst, nil
+}
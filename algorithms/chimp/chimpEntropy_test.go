@@ -0,0 +1,38 @@
+package chimp
+
+import (
+	"math"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompressEntropy(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloatEntropy, DecompressFloatEntropy, c.Data)
+	}
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, CompressEntropy, DecompressEntropy, c.Data)
+	}
+	// 只有一个特殊值，以及 NaN 和 ±Inf 交替，异或值的窗口反复变化
+	specials := []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Float64frombits(0x7ff8000000000001), math.Copysign(0, -1)}
+	var alternating []float64
+	for i := 0; i < 300; i++ {
+		alternating = append(alternating, specials[i%len(specials)])
+	}
+	for _, v := range specials {
+		codectest.RoundTripFloats(t, "one special", CompressFloatEntropy, DecompressFloatEntropy, []float64{v})
+	}
+	codectest.RoundTripFloats(t, "alternating", CompressFloatEntropy, DecompressFloatEntropy, alternating)
+}
+
+// 值个数超过原格式 14 位的上限；整数值的异或尾部零多于 6 个，只写中间位
+func TestEntropyLong(t *testing.T) {
+	data := make([]float64, 1<<14+100)
+	for i := range data {
+		data[i] = float64(i % 1000)
+	}
+	out := codectest.RoundTripFloats(t, "integers", CompressFloatEntropy, DecompressFloatEntropy, data)
+	if len(out) >= len(data)*4 {
+		t.Errorf("%d integers compressed into %d bytes", len(data), len(out))
+	}
+}
@@ -503,6 +503,27 @@ func CompressFloat(dst []byte, src []float64) []byte {
 	return append(dst, compressed...)
 }
 
+// CompressFloatBounded is CompressFloat that gives up as soon as the output grows past
+// limit bytes. It returns false in that case; otherwise the output equals CompressFloat's.
+func CompressFloatBounded(dst []byte, src []float64, limit int) ([]byte, bool) {
+	if len(src) == 0 {
+		return dst, true
+	}
+	chimp := NewChimpN(128)
+	for _, v := range src {
+		chimp.AddValueDouble(v)
+		// bytes already written never shrink, so they bound the final size from below
+		if chimp.out.bytePos > limit {
+			return nil, false
+		}
+	}
+	chimp.Close()
+	if compressed := chimp.GetOut(); len(compressed) <= limit {
+		return append(dst, compressed...), true
+	}
+	return nil, false
+}
+
 // DecompressFloat decompresses a byte array back to float64 array using Chimp128 algorithm
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
 	if len(src) == 0 {
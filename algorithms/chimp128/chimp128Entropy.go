@@ -0,0 +1,199 @@
+package chimp128
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/common"
+)
+
+// Chimp128 的熵编码变体：参考值的选择与 ChimpN 相同，2 位控制码、参考值距离、前导零档位和有效位长度
+// 交给自适应范围编码，控制码以上一个控制码为上下文；异或值的有效位仍按原样写入位流。
+// 参考值下标改为记录与当前位置的距离，近处的参考值更常用。
+// 格式：uvarint 值个数，uvarint 控制流长度，控制流，残差位流（首个值 64 位加各值的有效位）
+
+const (
+	entropyWindowLog2 = 7
+	entropyWindow     = 1 << entropyWindowLog2
+	entropyThreshold  = 6 + entropyWindowLog2
+	entropyKeyMask    = 1<<(entropyThreshold+1) - 1
+	// noLeading 表示没有可沿用的前导零个数
+	noLeading = 65
+)
+
+// 控制码，对应 ChimpN 中的两位标志
+const (
+	codeSame     = 0 // 与窗口中某个值相同
+	codeTrailing = 1 // 与窗口中某个值的异或尾部零足够多，只写中间位
+	codeLeading  = 2 // 沿用上一个前导零个数
+	codeNewLead  = 3 // 新的前导零个数
+)
+
+// CompressEntropy 熵编码控制码的 Chimp128
+func CompressEntropy(dst []byte, src []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst
+	}
+	var residual []byte
+	bs := &common.ByteWrapper{Stream: &residual, Count: 0}
+	bs.AppendBits(src[0], 64)
+
+	enc := rangeCoding.NewEncoder(nil)
+	codes := rangeCoding.NewSymbolModels(4, 2)
+	distModels := rangeCoding.NewSymbolModels(2, entropyWindowLog2)
+	leadingModel, sigModel := rangeCoding.NewSymbolModel(3), rangeCoding.NewSymbolModel(6)
+
+	var stored [entropyWindow]uint64
+	indices := make([]int, entropyKeyMask+1)
+	stored[0] = src[0]
+	indices[src[0]&entropyKeyMask] = 0
+	index, current, storedLeading, prevCode := 0, 0, noLeading, codeSame
+	for _, v := range src[1:] {
+		key := v & entropyKeyMask
+		ref := current
+		xor := stored[current] ^ v
+		trailing := 0
+		if last := indices[key]; index-last < entropyWindow {
+			tempXor := stored[last%entropyWindow] ^ v
+			if trailing = bits.TrailingZeros64(tempXor); trailing > entropyThreshold {
+				ref, xor = last%entropyWindow, tempXor
+			}
+		}
+
+		var code, leading int
+		if xor != 0 {
+			leading = int(leadingRound[bits.LeadingZeros64(xor)])
+		}
+		switch {
+		case xor == 0:
+			code = codeSame
+		case trailing > entropyThreshold:
+			code = codeTrailing
+		case leading == storedLeading:
+			code = codeLeading
+		default:
+			code = codeNewLead
+		}
+		enc.Encode(codes[prevCode], code)
+		prevCode = code
+		switch code {
+		case codeSame:
+			enc.Encode(distModels[code], (current-ref)&(entropyWindow-1))
+		case codeTrailing:
+			sig := 64 - leading - trailing
+			enc.Encode(distModels[code], (current-ref)&(entropyWindow-1))
+			enc.Encode(leadingModel, int(leadingRepresentation[leading]))
+			enc.Encode(sigModel, sig)
+			bs.AppendBits(xor>>trailing, sig)
+			storedLeading = noLeading
+		case codeLeading:
+			bs.AppendBits(xor, 64-leading)
+		case codeNewLead:
+			enc.Encode(leadingModel, int(leadingRepresentation[leading]))
+			bs.AppendBits(xor, 64-leading)
+			storedLeading = leading
+		}
+
+		current = (current + 1) % entropyWindow
+		stored[current] = v
+		index++
+		indices[key] = index
+	}
+	control := enc.Finish()
+	dst = binary.AppendUvarint(dst, uint64(len(control)))
+	dst = append(dst, control...)
+	return append(dst, residual...)
+}
+
+// DecompressEntropy 还原 CompressEntropy，结果追加到 dst
+func DecompressEntropy(dst []uint64, src []byte) ([]uint64, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 {
+		return nil, fmt.Errorf("chimp128: invalid entropy header")
+	}
+	if n == 0 {
+		return dst, nil
+	}
+	size, k := binary.Uvarint(src[m:])
+	if k <= 0 || size > uint64(len(src)-m-k) {
+		return nil, fmt.Errorf("chimp128: invalid control stream length")
+	}
+	src = src[m+k:]
+	dec := rangeCoding.NewDecoder(src[:size])
+	// ByteWrapper 读取时会改写输入，先复制残差位流
+	residual := append([]byte(nil), src[size:]...)
+	bs := &common.ByteWrapper{Stream: &residual, Count: 8}
+	first, err := bs.ReadBits(64)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, first)
+
+	codes := rangeCoding.NewSymbolModels(4, 2)
+	distModels := rangeCoding.NewSymbolModels(2, entropyWindowLog2)
+	leadingModel, sigModel := rangeCoding.NewSymbolModel(3), rangeCoding.NewSymbolModel(6)
+	var stored [entropyWindow]uint64
+	stored[0] = first
+	current, storedLeading, prevCode := 0, noLeading, codeSame
+	for i := uint64(1); i < n; i++ {
+		code := dec.Decode(codes[prevCode])
+		prevCode = code
+		var v uint64
+		switch code {
+		case codeSame:
+			v = stored[(current-dec.Decode(distModels[code]))&(entropyWindow-1)]
+		case codeTrailing:
+			ref := stored[(current-dec.Decode(distModels[code]))&(entropyWindow-1)]
+			leading := int(decompLeadingRepresentation[dec.Decode(leadingModel)])
+			sig := dec.Decode(sigModel)
+			if leading+sig > 64 {
+				return nil, fmt.Errorf("chimp128: invalid significant length at value %d", i)
+			}
+			xor, err := bs.ReadBits(sig)
+			if err != nil {
+				return nil, err
+			}
+			v = ref ^ xor<<(64-leading-sig)
+			storedLeading = noLeading
+		case codeLeading, codeNewLead:
+			if code == codeNewLead {
+				storedLeading = int(decompLeadingRepresentation[dec.Decode(leadingModel)])
+			} else if storedLeading == noLeading {
+				return nil, fmt.Errorf("chimp128: leading zeros reused before defined at value %d", i)
+			}
+			xor, err := bs.ReadBits(64 - storedLeading)
+			if err != nil {
+				return nil, err
+			}
+			v = stored[current] ^ xor
+		}
+		current = (current + 1) % entropyWindow
+		stored[current] = v
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+// CompressFloatEntropy 熵编码控制码的 CompressFloat
+func CompressFloatEntropy(dst []byte, src []float64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
+	}
+	return CompressEntropy(dst, values)
+}
+
+// DecompressFloatEntropy 还原 CompressFloatEntropy
+func DecompressFloatEntropy(dst []float64, src []byte) ([]float64, error) {
+	values, err := DecompressEntropy(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range values {
+		dst = append(dst, math.Float64frombits(u))
+	}
+	return dst, nil
+}
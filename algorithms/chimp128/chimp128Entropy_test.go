@@ -0,0 +1,48 @@
+package chimp128
+
+import (
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompressEntropy(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloatEntropy, DecompressFloatEntropy, c.Data)
+	}
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, CompressEntropy, DecompressEntropy, c.Data)
+	}
+	// 只有一个特殊值，以及 NaN 和 ±Inf 交替，异或值的窗口反复变化
+	specials := []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Float64frombits(0x7ff8000000000001), math.Copysign(0, -1)}
+	var alternating []float64
+	for i := 0; i < 300; i++ {
+		alternating = append(alternating, specials[i%len(specials)])
+	}
+	for _, v := range specials {
+		codectest.RoundTripFloats(t, "one special", CompressFloatEntropy, DecompressFloatEntropy, []float64{v})
+	}
+	codectest.RoundTripFloats(t, "alternating", CompressFloatEntropy, DecompressFloatEntropy, alternating)
+}
+
+// 与 128 个值之前相同的值还在窗口内，按距离引用；周期为 129 时参考值已移出窗口
+func TestEntropyWindow(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	block := make([]float64, entropyWindow+1)
+	for i := range block {
+		block[i] = math.Float64frombits(rng.Uint64())
+	}
+	size := func(period int) int {
+		var data []float64
+		for len(data) < 20*period {
+			data = append(data, block[:period]...)
+		}
+		return len(codectest.RoundTripFloats(t, "periodic", CompressFloatEntropy, DecompressFloatEntropy, data)) * period / len(data)
+	}
+	// 每个周期的字节数：窗口内只有第一个周期需要完整写出
+	inside, outside := size(entropyWindow), size(entropyWindow+1)
+	if inside > 4*entropyWindow || outside < 6*entropyWindow {
+		t.Errorf("%d bytes per period of %d, %d bytes per period of %d", inside, entropyWindow, outside, entropyWindow+1)
+	}
+}
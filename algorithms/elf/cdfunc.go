@@ -18,6 +18,27 @@ func CompressFloat(dst []byte, src []float64) []byte {
 	return append(dst, out...)
 }
 
+// CompressFloatBounded is CompressFloat that gives up as soon as the output grows past
+// limit bytes. It returns false in that case; otherwise the output equals CompressFloat's.
+func CompressFloatBounded(dst []byte, src []float64, limit int) ([]byte, bool) {
+	if len(src) == 0 {
+		return dst, true
+	}
+	c := NewCompressor()
+	for _, v := range src {
+		c.Add(v)
+		// bytes already written never shrink, so they bound the final size from below
+		if len(c.Bytes()) > limit {
+			return nil, false
+		}
+	}
+	c.Close()
+	if out := c.Bytes(); len(out) <= limit {
+		return append(dst, out...), true
+	}
+	return nil, false
+}
+
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
 	if len(src) == 0 {
 		return []float64{}, nil
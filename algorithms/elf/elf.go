@@ -24,6 +24,10 @@ func (c *Compressor) Add(v float64) {
 	} else if math.IsNaN(v) {
 		c.sizeBits += c.writeInt(2, 2)
 		vPrime = math.Float64bits(math.NaN()) // 0x7ff8...
+	} else if a := math.Abs(v); a < minErasable || a >= maxErasable {
+		// outside this range the significand search panics or never terminates, store as case 10
+		c.sizeBits += c.writeInt(2, 2)
+		vPrime = vLong
 	} else {
 		alpha, betaStar := GetAlphaAndBetaStar(v, c.lastBetaStar)
 		e := int((vLong >> 52) & 0x7ff)
@@ -0,0 +1,76 @@
+package elf
+
+import (
+	"fmt"
+	"math"
+)
+
+// Erasure is only attempted inside this magnitude range: above it the significand search
+// starts from a negative power of ten, below it v*10^i overflows to Inf and never becomes integral.
+const (
+	minErasable = 1e-250
+	maxErasable = 1e15
+	// maxBetaStar bounds beta* so it fits the 5-bit model used by Stage.
+	maxBetaStar = 31
+)
+
+// Eraser is the Elf erase step decoupled from ElfXORCompressor. Like Elf+, it passes the
+// last beta* into the significand search so runs of values with the same precision reuse it.
+type Eraser struct {
+	lastBetaStar int
+}
+
+func NewEraser() *Eraser {
+	return &Eraser{lastBetaStar: math.MaxInt32}
+}
+
+// Erase clears the trailing mantissa bits of v that Restore can rebuild from beta*.
+// It returns the bits of v unchanged and ok=false when erasing is not possible or would not be exact,
+// so the transform is lossless for every bit pattern including NaN payloads and -0.
+func (e *Eraser) Erase(v float64) (erased uint64, betaStar int, ok bool) {
+	vLong := math.Float64bits(v)
+	if a := math.Abs(v); !(a >= minErasable && a < maxErasable) {
+		return vLong, 0, false
+	}
+	alpha, betaStar := GetAlphaAndBetaStar(v, e.lastBetaStar)
+	if betaStar > maxBetaStar {
+		return vLong, 0, false
+	}
+	eraseBits := 52 - (GetFAlpha(alpha) + int(vLong>>52&0x7ff) - 1023)
+	if eraseBits <= 4 || eraseBits > 52 {
+		return vLong, 0, false
+	}
+	erased = vLong &^ (1<<uint(eraseBits) - 1)
+	if erased == vLong {
+		return vLong, 0, false
+	}
+	if r, err := Restore(erased, betaStar); err != nil || math.Float64bits(r) != vLong {
+		return vLong, 0, false
+	}
+	e.lastBetaStar = betaStar
+	return erased, betaStar, true
+}
+
+// Restore rebuilds the original value from its erased bits and beta*, like recoverVByBetaStar.
+func Restore(erased uint64, betaStar int) (float64, error) {
+	vPrime := math.Float64frombits(erased)
+	if vPrime == 0 || math.IsNaN(vPrime) || math.IsInf(vPrime, 0) {
+		return 0, fmt.Errorf("elf: cannot restore erased value %#x", erased)
+	}
+	sp := GetSP(math.Abs(vPrime))
+	if betaStar == 0 {
+		if sp >= 0 {
+			return 0, fmt.Errorf("elf: invalid beta* 0 for erased value %#x", erased)
+		}
+		v := Get10iN(-sp - 1)
+		if vPrime < 0 {
+			v = -v
+		}
+		return v, nil
+	}
+	alpha := betaStar - sp - 1
+	if alpha < 0 {
+		return 0, fmt.Errorf("elf: invalid beta* %d for erased value %#x", betaStar, erased)
+	}
+	return RoundUp(vPrime, alpha), nil
+}
@@ -0,0 +1,78 @@
+package elf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Erase is lossless: either Restore rebuilds the exact bits, or the value is passed through unchanged.
+func TestEraseLossless(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := []float64{
+		0, math.Copysign(0, -1), math.NaN(), math.Float64frombits(0x7ff8000000000001),
+		math.Inf(1), math.Inf(-1), math.SmallestNonzeroFloat64, math.MaxFloat64,
+		minErasable, math.Nextafter(minErasable, 0), maxErasable, math.Nextafter(maxErasable, 0),
+		0.1, -0.1, 3.14, 1e-5, 123456.789, 1.0 / 3,
+	}
+	for i := 0; i < 10000; i++ {
+		values = append(values, math.Float64frombits(rng.Uint64()), math.Round(rng.NormFloat64()*1e6)/1e3)
+	}
+	e := NewEraser()
+	for _, v := range values {
+		u, betaStar, ok := e.Erase(v)
+		if !ok {
+			if u != math.Float64bits(v) {
+				t.Fatalf("%v: not erased but bits changed from %#x to %#x", v, math.Float64bits(v), u)
+			}
+			continue
+		}
+		r, err := Restore(u, betaStar)
+		if err != nil || math.Float64bits(r) != math.Float64bits(v) {
+			t.Fatalf("%v: restored %v, %v with beta* %d", v, r, err, betaStar)
+		}
+	}
+}
+
+// Only finite values inside [minErasable, maxErasable) with a short decimal form are erased.
+func TestEraseRange(t *testing.T) {
+	cases := []struct {
+		v        float64
+		erasable bool
+	}{
+		{3.14, true},
+		{-0.001, true},
+		{12345678.9, true},
+		{maxErasable, false},
+		{math.Nextafter(minErasable, 0), false},
+		{math.NaN(), false},
+		{math.Inf(-1), false},
+		{math.Copysign(0, -1), false},
+		{1.0 / 3, false},
+		{1.5, false}, // exact in binary, nothing to erase
+	}
+	for _, c := range cases {
+		if _, _, ok := NewEraser().Erase(c.v); ok != c.erasable {
+			t.Errorf("%v: erased %v, expected %v", c.v, ok, c.erasable)
+		}
+	}
+}
+
+// beta* fits the 5-bit model, and Restore rejects erased bits it cannot rebuild.
+func TestBetaStar(t *testing.T) {
+	e := NewEraser()
+	for i := 0; i < 1000; i++ {
+		v := math.Round(float64(i)*1.37e4) / 1e8
+		if _, betaStar, ok := e.Erase(v); ok && (betaStar < 0 || betaStar > maxBetaStar) {
+			t.Fatalf("%v: beta* %d", v, betaStar)
+		}
+	}
+	for _, u := range []uint64{0, 1 << 63, math.Float64bits(math.NaN()), math.Float64bits(math.Inf(1))} {
+		if _, err := Restore(u, 3); err == nil {
+			t.Errorf("Restore(%#x): expected an error", u)
+		}
+	}
+	if _, err := Restore(math.Float64bits(3.14), 0); err == nil {
+		t.Error("Restore with beta* 0 and a value above 1: expected an error")
+	}
+}
@@ -0,0 +1,107 @@
+package elf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"myalgo/algorithms/rangeCoding"
+)
+
+// Control symbols of Stage, coded with the previous symbol as context.
+const (
+	ctrlSame = 0 // erased with the last beta*
+	ctrlNew  = 1 // erased with a new beta*, followed by the beta* symbol
+	ctrlRaw  = 2 // not erased
+)
+
+// Stage puts the Elf erasure in front of any XOR codec, e.g. "elf+chimp128(entropy)". The erased values go
+// to the inner codec, which must restore them bit-exactly; the per-value erase information is kept in a
+// separate range-coded control stream. As in Elf+, beta* is only written when it changes, and here it
+// is coded with an adaptive model instead of a fixed 4-bit field.
+type Stage struct {
+	Compress   func(dst []byte, src []float64) []byte
+	Decompress func(dst []float64, src []byte) ([]float64, error)
+}
+
+// CompressFloat format: uvarint count, uvarint control stream length, control stream, inner codec output.
+func (s Stage) CompressFloat(dst []byte, src []float64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst
+	}
+	eraser := NewEraser()
+	enc := rangeCoding.NewEncoder(nil)
+	ctrl, betaModel := rangeCoding.NewSymbolModels(3, 2), rangeCoding.NewSymbolModel(5)
+	erased := make([]float64, len(src))
+	prev, lastBetaStar := ctrlRaw, -1
+	for i, v := range src {
+		u, betaStar, ok := eraser.Erase(v)
+		erased[i] = math.Float64frombits(u)
+		sym := ctrlRaw
+		if ok {
+			sym = ctrlSame
+			if betaStar != lastBetaStar {
+				sym = ctrlNew
+			}
+		}
+		enc.Encode(ctrl[prev], sym)
+		if sym == ctrlNew {
+			enc.Encode(betaModel, betaStar)
+			lastBetaStar = betaStar
+		}
+		prev = sym
+	}
+	control := enc.Finish()
+	dst = binary.AppendUvarint(dst, uint64(len(control)))
+	dst = append(dst, control...)
+	return s.Compress(dst, erased)
+}
+
+// DecompressFloat restores CompressFloat, appending to dst.
+func (s Stage) DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 {
+		return nil, fmt.Errorf("elf: invalid stage header")
+	}
+	if n == 0 {
+		return dst, nil
+	}
+	size, m := binary.Uvarint(src[k:])
+	if m <= 0 || size > uint64(len(src)-k-m) {
+		return nil, fmt.Errorf("elf: invalid control stream length")
+	}
+	control := src[k+m : k+m+int(size)]
+	erased, err := s.Decompress(nil, src[k+m+int(size):])
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(erased)) != n {
+		return nil, fmt.Errorf("elf: inner codec returned %d values, expected %d", len(erased), n)
+	}
+	dec := rangeCoding.NewDecoder(control)
+	ctrl, betaModel := rangeCoding.NewSymbolModels(3, 2), rangeCoding.NewSymbolModel(5)
+	prev, lastBetaStar := ctrlRaw, -1
+	for i, v := range erased {
+		sym := dec.Decode(ctrl[prev])
+		prev = sym
+		switch sym {
+		case ctrlRaw:
+			dst = append(dst, v)
+			continue
+		case ctrlNew:
+			lastBetaStar = dec.Decode(betaModel)
+		case ctrlSame:
+			if lastBetaStar < 0 {
+				return nil, fmt.Errorf("elf: beta* reused before defined at value %d", i)
+			}
+		default:
+			return nil, fmt.Errorf("elf: invalid control symbol %d at value %d", sym, i)
+		}
+		r, err := Restore(math.Float64bits(v), lastBetaStar)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, r)
+	}
+	return dst, nil
+}
@@ -0,0 +1,98 @@
+// The inner codecs import elf, so the Stage tests live in an external test package.
+package elf_test
+
+import (
+	"encoding/binary"
+	"math"
+	"myalgo/algorithms/chimp128"
+	"myalgo/algorithms/elf"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/algorithms/registry"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// stage is one inner codec behind the erasure.
+type stage struct {
+	name  string
+	stage elf.Stage
+}
+
+// stages pairs the erasure with every inner codec the registry combines it with. The registry
+// versions of gorilla and chimp decompress a copy of their input.
+func stages(t *testing.T) []stage {
+	var ss []stage
+	for _, name := range []string{"gorilla", "chimp", "chimp128(entropy)"} {
+		c, ok := registry.Float(name)
+		if !ok {
+			t.Fatalf("unknown codec %q", name)
+		}
+		ss = append(ss, stage{name, elf.Stage{Compress: c.Compress, Decompress: c.Decompress}})
+	}
+	return ss
+}
+
+// decimals returns n values with the given number of decimal digits.
+func decimals(n, digits int) []float64 {
+	data := make([]float64, n)
+	scale := math.Pow10(digits)
+	for i := range data {
+		data[i] = math.Round((20+math.Sin(float64(i)/10))*scale) / scale
+	}
+	return data
+}
+
+func TestStage(t *testing.T) {
+	cases := append(codectest.Floats(),
+		codectest.FloatCase{Name: "one decimal", Data: decimals(1000, 1)},
+		codectest.FloatCase{Name: "four decimals", Data: decimals(1000, 4)})
+	for _, s := range stages(t) {
+		for _, c := range cases {
+			codectest.RoundTripFloats(t, s.name+"/"+c.Name, s.stage.CompressFloat, s.stage.DecompressFloat, c.Data)
+		}
+	}
+}
+
+// Erasing decimal data leaves long runs of trailing zeros, so every inner codec gets smaller input.
+func TestStageShrinks(t *testing.T) {
+	data := decimals(1000, 2)
+	for _, s := range stages(t) {
+		staged := s.stage.CompressFloat(nil, data)
+		plain := s.stage.Compress(nil, data)
+		if len(staged) >= len(plain) {
+			t.Errorf("%s: %d bytes with erasure, %d without", s.name, len(staged), len(plain))
+		}
+	}
+}
+
+// Values with a changing precision mix ctrlNew, ctrlSame and ctrlRaw symbols; a control stream
+// that reuses beta* before defining it is rejected.
+func TestStageControl(t *testing.T) {
+	var data []float64
+	for i := 0; i < 300; i++ {
+		switch i % 3 {
+		case 0:
+			data = append(data, decimals(1, 1+i%5)[0]+float64(i))
+		case 1:
+			data = append(data, math.NaN())
+		default:
+			data = append(data, math.Float64frombits(0x400921fb54442d18+uint64(i)))
+		}
+	}
+	for _, s := range stages(t) {
+		codectest.RoundTripFloats(t, s.name, s.stage.CompressFloat, s.stage.DecompressFloat, data)
+	}
+
+	// A stream whose first control symbol is ctrlSame (0), coded in the context of ctrlRaw (2).
+	enc := rangeCoding.NewEncoder(nil)
+	enc.Encode(rangeCoding.NewSymbolModels(3, 2)[2], 0)
+	control := enc.Finish()
+	forged := binary.AppendUvarint(nil, 1)
+	forged = binary.AppendUvarint(forged, uint64(len(control)))
+	forged = append(forged, control...)
+	forged = chimp128.CompressFloatEntropy(forged, []float64{1.5})
+	s := elf.Stage{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy}
+	if _, err := s.DecompressFloat(nil, forged); err == nil {
+		t.Error("expected an error for beta* reused before it is defined")
+	}
+}
@@ -1,5 +1,6 @@
 package fpc
 
+// DfcmPredictor 与 FcmPredictor 类似，但哈希和预测的都是相邻两个值的差
 type DfcmPredictor struct {
 	table     []uint64
 	lastValue uint64
@@ -7,6 +8,7 @@ type DfcmPredictor struct {
 	mask      uint64
 }
 
+// NewDfcmPredictor tableSize 必须是 2 的幂
 func NewDfcmPredictor(tableSize uint64) *DfcmPredictor {
 	return &DfcmPredictor{
 		table:     make([]uint64, tableSize),
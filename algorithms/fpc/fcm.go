@@ -1,11 +1,13 @@
 package fpc
 
+// FcmPredictor 以最近几个值高位的哈希为上下文，预测为该上下文上次出现之后的值
 type FcmPredictor struct {
 	table    []uint64
 	lastHash uint64
 	mask     uint64
 }
 
+// NewFcmPredictor tableSize 必须是 2 的幂
 func NewFcmPredictor(tableSize uint64) *FcmPredictor {
 	return &FcmPredictor{
 		table:    make([]uint64, tableSize),
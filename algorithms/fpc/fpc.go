@@ -1,139 +1,241 @@
+// Package fpc 实现 FPC：FCM 和 DFCM 两个预测器各给出一个预测，取异或残差前导零字节更多的那个。
+// 每个值有 4 位头部（1 位选择预测器，3 位前导零字节数），相邻两个值的头部合成一个字节，之后依次是两个残差去掉前导零字节后的字节
 package fpc
 
 import (
+	"encoding/binary"
+	"fmt"
 	"math"
 	"math/bits"
-	"myalgo/common"
 )
 
-func Compress(dst []byte, src []uint64) []byte {
-	bs := &common.ByteWrapper{Stream: &dst, Count: 0}
-	bs.AppendBits(uint64(len(src)), 14)
-	dfcm := NewDfcmPredictor(16)
-	fcm := NewFcmPredictor(16)
-	var dtmp, ftmp, cnt, xor uint64
-	for _, u := range src {
-		dtmp = dfcm.PredictNext() ^ u
-		ftmp = fcm.PredictNext() ^ u
-		if bits.LeadingZeros64(dtmp)/8 > bits.LeadingZeros64(ftmp)/8 {
-			cnt = uint64(bits.LeadingZeros64(dtmp) / 8)
-			if cnt == 8 {
-				cnt -= 1
-			}
-			xor = dtmp
-			bs.AppendBits(cnt|0b1000, 4)
-		} else {
-			cnt = uint64(bits.LeadingZeros64(ftmp) / 8)
-			if cnt == 8 {
-				cnt -= 1
-			}
-			xor = ftmp
-			bs.AppendBits(cnt|0b0000, 4)
+const (
+	// DefaultLevel 默认级别，两个预测器的哈希表各有 1<<DefaultLevel 项
+	DefaultLevel = 16
+	// MaxLevel 允许的最大级别
+	MaxLevel = 24
+)
+
+// coder 一对预测器和值的宽度（字节数）。float32 的位模式放在 uint64 的高 32 位，预测器的哈希仍取高位，残差的低 32 位恒为 0
+type coder struct {
+	fcm   *FcmPredictor
+	dfcm  *DfcmPredictor
+	width int
+}
+
+func newCoder(level, width int) *coder {
+	return &coder{NewFcmPredictor(1 << level), NewDfcmPredictor(1 << level), width}
+}
+
+// encode 把 v 的残差字节按小端追加到 dst，返回 4 位头部。
+// 8 字节的值前导零字节数有 0~8 九种，和论文一样不用 4，前导零为 4 时按 3 编码
+func (c *coder) encode(dst []byte, v uint64) ([]byte, byte) {
+	f, d := c.fcm.PredictNext()^v, c.dfcm.PredictNext()^v
+	c.fcm.Update(v)
+	c.dfcm.Update(v)
+	r, h := f, byte(0)
+	if bits.LeadingZeros64(d)/8 > bits.LeadingZeros64(f)/8 {
+		r, h = d, 8
+	}
+	r >>= 64 - 8*c.width
+	lzb := bits.LeadingZeros64(r)/8 - (8 - c.width)
+	code := lzb
+	if c.width == 8 && lzb >= 4 {
+		code--
+		if lzb == 4 {
+			lzb, code = 3, 3
 		}
-		bs.AppendBits(xor, int((8-cnt)*8))
-		dfcm.Update(u)
-		fcm.Update(u)
 	}
-	return dst
+	for i := 0; i < c.width-lzb; i++ {
+		dst = append(dst, byte(r>>(8*i)))
+	}
+	return dst, h | byte(code)
 }
 
-func Decompress(dst []uint64, src []byte) ([]uint64, error) {
-	bs := &common.ByteWrapper{Stream: &src, Count: 8}
-	size, err := bs.ReadBits(14)
+// decode 按 4 位头部 h 从 src 读出一个值，返回值和剩余的 src
+func (c *coder) decode(src []byte, h byte) (uint64, []byte, error) {
+	lzb := int(h & 7)
+	if c.width == 8 && lzb >= 4 {
+		lzb++
+	}
+	if lzb > c.width {
+		return 0, nil, fmt.Errorf("fpc: invalid header %#x", h)
+	}
+	n := c.width - lzb
+	if len(src) < n {
+		return 0, nil, fmt.Errorf("fpc: truncated residual")
+	}
+	var r uint64
+	for i := 0; i < n; i++ {
+		r |= uint64(src[i]) << (8 * i)
+	}
+	r <<= 64 - 8*c.width
+	if h&8 != 0 {
+		r ^= c.dfcm.PredictNext()
+	} else {
+		r ^= c.fcm.PredictNext()
+	}
+	c.fcm.Update(r)
+	c.dfcm.Update(r)
+	return r, src[n:], nil
+}
+
+// levelLimit 值个数为 n 时实际使用的最大级别。超过 DefaultLevel 的级别只在哈希表不超过约 4n 项时使用，
+// 解压时据此拒绝伪造的级别，不会按不可信的头部分配上百 MB 的哈希表
+func levelLimit(n int) int {
+	return min(MaxLevel, max(DefaultLevel, bits.Len(uint(n))+1))
+}
+
+// compress 格式：uvarint 值个数，1 字节级别，之后每两个值一个头部字节（高 4 位是前一个值），接着是两个值的残差字节。
+// 值个数为奇数时最后一个头部字节的低 4 位为 0。level 超出 [0, MaxLevel] 时返回错误，超过 levelLimit 时按 levelLimit 压缩
+func compress(dst []byte, src []uint64, level, width int) ([]byte, error) {
+	if level < 0 || level > MaxLevel {
+		return nil, fmt.Errorf("fpc: invalid level %d", level)
+	}
+	out, _ := compressBounded(dst, src, level, width, math.MaxInt)
+	return out, nil
+}
+
+// compressBounded 按 compress 的格式压缩，输出超过 limit 字节时提前停止并返回 false
+func compressBounded(dst []byte, src []uint64, level, width, limit int) ([]byte, bool) {
+	start := len(dst)
+	level = min(level, levelLimit(len(src)))
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	dst = append(dst, byte(level))
+	c := newCoder(level, width)
+	for i := 0; i < len(src); i += 2 {
+		pos := len(dst)
+		dst = append(dst, 0)
+		var hi, lo byte
+		dst, hi = c.encode(dst, src[i])
+		if i+1 < len(src) {
+			dst, lo = c.encode(dst, src[i+1])
+		}
+		dst[pos] = hi<<4 | lo
+		if len(dst)-start > limit {
+			return nil, false
+		}
+	}
+	if len(dst)-start > limit {
+		return nil, false
+	}
+	return dst, true
+}
+
+// compressDefault 以 DefaultLevel 压缩，DefaultLevel 总是有效
+func compressDefault(dst []byte, src []uint64, width int) []byte {
+	out, err := compress(dst, src, DefaultLevel, width)
 	if err != nil {
-		return nil, err
+		panic(err)
 	}
-	dfcm := NewDfcmPredictor(16)
-	fcm := NewFcmPredictor(16)
-	var pred, xor, cnt uint64
-	for i := uint64(0); i < size; i++ {
-		if bit, err := bs.ReadBit(); err != nil {
-			return nil, err
-		} else {
-			cnt, err = bs.ReadBits(3)
-			if err != nil {
-				return nil, err
-			}
-			if bit {
-				pred = dfcm.PredictNext()
-			} else {
-				pred = fcm.PredictNext()
-			}
-			xor, err = bs.ReadBits(int(8 * (8 - cnt)))
-			if err != nil {
-				return nil, err
+	return out
+}
+
+// decompress 还原 compress，每个值调用一次 emit
+func decompress(src []byte, width int, emit func(uint64)) error {
+	n, k := binary.Uvarint(src)
+	// 每两个值至少占 1 个头部字节
+	if k <= 0 || len(src) <= k || n > uint64(len(src)-k-1)*2 {
+		return fmt.Errorf("fpc: invalid header")
+	}
+	level := int(src[k])
+	if level > levelLimit(int(n)) {
+		return fmt.Errorf("fpc: invalid level %d", level)
+	}
+	src = src[k+1:]
+	c := newCoder(level, width)
+	for i := uint64(0); i < n; i += 2 {
+		if len(src) == 0 {
+			return fmt.Errorf("fpc: truncated header at value %d", i)
+		}
+		h := src[0]
+		v, rest, err := c.decode(src[1:], h>>4)
+		if err != nil {
+			return err
+		}
+		emit(v)
+		if i+1 < n {
+			if v, rest, err = c.decode(rest, h&15); err != nil {
+				return err
 			}
-			xor ^= pred
-			dst = append(dst, xor)
-			dfcm.Update(xor)
-			fcm.Update(xor)
+			emit(v)
 		}
+		src = rest
+	}
+	return nil
+}
+
+func Compress(dst []byte, src []uint64) []byte {
+	return compressDefault(dst, src, 8)
+}
+
+// CompressLevel 哈希表大小为 1<<level，level 越大越能记住久远的值，内存为 2*8<<level 字节。level 超出 [0, MaxLevel] 时返回错误，
+// 值个数少时超过 DefaultLevel 的部分不起作用，按 levelLimit 截断
+func CompressLevel(dst []byte, src []uint64, level int) ([]byte, error) {
+	return compress(dst, src, level, 8)
+}
+
+// Decompress 还原 Compress 和 CompressLevel，级别从数据中读取，结果追加到 dst
+func Decompress(dst []uint64, src []byte) ([]uint64, error) {
+	err := decompress(src, 8, func(v uint64) { dst = append(dst, v) })
+	if err != nil {
+		return nil, err
 	}
 	return dst, nil
 }
+
 func CompressFloat(dst []byte, src []float64) []byte {
-	bs := &common.ByteWrapper{Stream: &dst, Count: 0}
-	bs.AppendBits(uint64(len(src)), 64)
-	dfcm := NewDfcmPredictor(16)
-	fcm := NewFcmPredictor(16)
-	var dtmp, ftmp, cnt, xor uint64
-	for _, u := range src {
-		uint64U := math.Float64bits(u)
-		dtmp = dfcm.PredictNext() ^ uint64U
-		ftmp = fcm.PredictNext() ^ uint64U
-		if bits.LeadingZeros64(dtmp)/8 > bits.LeadingZeros64(ftmp)/8 {
-			cnt = uint64(bits.LeadingZeros64(dtmp) / 8)
-			if cnt == 8 {
-				cnt -= 1
-			}
-			xor = dtmp
-			bs.AppendBits(cnt|0b1000, 4)
-		} else {
-			cnt = uint64(bits.LeadingZeros64(ftmp) / 8)
-			if cnt == 8 {
-				cnt -= 1
-			}
-			xor = ftmp
-			bs.AppendBits(cnt|0b0000, 4)
-		}
-		bs.AppendBits(xor, int((8-cnt)*8))
-		dfcm.Update(uint64U)
-		fcm.Update(uint64U)
+	return compressDefault(dst, float64Bits(src), 8)
+}
+
+func CompressFloatLevel(dst []byte, src []float64, level int) ([]byte, error) {
+	return compress(dst, float64Bits(src), level, 8)
+}
+
+// CompressFloatBounded 以 DefaultLevel 压缩，输出超过 limit 字节时提前停止并返回 false，否则输出与 CompressFloat 相同
+func CompressFloatBounded(dst []byte, src []float64, limit int) ([]byte, bool) {
+	return compressBounded(dst, float64Bits(src), DefaultLevel, 8, limit)
+}
+
+func float64Bits(src []float64) []uint64 {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
 	}
-	return dst
+	return values
 }
 
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
-	bs := &common.ByteWrapper{Stream: &src, Count: 8}
-	size, err := bs.ReadBits(64)
+	err := decompress(src, 8, func(v uint64) { dst = append(dst, math.Float64frombits(v)) })
 	if err != nil {
 		return nil, err
 	}
-	dfcm := NewDfcmPredictor(16)
-	fcm := NewFcmPredictor(16)
-	var pred, xor, cnt uint64
-	for i := uint64(0); i < size; i++ {
-		if bit, err := bs.ReadBit(); err != nil {
-			return nil, err
-		} else {
-			cnt, err = bs.ReadBits(3)
-			if err != nil {
-				return nil, err
-			}
-			if bit {
-				pred = dfcm.PredictNext()
-			} else {
-				pred = fcm.PredictNext()
-			}
-			xor, err = bs.ReadBits(int(8 * (8 - cnt)))
-			if err != nil {
-				return nil, err
-			}
-			xor ^= pred
-			dst = append(dst, math.Float64frombits(xor))
-			dfcm.Update(xor)
-			fcm.Update(xor)
-		}
+	return dst, nil
+}
+
+// CompressFloat32 float32 版本，每个残差最多 4 字节，头部的前导零字节数为 0~4
+func CompressFloat32(dst []byte, src []float32) []byte {
+	return compressDefault(dst, float32Bits(src), 4)
+}
+
+func CompressFloat32Level(dst []byte, src []float32, level int) ([]byte, error) {
+	return compress(dst, float32Bits(src), level, 4)
+}
+
+// float32Bits 位模式放在高 32 位
+func float32Bits(src []float32) []uint64 {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = uint64(math.Float32bits(v)) << 32
+	}
+	return values
+}
+
+// DecompressFloat32 还原 CompressFloat32 和 CompressFloat32Level，不能用来解压 float64 的数据
+func DecompressFloat32(dst []float32, src []byte) ([]float32, error) {
+	err := decompress(src, 4, func(v uint64) { dst = append(dst, math.Float32frombits(uint32(v>>32))) })
+	if err != nil {
+		return nil, err
 	}
 	return dst, nil
 }
@@ -0,0 +1,154 @@
+package fpc
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, Compress, Decompress, c.Data)
+	}
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloat, DecompressFloat, c.Data)
+	}
+}
+
+// 值个数超过 16383 时 uvarint 占 3 字节，级别字节紧随其后；奇数个值时最后一个头部字节的低 4 位为 0
+func TestCounts(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 3, 127, 128, 16383, 16384, 16385} {
+		data := make([]uint64, n)
+		for i := range data {
+			data[i] = rng.Uint64() >> uint(rng.Intn(64))
+		}
+		out := codectest.RoundTripUint64s(t, "count", Compress, Decompress, data)
+		m := len(binary.AppendUvarint(nil, uint64(n)))
+		if out[m] != DefaultLevel {
+			t.Errorf("%d values: level byte %d at offset %d", n, out[m], m)
+		}
+	}
+	out := Compress(nil, []uint64{0, 0, 0})
+	// 两个头部字节：第三个值的前导零为 8 字节（编码为 7），没有第四个值
+	if h := out[len(out)-1]; h != 7<<4 {
+		t.Errorf("last header byte %#x, expected 0x70", h)
+	}
+}
+
+// 两个预测器起初都预测 0，残差就是值本身。前导零字节数 4 按 3 编码并多写 1 字节，5~8 编码为 4~7
+func TestLeadingZeroBytes(t *testing.T) {
+	cases := []struct {
+		v     uint64
+		code  byte
+		bytes int
+	}{
+		{math.MaxUint64, 0, 8},
+		{1<<40 - 1, 3, 5},
+		{1<<32 - 1, 3, 5},
+		{1<<24 - 1, 4, 3},
+		{1, 6, 1},
+		{0, 7, 0},
+	}
+	for _, c := range cases {
+		out := codectest.RoundTripUint64s(t, "single value", Compress, Decompress, []uint64{c.v})
+		if h := out[2] >> 4; h != c.code || len(out) != 3+c.bytes {
+			t.Errorf("%#x: header %d and %d residual bytes, expected %d and %d", c.v, h, len(out)-3, c.code, c.bytes)
+		}
+	}
+}
+
+// 每个有效级别都能还原；值少时超过 DefaultLevel 的级别按 levelLimit 截断，解压时拒绝超过 levelLimit 的级别
+func TestLevels(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	data := make([]float64, 1000)
+	for i := range data {
+		data[i] = float64(rng.Intn(100)) / 8
+	}
+	for level := 0; level <= MaxLevel; level++ {
+		compress := func(dst []byte, src []float64) []byte {
+			out, err := CompressFloatLevel(dst, src, level)
+			if err != nil {
+				t.Fatalf("level %d: %v", level, err)
+			}
+			return out
+		}
+		out := codectest.RoundTripFloats(t, "level", compress, DecompressFloat, data)
+		if want := min(level, levelLimit(len(data))); int(out[2]) != want {
+			t.Errorf("level %d: stored level %d, expected %d", level, out[2], want)
+		}
+	}
+	for _, level := range []int{-1, MaxLevel + 1} {
+		if _, err := CompressLevel(nil, []uint64{1}, level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+		if _, err := CompressFloatLevel(nil, []float64{1}, level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+		if _, err := CompressFloat32Level(nil, []float32{1}, level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+	}
+
+	// 2^16 个值可以用到 18 级
+	many := make([]uint64, 1<<16)
+	for i := range many {
+		many[i] = uint64(i) * 0x9E3779B97F4A7C15
+	}
+	out, err := CompressLevel(nil, many, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := len(binary.AppendUvarint(nil, uint64(len(many))))
+	if out[m] != 18 {
+		t.Errorf("%d values: stored level %d, expected 18", len(many), out[m])
+	}
+	if levelLimit(1<<22) != MaxLevel || levelLimit(0) != DefaultLevel {
+		t.Errorf("levelLimit(1<<22) = %d, levelLimit(0) = %d", levelLimit(1<<22), levelLimit(0))
+	}
+
+	// 伪造的级别：10 个值的头部声明超过 DefaultLevel 的级别
+	forged := Compress(nil, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	for _, level := range []byte{DefaultLevel + 1, MaxLevel, MaxLevel + 1, 255} {
+		forged[1] = level
+		if _, err := Decompress(nil, forged); err == nil {
+			t.Errorf("forged level %d: expected an error", level)
+		}
+	}
+}
+
+func TestCompressFloat32(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	random := make([]float32, 999)
+	for i := range random {
+		random[i] = math.Float32frombits(rng.Uint32())
+	}
+	special := []float32{
+		math.Float32frombits(0x7fc00001), // quiet NaN，载荷为 1
+		math.Float32frombits(0xffa00000), // 负的 signaling NaN
+		float32(math.Copysign(0, -1)), 0,
+		float32(math.Inf(1)), float32(math.Inf(-1)),
+		math.SmallestNonzeroFloat32, -math.MaxFloat32,
+		1.5,
+	}
+	for _, data := range [][]float32{nil, {3.14}, special, random} {
+		got, err := DecompressFloat32(nil, CompressFloat32(nil, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(data) {
+			t.Fatalf("got %d values, expected %d", len(got), len(data))
+		}
+		for i := range data {
+			if math.Float32bits(got[i]) != math.Float32bits(data[i]) {
+				t.Fatalf("value %d: got %#x, expected %#x", i, math.Float32bits(got[i]), math.Float32bits(data[i]))
+			}
+		}
+	}
+	// float32 的前导零字节数为 0~4，不跳过 4：0 的残差不写任何字节
+	if out := CompressFloat32(nil, []float32{0}); len(out) != 3 || out[2]>>4 != 4 {
+		t.Errorf("zero compressed into %x", out)
+	}
+}
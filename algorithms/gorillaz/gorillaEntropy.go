@@ -0,0 +1,147 @@
+package gorillaz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/common"
+)
+
+// Gorilla 的熵编码变体：判定逻辑与 Compress 相同，控制码、前导零个数和有效位长度交给自适应范围编码，
+// 控制码以上一个控制码为上下文；有效位仍按原样写入位流。
+// 格式：uvarint 值个数，uvarint 控制流长度，控制流，残差位流（首个值 64 位加各值的有效位）
+
+// 控制码
+const (
+	codeSame   = 0 // 与前一个值相同
+	codeReuse  = 1 // 沿用上一个窗口
+	codeWindow = 2 // 新窗口，随后编码前导零个数和有效位长度
+)
+
+// CompressEntropy 熵编码控制码的 Compress
+func CompressEntropy(dst []byte, src []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst
+	}
+	var residual []byte
+	bs := &common.ByteWrapper{Stream: &residual, Count: 0}
+	bs.AppendBits(src[0], 64)
+
+	enc := rangeCoding.NewEncoder(nil)
+	codes := rangeCoding.NewSymbolModels(3, 2)
+	leadingModel, sigModel := rangeCoding.NewSymbolModel(6), rangeCoding.NewSymbolModel(6)
+	prev, prevCode := src[0], codeSame
+	prevLeadingZeros, prevTrailingZeros := -1, 0
+	for _, num := range src[1:] {
+		v := num ^ prev
+		prev = num
+		code := codeSame
+		leadingZeros, trailingZeros := 0, 0
+		if v != 0 {
+			leadingZeros, trailingZeros = bits.LeadingZeros64(v), bits.TrailingZeros64(v)
+			code = codeWindow
+			if prevLeadingZeros >= 0 && leadingZeros >= prevLeadingZeros && trailingZeros >= prevTrailingZeros {
+				code = codeReuse
+			}
+		}
+		enc.Encode(codes[prevCode], code)
+		prevCode = code
+		switch code {
+		case codeReuse:
+			bs.AppendBits(v>>prevTrailingZeros, 64-prevLeadingZeros-prevTrailingZeros)
+		case codeWindow:
+			prevLeadingZeros, prevTrailingZeros = leadingZeros, trailingZeros
+			sigbits := 64 - leadingZeros - trailingZeros
+			enc.Encode(leadingModel, leadingZeros)
+			enc.Encode(sigModel, sigbits-1)
+			bs.AppendBits(v>>trailingZeros, sigbits)
+		}
+	}
+	control := enc.Finish()
+	dst = binary.AppendUvarint(dst, uint64(len(control)))
+	dst = append(dst, control...)
+	return append(dst, residual...)
+}
+
+// DecompressEntropy 还原 CompressEntropy，结果追加到 dst
+func DecompressEntropy(dst []uint64, src []byte) ([]uint64, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 {
+		return nil, fmt.Errorf("gorillaz: invalid entropy header")
+	}
+	if n == 0 {
+		return dst, nil
+	}
+	size, k := binary.Uvarint(src[m:])
+	if k <= 0 || size > uint64(len(src)-m-k) {
+		return nil, fmt.Errorf("gorillaz: invalid control stream length")
+	}
+	src = src[m+k:]
+	dec := rangeCoding.NewDecoder(src[:size])
+	// ByteWrapper 读取时会改写输入，先复制残差位流
+	residual := append([]byte(nil), src[size:]...)
+	bs := &common.ByteWrapper{Stream: &residual, Count: 8}
+	prev, err := bs.ReadBits(64)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, prev)
+
+	codes := rangeCoding.NewSymbolModels(3, 2)
+	leadingModel, sigModel := rangeCoding.NewSymbolModel(6), rangeCoding.NewSymbolModel(6)
+	prevCode := codeSame
+	leadingZeros, trailingZeros := -1, 0
+	for i := uint64(1); i < n; i++ {
+		code := dec.Decode(codes[prevCode])
+		prevCode = code
+		switch code {
+		case codeSame:
+			dst = append(dst, prev)
+			continue
+		case codeReuse:
+			if leadingZeros < 0 {
+				return nil, fmt.Errorf("gorillaz: window reused before defined at value %d", i)
+			}
+		case codeWindow:
+			leadingZeros = dec.Decode(leadingModel)
+			sigbits := dec.Decode(sigModel) + 1
+			if leadingZeros+sigbits > 64 {
+				return nil, fmt.Errorf("gorillaz: invalid window at value %d", i)
+			}
+			trailingZeros = 64 - leadingZeros - sigbits
+		default:
+			return nil, fmt.Errorf("gorillaz: invalid control code %d at value %d", code, i)
+		}
+		v, err := bs.ReadBits(64 - leadingZeros - trailingZeros)
+		if err != nil {
+			return nil, err
+		}
+		prev ^= v << trailingZeros
+		dst = append(dst, prev)
+	}
+	return dst, nil
+}
+
+// CompressFloatEntropy 熵编码控制码的 CompressFloat
+func CompressFloatEntropy(dst []byte, src []float64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
+	}
+	return CompressEntropy(dst, values)
+}
+
+// DecompressFloatEntropy 还原 CompressFloatEntropy
+func DecompressFloatEntropy(dst []float64, src []byte) ([]float64, error) {
+	values, err := DecompressEntropy(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range values {
+		dst = append(dst, math.Float64frombits(u))
+	}
+	return dst, nil
+}
@@ -0,0 +1,51 @@
+package gorillaz
+
+import (
+	"math"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompressEntropy(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloatEntropy, DecompressFloatEntropy, c.Data)
+	}
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, CompressEntropy, DecompressEntropy, c.Data)
+	}
+	// 只有一个特殊值，以及 NaN 和 ±Inf 交替，异或值的窗口反复变化
+	specials := []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Float64frombits(0x7ff8000000000001), math.Copysign(0, -1)}
+	var alternating []float64
+	for i := 0; i < 300; i++ {
+		alternating = append(alternating, specials[i%len(specials)])
+	}
+	for _, v := range specials {
+		codectest.RoundTripFloats(t, "one special", CompressFloatEntropy, DecompressFloatEntropy, []float64{v})
+	}
+	codectest.RoundTripFloats(t, "alternating", CompressFloatEntropy, DecompressFloatEntropy, alternating)
+}
+
+// 相同的值只编码控制码，在上一个控制码的上下文中几乎不占空间
+func TestEntropyConstant(t *testing.T) {
+	data := make([]float64, 10000)
+	for i := range data {
+		data[i] = 21.5
+	}
+	if out := codectest.RoundTripFloats(t, "constant", CompressFloatEntropy, DecompressFloatEntropy, data); len(out) > 128 {
+		t.Errorf("%d equal values compressed into %d bytes", len(data), len(out))
+	}
+}
+
+// 截断的输入返回错误，不会 panic 或还原出错误的值
+func TestEntropyTruncated(t *testing.T) {
+	var data []float64
+	for _, c := range codectest.Floats() {
+		data = append(data, c.Data...)
+	}
+	out := CompressFloatEntropy(nil, data[:300])
+	for n := 0; n < len(out); n++ {
+		if got, err := DecompressFloatEntropy(nil, out[:n]); err == nil && len(got) == 300 {
+			t.Fatalf("%d of %d bytes decoded without an error", n, len(out))
+		}
+	}
+}
@@ -28,7 +28,7 @@ func XorWithAndWithoutErase(src []float64) error {
 	// 初始化
 	prevBits := math.Float64bits(src[0])
 	prevErased := prevBits
-	lastBetaStar := math.MaxInt32
+	eraser := elf.NewEraser()
 
 	for i := 1; i < len(src); i++ {
 		v := src[i]
@@ -37,34 +37,8 @@ func XorWithAndWithoutErase(src []float64) error {
 		// 1. 直接 XOR
 		directXor := currBits ^ prevBits
 
-		// 2. 擦除后 XOR
-		var currErased uint64
-
-		// 按照 ELF 的擦除逻辑
-		if v == 0.0 || math.IsInf(v, 0) || math.IsNaN(v) {
-			// 特殊值不擦除
-			currErased = currBits
-		} else {
-			alpha, betaStar := elf.GetAlphaAndBetaStar(v, lastBetaStar)
-			e := int((currBits >> 52) & 0x7ff)
-			gAlpha := elf.GetFAlpha(alpha) + e - 1023
-			eraseBits := 52 - gAlpha
-
-			if eraseBits > 4 {
-				// 执行擦除
-				mask := ^uint64(0) << uint(eraseBits)
-				delta := (^mask) & currBits
-
-				if delta != 0 {
-					currErased = mask & currBits
-					lastBetaStar = betaStar
-				} else {
-					currErased = currBits
-				}
-			} else {
-				currErased = currBits
-			}
-		}
+		// 2. 擦除后 XOR，不能擦除的值保持原样
+		currErased, _, _ := eraser.Erase(v)
 
 		erasedXor := currErased ^ prevErased
 
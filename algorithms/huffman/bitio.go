@@ -0,0 +1,79 @@
+package huffman
+
+// bitWriter 高位在前写入，用 64 位缓冲累积，每满一个字节输出一次
+type bitWriter struct {
+	dst []byte
+	acc uint64
+	n   uint // acc 中尚未输出的位数，始终小于 8
+}
+
+func (w *bitWriter) writeBits(v uint64, nbits uint) {
+	if nbits > 32 {
+		w.writeBits(v>>32, nbits-32)
+		v, nbits = v&(1<<32-1), 32
+	}
+	w.acc = w.acc<<nbits | v
+	w.n += nbits
+	for w.n >= 8 {
+		w.n -= 8
+		w.dst = append(w.dst, byte(w.acc>>w.n))
+	}
+}
+
+// flush 不足一个字节的部分补 0 输出
+func (w *bitWriter) flush() []byte {
+	if w.n > 0 {
+		w.dst = append(w.dst, byte(w.acc<<(8-w.n)))
+		w.n = 0
+	}
+	return w.dst
+}
+
+// bitReader 高位在前读取，输入结束后补 0，padding 记录补了多少位
+type bitReader struct {
+	src     []byte
+	acc     uint64
+	n       uint
+	padding uint
+}
+
+// fill 保证缓冲中至少有 57 位
+func (r *bitReader) fill() {
+	for r.n <= 56 {
+		var b byte
+		if len(r.src) > 0 {
+			b, r.src = r.src[0], r.src[1:]
+		} else {
+			r.padding += 8
+		}
+		r.acc = r.acc<<8 | uint64(b)
+		r.n += 8
+	}
+}
+
+// peek 返回接下来的 nbits 位（不超过 56）但不消耗
+func (r *bitReader) peek(nbits uint) uint64 {
+	if r.n < nbits {
+		r.fill()
+	}
+	return r.acc >> (r.n - nbits) & (1<<nbits - 1)
+}
+
+func (r *bitReader) skip(nbits uint) {
+	r.n -= nbits
+}
+
+func (r *bitReader) readBits(nbits uint) uint64 {
+	if nbits > 32 {
+		hi := r.readBits(nbits - 32)
+		return hi<<32 | r.readBits(32)
+	}
+	v := r.peek(nbits)
+	r.skip(nbits)
+	return v
+}
+
+// overrun 是否读到了输入末尾之后补的 0
+func (r *bitReader) overrun() bool {
+	return r.padding > r.n
+}
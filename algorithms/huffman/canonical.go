@@ -0,0 +1,140 @@
+package huffman
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	maxCodeLen = 20
+	// tableBits 解码查找表的位数，更长的码按码长逐级比较
+	tableBits = 11
+)
+
+// pmItem package-merge 中的一项：叶子或上一层相邻两项的打包
+type pmItem struct {
+	weight uint64
+	leaf   int32 // 叶子的符号下标，打包时为 -1
+	left   int32 // 打包时上一层中第一项的下标，第二项紧随其后
+}
+
+// codeLengths 用 package-merge 计算码长不超过 maxLen 的最优前缀码，freqs 均大于 0
+func codeLengths(freqs []uint64, maxLen int) []int {
+	n := len(freqs)
+	lengths := make([]int, n)
+	if n == 1 {
+		lengths[0] = 1
+		return lengths
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return freqs[order[i]] < freqs[order[j]] })
+	leaves := make([]pmItem, n)
+	for i, s := range order {
+		leaves[i] = pmItem{freqs[s], int32(s), 0}
+	}
+
+	levels := [][]pmItem{leaves}
+	for l := 1; l < maxLen; l++ {
+		prev := levels[l-1]
+		merged := make([]pmItem, 0, n+len(prev)/2)
+		i := 0
+		for j := 0; j+1 < len(prev); j += 2 {
+			pkg := pmItem{prev[j].weight + prev[j+1].weight, -1, int32(j)}
+			for i < n && leaves[i].weight <= pkg.weight {
+				merged = append(merged, leaves[i])
+				i++
+			}
+			merged = append(merged, pkg)
+		}
+		levels = append(levels, append(merged, leaves[i:]...))
+	}
+
+	// 最后一层的前 2n-2 项中，每个符号出现的次数就是它的码长
+	var visit func(l int, k int32)
+	visit = func(l int, k int32) {
+		it := levels[l][k]
+		if it.leaf >= 0 {
+			lengths[it.leaf]++
+			return
+		}
+		visit(l-1, it.left)
+		visit(l-1, it.left+1)
+	}
+	for k := 0; k < 2*n-2; k++ {
+		visit(maxLen-1, int32(k))
+	}
+	return lengths
+}
+
+// canonicalCodes 符号已按 (码长, 顺序) 排好时的范式编码
+func canonicalCodes(lengths []int) []uint64 {
+	codes := make([]uint64, len(lengths))
+	code, prevLen := uint64(0), 0
+	for i, l := range lengths {
+		if i > 0 {
+			code++
+		}
+		code <<= l - prevLen
+		codes[i], prevLen = code, l
+	}
+	return codes
+}
+
+// decoder 范式 Huffman 的查表解码器
+type decoder struct {
+	maxLen int
+	// table 以接下来 tableBits 位为下标，低 5 位是码长，其余是符号；0 表示码长超过 tableBits
+	table []uint32
+	// 码长为 l 的第一个码、个数和第一个符号
+	first, count, offset [maxCodeLen + 1]int
+}
+
+// newDecoder counts[l] 是码长为 l 的符号个数，符号按码长从短到长编号
+func newDecoder(counts []int) (*decoder, error) {
+	d := &decoder{maxLen: len(counts) - 1, table: make([]uint32, 1<<tableBits)}
+	if d.maxLen > maxCodeLen {
+		return nil, fmt.Errorf("huffman: code length %d exceeds %d", d.maxLen, maxCodeLen)
+	}
+	code, sym := 0, 0
+	for l := 1; l <= d.maxLen; l++ {
+		code <<= 1
+		d.first[l], d.count[l], d.offset[l] = code, counts[l], sym
+		if code+counts[l] > 1<<l {
+			return nil, fmt.Errorf("huffman: oversubscribed code lengths")
+		}
+		if l <= tableBits {
+			for c := code; c < code+counts[l]; c++ {
+				entry := uint32(sym+c-code)<<5 | uint32(l)
+				start := c << (tableBits - l)
+				for k := start; k < start+1<<(tableBits-l); k++ {
+					d.table[k] = entry
+				}
+			}
+		}
+		code += counts[l]
+		sym += counts[l]
+	}
+	return d, nil
+}
+
+func (d *decoder) decode(r *bitReader) (int, error) {
+	bits := r.peek(uint(d.maxLen))
+	if d.maxLen <= tableBits {
+		bits <<= tableBits - d.maxLen
+	}
+	if entry := d.table[bits>>max(d.maxLen-tableBits, 0)]; entry != 0 {
+		r.skip(uint(entry & 31))
+		return int(entry >> 5), nil
+	}
+	for l := tableBits + 1; l <= d.maxLen; l++ {
+		c := int(bits >> (d.maxLen - l))
+		if c >= d.first[l] && c-d.first[l] < d.count[l] {
+			r.skip(uint(l))
+			return d.offset[l] + c - d.first[l], nil
+		}
+	}
+	return 0, fmt.Errorf("huffman: invalid code")
+}
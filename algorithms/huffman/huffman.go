@@ -1,370 +1,214 @@
+// Package huffman 以 float64 的位模式为符号的范式 Huffman 编码。
+//
+// 码长由 package-merge 计算并限制在 maxCodeLen 以内，流中只保存每个码长的符号个数和按码长排好的值字典，
+// 解码时查表。只出现一次的值不进字典，用转义码加 64 位原值写入。
 package huffman
 
 import (
-	"container/heap"
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sort"
 )
 
-// HuffmanNode 表示Huffman树的节点
-type HuffmanNode struct {
-	value  float64
-	freq   int
-	left   *HuffmanNode
-	right  *HuffmanNode
-	isLeaf bool
-}
-
-// PriorityQueue 实现优先队列用于构建Huffman树
-type PriorityQueue []*HuffmanNode
-
-func (pq PriorityQueue) Len() int { return len(pq) }
-
-func (pq PriorityQueue) Less(i, j int) bool {
-	return pq[i].freq < pq[j].freq
-}
-
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-}
-
-func (pq *PriorityQueue) Push(x interface{}) {
-	*pq = append(*pq, x.(*HuffmanNode))
-}
-
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	*pq = old[0 : n-1]
-	return item
-}
+const (
+	// minDictCount 出现次数少于它的值走转义
+	minDictCount = 2
+	// maxDictSize 字典最多保存的值个数，其余按出现次数从少到多转义
+	maxDictSize = 1 << 16
+)
 
-// buildFrequencyTable 构建浮点数频率表
-func buildFrequencyTable(data []float64) map[float64]int {
-	freq := make(map[float64]int)
-	for _, f := range data {
-		freq[f]++
-	}
-	return freq
+// symbol 字典中的一个值，escape 为 true 时表示转义码
+type symbol struct {
+	value  uint64
+	freq   uint64
+	escape bool
+	length int
 }
 
-// buildHuffmanTree 构建Huffman树
-func buildHuffmanTree(freq map[float64]int) *HuffmanNode {
-	if len(freq) == 0 {
-		return nil
+// buildSymbols 统计频率，返回按范式顺序排列、已分配码长的字典（含转义码）
+func buildSymbols(src []float64) []symbol {
+	freq := make(map[uint64]uint64)
+	for _, f := range src {
+		freq[math.Float64bits(f)]++
 	}
-	// 如果只有一个不同的浮点数，创建特殊情况
-	if len(freq) == 1 {
-		for f, count := range freq {
-			return &HuffmanNode{
-				value:  f,
-				freq:   count,
-				isLeaf: true,
-			}
-		}
+	syms := make([]symbol, 0, len(freq))
+	for v, c := range freq {
+		syms = append(syms, symbol{value: v, freq: c})
 	}
-
-	pq := &PriorityQueue{}
-	heap.Init(pq)
-
-	// 为每个浮点数创建叶子节点
-	for f, count := range freq {
-		node := &HuffmanNode{
-			value:  f,
-			freq:   count,
-			isLeaf: true,
+	// 按出现次数从多到少，保证结果与 map 的遍历顺序无关
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].freq != syms[j].freq {
+			return syms[i].freq > syms[j].freq
 		}
-		heap.Push(pq, node)
+		return syms[i].value < syms[j].value
+	})
+	keep := 0
+	for keep < len(syms) && keep < maxDictSize && syms[keep].freq >= minDictCount {
+		keep++
 	}
-
-	// 构建Huffman树
-	for pq.Len() > 1 {
-		left := heap.Pop(pq).(*HuffmanNode)
-		right := heap.Pop(pq).(*HuffmanNode)
-
-		merged := &HuffmanNode{
-			freq:   left.freq + right.freq,
-			left:   left,
-			right:  right,
-			isLeaf: false,
-		}
-		heap.Push(pq, merged)
+	escaped := uint64(0)
+	for _, s := range syms[keep:] {
+		escaped += s.freq
 	}
-
-	return heap.Pop(pq).(*HuffmanNode)
-}
-
-// generateCodes 生成Huffman编码表
-func generateCodes(root *HuffmanNode) map[float64]string {
-	if root == nil {
-		return nil
+	syms = syms[:keep]
+	if escaped > 0 {
+		syms = append(syms, symbol{freq: escaped, escape: true})
 	}
 
-	codes := make(map[float64]string)
-
-	// 特殊情况：只有一个不同的浮点数
-	if root.isLeaf {
-		codes[root.value] = "0"
-		return codes
+	freqs := make([]uint64, len(syms))
+	for i, s := range syms {
+		freqs[i] = s.freq
 	}
-
-	var generate func(*HuffmanNode, string)
-	generate = func(node *HuffmanNode, code string) {
-		if node.isLeaf {
-			codes[node.value] = code
-			return
-		}
-		if node.left != nil {
-			generate(node.left, code+"0")
-		}
-		if node.right != nil {
-			generate(node.right, code+"1")
-		}
+	for i, l := range codeLengths(freqs, maxCodeLen) {
+		syms[i].length = l
 	}
-
-	generate(root, "")
-	return codes
-}
-
-// encodeBits 将比特字符串转换为字节数组
-func encodeBits(bits string) []byte {
-	// 计算需要的字节数
-	byteCount := (len(bits) + 7) / 8
-	result := make([]byte, byteCount)
-
-	for i, bit := range bits {
-		if bit == '1' {
-			byteIndex := i / 8
-			bitIndex := 7 - (i % 8)
-			result[byteIndex] |= 1 << bitIndex
+	// 范式顺序：码长从短到长，同一码长内转义码在前，其余按值从小到大
+	sort.Slice(syms, func(i, j int) bool {
+		a, b := syms[i], syms[j]
+		if a.length != b.length {
+			return a.length < b.length
 		}
-	}
-
-	return result
-}
-
-// serializeTree 序列化Huffman树以便解压时重建
-func serializeTree(root *HuffmanNode) []byte {
-	if root == nil {
-		return []byte{}
-	}
-
-	var result []byte
-	var serialize func(*HuffmanNode)
-	serialize = func(node *HuffmanNode) {
-		if node.isLeaf {
-			result = append(result, 1) // 标记叶子节点
-			// 将float64转换为字节存储
-			bits := math.Float64bits(node.value)
-			valueBytes := make([]byte, 8)
-			binary.LittleEndian.PutUint64(valueBytes, bits)
-			result = append(result, valueBytes...)
-		} else {
-			result = append(result, 0) // 标记内部节点
-			serialize(node.left)
-			serialize(node.right)
+		if a.escape != b.escape {
+			return a.escape
 		}
-	}
-
-	serialize(root)
-	return result
+		return a.value < b.value
+	})
+	return syms
 }
 
-// CompressFloat 使用Huffman编码压缩浮点数数组
+// CompressFloat 格式：uvarint 值个数，1 字节最大码长 L，1 字节转义码长（0 表示没有转义），
+// L 个 uvarint 表示每个码长的符号个数，按范式顺序排列的字典值（同一码长内 uvarint 差分），编码位流。
+// 空输入不输出任何字节
 func CompressFloat(dst []byte, src []float64) []byte {
 	if len(src) == 0 {
 		return dst
 	}
-	// 1. 构建浮点数频率表
-	freq := buildFrequencyTable(src)
-
-	// 2. 构建Huffman树
-	root := buildHuffmanTree(freq)
-	if root == nil {
-		return dst
+	syms := buildSymbols(src)
+	maxLen := syms[len(syms)-1].length
+	escapeLen := 0
+	counts := make([]int, maxLen+1)
+	for _, s := range syms {
+		counts[s.length]++
+		if s.escape {
+			escapeLen = s.length
+		}
 	}
-	// 3. 生成编码表
-	codes := generateCodes(root)
-
-	// 4. 编码数据
-	var encodedBits string
-	for _, f := range src {
-		encodedBits += codes[f]
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	dst = append(dst, byte(maxLen), byte(escapeLen))
+	for _, c := range counts[1:] {
+		dst = binary.AppendUvarint(dst, uint64(c))
 	}
-
-	// 5. 序列化结果
-	// 格式: [原始长度(4字节)] + [树大小(4字节)] + [序列化的树] + [编码长度(4字节)] + [编码数据]
-
-	// 原始数据长度
-	originalLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(originalLen, uint32(len(src)))
-
-	// 序列化树
-	serializedTree := serializeTree(root)
-	treeSize := make([]byte, 4)
-	binary.LittleEndian.PutUint32(treeSize, uint32(len(serializedTree)))
-
-	// 编码数据
-	encodedData := encodeBits(encodedBits)
-	encodedLen := make([]byte, 4)
-	binary.LittleEndian.PutUint32(encodedLen, uint32(len(encodedBits)))
-
-	// 组装结果
-	dst = append(dst, originalLen...)
-	dst = append(dst, treeSize...)
-	dst = append(dst, serializedTree...)
-	dst = append(dst, encodedLen...)
-	dst = append(dst, encodedData...)
-
-	return dst
-}
-
-// deserializeTree 从字节数据重建Huffman树
-func deserializeTree(data []byte) (*HuffmanNode, int, error) {
-	if len(data) == 0 {
-		return nil, 0, fmt.Errorf("empty tree data")
+	prev := uint64(0)
+	for i, s := range syms {
+		if i > 0 && s.length != syms[i-1].length {
+			prev = 0
+		}
+		if !s.escape {
+			dst = binary.AppendUvarint(dst, s.value-prev)
+			prev = s.value
+		}
 	}
 
-	offset := 0
-	var deserialize func() (*HuffmanNode, error)
-	deserialize = func() (*HuffmanNode, error) {
-		if offset >= len(data) {
-			return nil, fmt.Errorf("unexpected end of tree data")
+	lengths := make([]int, len(syms))
+	for i, s := range syms {
+		lengths[i] = s.length
+	}
+	codes := canonicalCodes(lengths)
+	index := make(map[uint64]int, len(syms))
+	escape := -1
+	for i, s := range syms {
+		if s.escape {
+			escape = i
+		} else {
+			index[s.value] = i
 		}
-
-		nodeType := data[offset]
-		offset++
-
-		if nodeType == 1 { // 叶子节点
-			if offset+8 > len(data) {
-				return nil, fmt.Errorf("insufficient data for leaf node")
-			}
-
-			bits := binary.LittleEndian.Uint64(data[offset : offset+8])
-			value := math.Float64frombits(bits)
-			offset += 8
-
-			return &HuffmanNode{
-				value:  value,
-				isLeaf: true,
-			}, nil
-		} else { // 内部节点
-			left, err := deserialize()
-			if err != nil {
-				return nil, err
-			}
-
-			right, err := deserialize()
-			if err != nil {
-				return nil, err
-			}
-
-			return &HuffmanNode{
-				left:   left,
-				right:  right,
-				isLeaf: false,
-			}, nil
+	}
+	w := &bitWriter{dst: dst}
+	for _, f := range src {
+		v := math.Float64bits(f)
+		if i, ok := index[v]; ok {
+			w.writeBits(codes[i], uint(lengths[i]))
+			continue
 		}
+		w.writeBits(codes[escape], uint(lengths[escape]))
+		w.writeBits(v, 64)
 	}
-
-	root, err := deserialize()
-	return root, offset, err
+	return w.flush()
 }
 
-// DecompressFloat 解压缩浮点数数组
+// DecompressFloat 还原 CompressFloat，结果追加到 dst
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
 	if len(src) == 0 {
 		return dst, nil
 	}
-
-	offset := 0
-
-	// 1. 读取原始长度
-	if offset+4 > len(src) {
-		return nil, fmt.Errorf("insufficient data for original length")
-	}
-	originalLen := binary.LittleEndian.Uint32(src[offset : offset+4])
-	offset += 4
-
-	// 2. 读取树大小
-	if offset+4 > len(src) {
-		return nil, fmt.Errorf("insufficient data for tree size")
-	}
-	treeSize := binary.LittleEndian.Uint32(src[offset : offset+4])
-	offset += 4
-
-	// 3. 反序列化Huffman树
-	if offset+int(treeSize) > len(src) {
-		return nil, fmt.Errorf("insufficient data for tree")
+	n, m := binary.Uvarint(src)
+	if m <= 0 || len(src) < m+2 {
+		return nil, fmt.Errorf("huffman: invalid header")
+	}
+	maxLen, escapeLen := int(src[m]), int(src[m+1])
+	if maxLen == 0 || maxLen > maxCodeLen || escapeLen > maxLen {
+		return nil, fmt.Errorf("huffman: invalid code lengths %d/%d", maxLen, escapeLen)
+	}
+	src = src[m+2:]
+	counts := make([]int, maxLen+1)
+	total := 0
+	for l := 1; l <= maxLen; l++ {
+		c, k := binary.Uvarint(src)
+		// 每个字典值至少占 1 字节
+		if k <= 0 || c > uint64(len(src)) {
+			return nil, fmt.Errorf("huffman: invalid symbol count for length %d", l)
+		}
+		counts[l] = int(c)
+		total += int(c)
+		src = src[k:]
+	}
+	if escapeLen > 0 && counts[escapeLen] == 0 {
+		return nil, fmt.Errorf("huffman: missing escape code")
+	}
+
+	values := make([]uint64, 0, total)
+	escape := -1
+	for l := 1; l <= maxLen; l++ {
+		prev := uint64(0)
+		for j := 0; j < counts[l]; j++ {
+			if l == escapeLen && j == 0 {
+				escape = len(values)
+				values = append(values, 0)
+				continue
+			}
+			d, k := binary.Uvarint(src)
+			if k <= 0 {
+				return nil, fmt.Errorf("huffman: truncated dictionary")
+			}
+			prev += d
+			values = append(values, prev)
+			src = src[k:]
+		}
 	}
-	root, _, err := deserializeTree(src[offset : offset+int(treeSize)])
+	dec, err := newDecoder(counts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to deserialize tree: %v", err)
+		return nil, err
 	}
-	offset += int(treeSize)
-
-	// 4. 读取编码长度
-	if offset+4 > len(src) {
-		return nil, fmt.Errorf("insufficient data for encoded length")
-	}
-	encodedLen := binary.LittleEndian.Uint32(src[offset : offset+4])
-	offset += 4
-
-	// 5. 读取编码数据
-	encodedDataSize := (encodedLen + 7) / 8 // 计算字节数
-	if offset+int(encodedDataSize) > len(src) {
-		return nil, fmt.Errorf("insufficient data for encoded data")
+	// 每个值至少占 1 位
+	if n > uint64(len(src))*8 {
+		return nil, fmt.Errorf("huffman: %d values do not fit in %d bytes", n, len(src))
 	}
-	encodedData := src[offset : offset+int(encodedDataSize)]
 
-	// 6. 解码数据
-	result := dst
-	if cap(result)-len(result) < int(originalLen) {
-		newResult := make([]float64, len(result), len(result)+int(originalLen))
-		copy(newResult, result)
-		result = newResult
-	}
-
-	// 特殊情况：只有一个不同的浮点数
-	if root.isLeaf {
-		for i := 0; i < int(originalLen); i++ {
-			result = append(result, root.value)
+	r := &bitReader{src: src}
+	for i := uint64(0); i < n; i++ {
+		s, err := dec.decode(r)
+		if err != nil {
+			return nil, err
 		}
-		return result, nil
-	}
-
-	// 解码比特流
-	current := root
-	bitIndex := 0
-
-	for len(result)-len(dst) < int(originalLen) && bitIndex < int(encodedLen) {
-		// 读取当前比特
-		byteIndex := bitIndex / 8
-		bitPos := 7 - (bitIndex % 8)
-		bit := (encodedData[byteIndex] >> bitPos) & 1
-		bitIndex++
-
-		// 根据比特移动到左子树或右子树
-		if bit == 0 {
-			current = current.left
-		} else {
-			current = current.right
-		}
-
-		// 如果到达叶子节点，输出值并重置到根节点
-		if current.isLeaf {
-			result = append(result, current.value)
-			current = root
+		v := values[s]
+		if s == escape {
+			v = r.readBits(64)
 		}
+		dst = append(dst, math.Float64frombits(v))
 	}
-
-	// 检查是否解码了预期数量的浮点数
-	if len(result)-len(dst) != int(originalLen) {
-		return nil, fmt.Errorf("decoded %d values, expected %d",
-			len(result)-len(dst), originalLen)
+	if r.overrun() {
+		return nil, fmt.Errorf("huffman: encoded data is truncated")
 	}
-
-	return result, nil
+	return dst, nil
 }
@@ -0,0 +1,136 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// header 返回最大码长和转义码长
+func header(t *testing.T, out []byte) (int, int) {
+	t.Helper()
+	_, m := binary.Uvarint(out)
+	if m <= 0 || len(out) < m+2 {
+		t.Fatalf("%d bytes, no header", len(out))
+	}
+	return int(out[m]), int(out[m+1])
+}
+
+// fibonacci 第 v 个值出现 fib(v+3) 次，打乱顺序。不限制码长时最长的码有 n-1 位
+func fibonacci(n int) []float64 {
+	var data []float64
+	a, b := 2, 3
+	for v := 0; v < n; v++ {
+		for i := 0; i < a; i++ {
+			data = append(data, float64(v))
+		}
+		a, b = b, a+b
+	}
+	rng := rand.New(rand.NewSource(1))
+	rng.Shuffle(len(data), func(i, j int) { data[i], data[j] = data[j], data[i] })
+	return data
+}
+
+func TestCompressFloat(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloat, DecompressFloat, c.Data)
+	}
+}
+
+// 只有一种值时码长为 1，没有转义；只出现一次的值走转义码加 64 位原值
+func TestEscape(t *testing.T) {
+	repeated := make([]float64, 100)
+	for i := range repeated {
+		repeated[i] = math.NaN()
+	}
+	out := codectest.RoundTripFloats(t, "repeated", CompressFloat, DecompressFloat, repeated)
+	if maxLen, escapeLen := header(t, out); maxLen != 1 || escapeLen != 0 {
+		t.Errorf("repeated: code lengths %d/%d, expected 1/0", maxLen, escapeLen)
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	mixed := make([]float64, 1000)
+	for i := range mixed {
+		mixed[i] = float64(i % 10)
+		if i%7 == 0 {
+			mixed[i] = math.Float64frombits(rng.Uint64())
+		}
+	}
+	out = codectest.RoundTripFloats(t, "mixed", CompressFloat, DecompressFloat, mixed)
+	if _, escapeLen := header(t, out); escapeLen == 0 {
+		t.Error("mixed: no escape code")
+	}
+}
+
+// 不限制时最长的码有 maxCodeLen 位，限制不起作用，码长与普通 Huffman 相同
+func TestCodeLengthsAtLimit(t *testing.T) {
+	freqs := make([]uint64, maxCodeLen+1)
+	a, b := uint64(1), uint64(2)
+	for i := range freqs {
+		freqs[i] = a
+		a, b = b, a+b
+	}
+	lengths := codeLengths(freqs, maxCodeLen)
+	for i, l := range lengths {
+		want := maxCodeLen - i + 1
+		if i == 0 {
+			want = maxCodeLen
+		}
+		if l != want {
+			t.Fatalf("symbol %d: code length %d, expected %d", i, l, want)
+		}
+	}
+	// 2^k 个等频符号在上限为 k 时全部取满 k 位
+	for k := 1; k <= 10; k++ {
+		equal := make([]uint64, 1<<k)
+		for i := range equal {
+			equal[i] = 1
+		}
+		for i, l := range codeLengths(equal, k) {
+			if l != k {
+				t.Fatalf("%d equal symbols: symbol %d has code length %d", 1<<k, i, l)
+			}
+		}
+	}
+}
+
+func TestCodeLengths(t *testing.T) {
+	freqs := make([]uint64, 40)
+	a, b := uint64(1), uint64(1)
+	for i := range freqs {
+		freqs[i] = a
+		a, b = b, a+b
+	}
+	lengths := codeLengths(freqs, maxCodeLen)
+	// Kraft 不等式：sum 2^-l <= 1，且没有超过上限的码长
+	kraft := 0.0
+	for i, l := range lengths {
+		if l < 1 || l > maxCodeLen {
+			t.Fatalf("symbol %d: code length %d", i, l)
+		}
+		kraft += math.Ldexp(1, -l)
+	}
+	if kraft > 1 {
+		t.Errorf("Kraft sum %v exceeds 1", kraft)
+	}
+}
+
+// 码长被限制在 maxCodeLen，超过查找表位数的码按码长逐级解码；头部声明更长的码长时返回错误
+func TestMaxDepth(t *testing.T) {
+	for _, n := range []int{maxCodeLen + 1, maxCodeLen + 4} {
+		data := fibonacci(n)
+		out := codectest.RoundTripFloats(t, "fibonacci", CompressFloat, DecompressFloat, data)
+		maxLen, _ := header(t, out)
+		if maxLen != maxCodeLen {
+			t.Errorf("%d symbols: maximum code length %d, expected %d", n, maxLen, maxCodeLen)
+		}
+		_, m := binary.Uvarint(out)
+		corrupt := append([]byte(nil), out...)
+		corrupt[m] = maxCodeLen + 1
+		if _, err := DecompressFloat(nil, corrupt); err == nil {
+			t.Errorf("%d symbols: expected an error for code length %d", n, maxCodeLen+1)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package lz77
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"myalgo/algorithms/zstd"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// periodic n 个值，每 period 个值重复一个两位小数的日周期；noise 为每个值被随机扰动的概率
+func periodic(n, period int, noise float64) []float64 {
+	rng := rand.New(rand.NewSource(int64(period)))
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = math.Round((20+5*math.Sin(2*math.Pi*float64(i%period)/float64(period)))*100) / 100
+		if rng.Float64() < noise {
+			data[i] += float64(rng.Intn(100)) / 100
+		}
+	}
+	return data
+}
+
+func TestCompressFloat(t *testing.T) {
+	cases := append(codectest.Floats(), codectest.FloatCase{Name: "periodic", Data: periodic(5000, 96, 0)})
+	for _, c := range cases {
+		codectest.RoundTripFloats(t, c.Name+"/byte", CompressFloat, DecompressFloat, c.Data)
+		codectest.RoundTripFloats(t, c.Name+"/aligned", CompressFloatAligned, DecompressFloat, c.Data)
+	}
+}
+
+// repeatBlock 把 size 字节的随机块重复 times 次
+func repeatBlock(size, times int) []byte {
+	block := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(block)
+	var data []byte
+	for i := 0; i < times; i++ {
+		data = append(data, block...)
+	}
+	return data
+}
+
+// 距离恰好等于窗口时能匹配，多一个单位就不能；哈希链的循环表多次回绕后不会沿被覆盖的旧项找到窗口外的位置
+func TestWindow(t *testing.T) {
+	const window = 1 << 10
+	for _, aligned := range []bool{false, true} {
+		opts := Options{Window: window, MaxChain: 64, Aligned: aligned, Entropy: "none"}
+		m := newMatcher(repeatBlock(window, 2), opts)
+		m.advance(window)
+		if length, offset := m.find(window); length != window || offset != window {
+			t.Errorf("aligned %v: match of %d bytes at distance %d, expected %d at %d", aligned, length, offset, window, window)
+		}
+		m = newMatcher(repeatBlock(window+m.unit, 2), opts)
+		m.advance(window + m.unit)
+		if length, _ := m.find(window + m.unit); length != 0 {
+			t.Errorf("aligned %v: match of %d bytes beyond the window", aligned, length)
+		}
+
+		for _, c := range []struct {
+			period int
+			fits   bool
+		}{{window, true}, {window + m.unit, false}} {
+			src := repeatBlock(c.period, 16)
+			out, err := CompressBytes(nil, src, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecompressBytes(nil, out)
+			if err != nil || string(got) != string(src) {
+				t.Fatalf("aligned %v period %d: %d bytes, %v", aligned, c.period, len(got), err)
+			}
+			if compressed := len(out) < 2*c.period; compressed != c.fits {
+				t.Errorf("aligned %v period %d: %d bytes compressed into %d", aligned, c.period, len(src), len(out))
+			}
+		}
+	}
+}
+
+// 字节流中的匹配长度没有上限，重叠的匹配一次复制整段；按符号编码时匹配长度不超过 maxMatchLength
+func TestMaxMatchLength(t *testing.T) {
+	zeros := make([]byte, 1<<20)
+	for _, aligned := range []bool{false, true} {
+		opts := Options{Window: 1 << 10, MaxChain: 8, Aligned: aligned, Entropy: "none"}
+		out, err := CompressBytes(nil, zeros, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecompressBytes(nil, out)
+		if err != nil || string(got) != string(zeros) {
+			t.Fatalf("aligned %v: %d bytes, %v", aligned, len(got), err)
+		}
+		if len(out) > 32 {
+			t.Errorf("aligned %v: %d zeros compressed into %d bytes, expected one match", aligned, len(zeros), len(out))
+		}
+	}
+
+	src := make([]uint64, 2*maxMatchLength+10)
+	encoded := CompressSymbols(nil, src)
+	got, err := DecompressSymbols(nil, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codectest.EqualUint64s(t, "symbols", got, src)
+	longest := 0
+	for i := 0; i < len(encoded); {
+		if encoded[i] == symbolTokenLiteral {
+			i += 2 + int(encoded[i+1])
+			continue
+		}
+		if l := int(encoded[i+2]); l > longest {
+			longest = l
+		}
+		i += 3
+	}
+	if longest != maxMatchLength {
+		t.Errorf("longest match %d, expected %d", longest, maxMatchLength)
+	}
+}
+
+// 每种熵编码和匹配方式都能还原，包括重叠的匹配
+func TestCompressBytes(t *testing.T) {
+	src := []byte("abababababababababab hello hello hello xyz")
+	for _, c := range entropyCoders {
+		for _, aligned := range []bool{false, true} {
+			opts := Options{Window: 1 << 10, MaxChain: 8, Lazy: true, Aligned: aligned, Entropy: c.Name}
+			out, err := CompressBytes(nil, src, opts)
+			if err != nil {
+				t.Fatalf("%+v: %v", opts, err)
+			}
+			got, err := DecompressBytes(nil, out)
+			if err != nil || string(got) != string(src) {
+				t.Fatalf("%+v: got %q, %v", opts, got, err)
+			}
+		}
+	}
+}
+
+func TestCompressBytesUnknownEntropy(t *testing.T) {
+	if _, err := CompressBytes(nil, []byte("abc"), Options{Entropy: "lzma"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// BenchmarkPeriodic 在周期数据上对比 lz77 和 zstd，ratio 为原始大小与压缩后大小之比
+func BenchmarkPeriodic(b *testing.B) {
+	codecs := []struct {
+		name     string
+		compress func([]byte, []float64) []byte
+	}{
+		{"lz77", CompressFloat},
+		{"lz77Aligned", CompressFloatAligned},
+		{"zstd", zstd.CompressFloat},
+	}
+	for _, period := range []int{24, 96, 1440} {
+		for _, noise := range []float64{0, 0.01} {
+			data := periodic(100_000, period, noise)
+			for _, c := range codecs {
+				b.Run(fmt.Sprintf("period=%d/noise=%v/%s", period, noise, c.name), func(b *testing.B) {
+					b.SetBytes(int64(len(data) * 8))
+					var out []byte
+					for i := 0; i < b.N; i++ {
+						out = c.compress(out[:0], data)
+					}
+					b.ReportMetric(float64(len(data)*8)/float64(len(out)), "ratio")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package lz77
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const hashBits = 18
+
+// matcher 哈希链匹配器。head 记录每个哈希值最近出现的位置，prev 是按窗口大小循环使用的链表，位置都加 1 存放，0 表示空
+type matcher struct {
+	data     []byte
+	unit     int // 匹配的起点和长度都是 unit 的倍数，按值对齐时为 8
+	minMatch int
+	window   int
+	maxChain int
+	head     []int32
+	prev     []int32
+	inserted int // 已插入哈希链的位置上界
+}
+
+func newMatcher(data []byte, opts Options) *matcher {
+	m := &matcher{data: data, unit: 1, minMatch: minByteMatch, maxChain: max(opts.MaxChain, 1)}
+	if opts.Aligned {
+		m.unit, m.minMatch = valueSize, valueSize
+	}
+	m.window = 1 << bits.Len(uint(max(opts.Window, m.unit)-1))
+	m.head = make([]int32, 1<<hashBits)
+	m.prev = make([]int32, m.window/m.unit)
+	return m
+}
+
+// hash 按值对齐时对整个 8 字节值取哈希，否则取前 4 字节
+func (m *matcher) hash(pos int) uint32 {
+	var v uint64
+	if m.unit == valueSize {
+		v = binary.LittleEndian.Uint64(m.data[pos:])
+	} else {
+		v = uint64(binary.LittleEndian.Uint32(m.data[pos:]))
+	}
+	return uint32(v * 0x9E3779B97F4A7C15 >> (64 - hashBits))
+}
+
+func (m *matcher) slot(pos int) int {
+	return pos / m.unit & (len(m.prev) - 1)
+}
+
+// advance 把 end 之前还没插入的位置加入哈希链
+func (m *matcher) advance(end int) {
+	for ; m.inserted < end; m.inserted += m.unit {
+		if m.inserted+m.minMatch > len(m.data) {
+			continue
+		}
+		h := m.hash(m.inserted)
+		m.prev[m.slot(m.inserted)] = m.head[h]
+		m.head[h] = int32(m.inserted + 1)
+	}
+}
+
+// find 在窗口内沿哈希链找 pos 处最长的匹配，返回长度和距离，没有时长度为 0
+func (m *matcher) find(pos int) (length, offset int) {
+	if pos+m.minMatch > len(m.data) {
+		return 0, 0
+	}
+	cand := int(m.head[m.hash(pos)]) - 1
+	for chain := 0; chain < m.maxChain && cand >= 0 && pos-cand <= m.window; chain++ {
+		// 先比较当前最长匹配之后的那个字节，不可能更长的候选不必完整比较
+		if pos+length >= len(m.data) || m.data[cand+length] == m.data[pos+length] {
+			if l := m.matchLen(cand, pos); l > length {
+				length, offset = l, pos-cand
+			}
+		}
+		next := int(m.prev[m.slot(cand)]) - 1
+		// 循环表中的旧项已被更新的位置覆盖
+		if next >= cand {
+			break
+		}
+		cand = next
+	}
+	if length < m.minMatch {
+		return 0, 0
+	}
+	return length, offset
+}
+
+func (m *matcher) matchLen(cand, pos int) int {
+	n := 0
+	for pos+n+8 <= len(m.data) {
+		if x := binary.LittleEndian.Uint64(m.data[cand+n:]) ^ binary.LittleEndian.Uint64(m.data[pos+n:]); x != 0 {
+			n += bits.TrailingZeros64(x) / 8
+			return n - n%m.unit
+		}
+		n += 8
+	}
+	for pos+n < len(m.data) && m.data[cand+n] == m.data[pos+n] {
+		n++
+	}
+	return n - n%m.unit
+}
@@ -0,0 +1,213 @@
+package lz77
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"myalgo/algorithms/ans"
+	"myalgo/algorithms/huffmanLib"
+)
+
+const (
+	// minByteMatch 按字节匹配时的最短匹配，与哈希的字节数相同
+	minByteMatch = 4
+	// valueSize 按值对齐时一个值的字节数，也是最短匹配
+	valueSize = 8
+)
+
+// Options 匹配参数和熵编码方式。Aligned 和 Entropy 写入流中，解压时不需要提供
+type Options struct {
+	Window   int    // 窗口字节数，向上取整到 2 的幂
+	MaxChain int    // 每个位置最多比较的候选个数
+	Lazy     bool   // 先看下一个位置有没有更长的匹配再决定是否输出
+	Aligned  bool   // 只在 8 字节边界上匹配整值，周期性的 float64/uint64 序列会变成长匹配
+	Entropy  string // 序列流的熵编码："none"、"ans" 或 "huffman"
+}
+
+// DefaultOptions 按字节匹配
+var DefaultOptions = Options{Window: 1 << 20, MaxChain: 16, Lazy: true, Entropy: "ans"}
+
+// ValueOptions 按 8 字节值匹配
+var ValueOptions = Options{Window: 1 << 20, MaxChain: 64, Lazy: true, Aligned: true, Entropy: "ans"}
+
+type entropyCoder struct {
+	Name       string
+	Compress   func(dst []byte, src []byte) []byte
+	Decompress func(dst []byte, src []byte) ([]byte, error)
+}
+
+// entropyCoders 下标即流中的编号
+var entropyCoders = []entropyCoder{
+	{"none", nil, nil},
+	{"ans", ans.CompressBytes, ans.DecompressBytes},
+	{"huffman", huffmanLib.CompressBytes, huffmanLib.DecompressBytes},
+}
+
+func findEntropy(name string) (int, error) {
+	for i, c := range entropyCoders {
+		if c.Name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("lz77: unknown entropy coder %q", name)
+}
+
+// sequences 解析结果拆成的四个流，每个序列是一段字面量加一个匹配
+type sequences struct {
+	literals   []byte
+	litLens    []byte // uvarint 字面量长度
+	matchLens  []byte // uvarint 匹配长度，以 unit 计并减去最短匹配后加 1，0 表示最后一个没有匹配的序列
+	offsets    []byte // uvarint 匹配距离，以 unit 计，0 表示沿用上一个距离
+	lastOffset int
+}
+
+func (s *sequences) add(literals []byte, length, offset, unit, minMatch int) {
+	s.literals = append(s.literals, literals...)
+	s.litLens = binary.AppendUvarint(s.litLens, uint64(len(literals)))
+	if length == 0 {
+		s.matchLens = binary.AppendUvarint(s.matchLens, 0)
+		return
+	}
+	s.matchLens = binary.AppendUvarint(s.matchLens, uint64((length-minMatch)/unit+1))
+	if offset == s.lastOffset {
+		s.offsets = binary.AppendUvarint(s.offsets, 0)
+	} else {
+		s.offsets = binary.AppendUvarint(s.offsets, uint64(offset/unit))
+	}
+	s.lastOffset = offset
+}
+
+// CompressBytes 格式：uvarint 原始长度，1 字节标志（最低位表示按值对齐），1 字节熵编码编号，
+// 之后是字面量、字面量长度、匹配长度、距离四个流，每个流为 uvarint 长度加熵编码后的数据。
+// opts.Entropy 不是已知的熵编码时返回错误
+func CompressBytes(dst []byte, src []byte, opts Options) ([]byte, error) {
+	coder, err := findEntropy(opts.Entropy)
+	if err != nil {
+		return nil, err
+	}
+	m := newMatcher(src, opts)
+	var s sequences
+	pos, litStart := 0, 0
+	for pos+m.minMatch <= len(src) {
+		m.advance(pos)
+		length, offset := m.find(pos)
+		if length == 0 {
+			pos += m.unit
+			continue
+		}
+		for opts.Lazy && pos+m.unit+m.minMatch <= len(src) {
+			m.advance(pos + m.unit)
+			l, o := m.find(pos + m.unit)
+			if l <= length {
+				break
+			}
+			pos += m.unit
+			length, offset = l, o
+		}
+		s.add(src[litStart:pos], length, offset, m.unit, m.minMatch)
+		pos += length
+		litStart = pos
+	}
+	s.add(src[litStart:], 0, 0, m.unit, m.minMatch)
+
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	flags := byte(0)
+	if opts.Aligned {
+		flags |= 1
+	}
+	dst = append(dst, flags, byte(coder))
+	for _, stream := range [][]byte{s.literals, s.litLens, s.matchLens, s.offsets} {
+		if c := entropyCoders[coder]; c.Compress != nil {
+			stream = c.Compress(nil, stream)
+		}
+		dst = binary.AppendUvarint(dst, uint64(len(stream)))
+		dst = append(dst, stream...)
+	}
+	return dst, nil
+}
+
+// DecompressBytes 还原 CompressBytes，结果追加到 dst
+func DecompressBytes(dst []byte, src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || len(src) < k+2 {
+		return nil, errors.New("lz77: invalid header")
+	}
+	flags, coder := src[k], int(src[k+1])
+	if coder >= len(entropyCoders) {
+		return nil, fmt.Errorf("lz77: unknown entropy coder %d", coder)
+	}
+	unit, minMatch := 1, minByteMatch
+	if flags&1 != 0 {
+		unit, minMatch = valueSize, valueSize
+	}
+	src = src[k+2:]
+	var streams [4][]byte
+	for i := range streams {
+		size, k := binary.Uvarint(src)
+		if k <= 0 || size > uint64(len(src)-k) {
+			return nil, errors.New("lz77: truncated stream")
+		}
+		streams[i] = src[k : k+int(size)]
+		src = src[k+int(size):]
+		if c := entropyCoders[coder]; c.Decompress != nil {
+			var err error
+			if streams[i], err = c.Decompress(nil, streams[i]); err != nil {
+				return nil, fmt.Errorf("lz77: %w", err)
+			}
+		}
+	}
+	literals, litLens, matchLens, offsets := streams[0], streams[1], streams[2], streams[3]
+
+	start := len(dst)
+	lastOffset := 0
+	readUvarint := func(b *[]byte) (int, error) {
+		v, k := binary.Uvarint(*b)
+		if k <= 0 || v > n {
+			return 0, errors.New("lz77: invalid sequence")
+		}
+		*b = (*b)[k:]
+		return int(v), nil
+	}
+	for len(litLens) > 0 {
+		litLen, err := readUvarint(&litLens)
+		if err != nil {
+			return nil, err
+		}
+		if litLen > len(literals) {
+			return nil, errors.New("lz77: truncated literals")
+		}
+		dst = append(dst, literals[:litLen]...)
+		literals = literals[litLen:]
+
+		field, err := readUvarint(&matchLens)
+		if err != nil {
+			return nil, err
+		}
+		if field == 0 {
+			continue
+		}
+		length := (field-1)*unit + minMatch
+		offset, err := readUvarint(&offsets)
+		if err != nil {
+			return nil, err
+		}
+		if offset == 0 {
+			offset = lastOffset
+		} else {
+			offset *= unit
+		}
+		if offset <= 0 || offset > len(dst)-start || uint64(len(dst)-start+length) > n {
+			return nil, errors.New("lz77: invalid match")
+		}
+		lastOffset = offset
+		// 距离小于长度时源和目标重叠，需要逐字节复制
+		from := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[from+i])
+		}
+	}
+	if uint64(len(dst)-start) != n {
+		return nil, fmt.Errorf("lz77: decoded %d bytes, expected %d", len(dst)-start, n)
+	}
+	return dst, nil
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"myalgo/common"
+	"sync"
+	"time"
+)
+
+// AdaptiveOptions 在线自适应压缩参数
+type AdaptiveOptions struct {
+	BlockLen int // 每块数值个数
+	// TryFraction 额外试用一个候选参数组合的块比例，试用结果更小时采用试用结果
+	TryFraction float64
+	// Explore UCB 探索系数，越大越倾向尝试观测次数少的组合
+	Explore float64
+	// Decay 每块之后历史统计的衰减系数 (0,1]，小于 1 时可以跟随数据分布的漂移
+	Decay float64
+	// SpeedWeight 每微秒压缩耗时（含校验用的解压）折算的字节数，0 表示只看压缩率
+	SpeedWeight float64
+	Seed        int64
+	// Tracer 接收每块选择的参数组合，为 nil 时静默
+	Tracer common.Tracer
+}
+
+// DefaultAdaptiveOptions 默认在线自适应参数
+func DefaultAdaptiveOptions() AdaptiveOptions {
+	return AdaptiveOptions{
+		BlockLen:    1024,
+		TryFraction: 0.1,
+		Explore:     0.1,
+		Decay:       0.99,
+		Seed:        114514,
+	}
+}
+
+// armStats 一个参数组合的（衰减后的）观测次数和累计收益
+type armStats struct {
+	param  []int
+	pulls  float64
+	reward float64
+}
+
+// AdaptiveCompressor 按块压缩长时间运行的数据流，用折扣 UCB 在合法参数组合之间分配流量。
+// 每块都记录自己的参数，解压不依赖压缩器状态
+type AdaptiveCompressor struct {
+	mu   sync.Mutex
+	opts AdaptiveOptions
+	rng  *rand.Rand
+	arms []*armStats
+	best int  // 当前平均收益最高的组合
+	safe int  // 对任意数据都能还原的组合，其他组合都失败时使用
+	warm bool // 是否已完成首块的全量评估
+}
+
+func NewAdaptiveCompressor(opts AdaptiveOptions) *AdaptiveCompressor {
+	c := &AdaptiveCompressor{opts: opts, rng: rand.New(rand.NewSource(opts.Seed)), best: -1}
+	for ranged := range rangedFunc {
+		for scale := range scaleFunc {
+			for del := range delFunc {
+				for algo := range compressFunc {
+					param := []int{ranged, scale, del, algo}
+					if !compatibleParam(param) {
+						continue
+					}
+					if equalParam(param, fallbackSelector.Select(nil)) {
+						c.best, c.safe = len(c.arms), len(c.arms)
+					}
+					c.arms = append(c.arms, &armStats{param: param})
+				}
+			}
+		}
+	}
+	return c
+}
+
+// CompressFloat 逐块压缩：每块为 uvarint 长度 + RunCompressWithParam 的输出
+func (c *AdaptiveCompressor) CompressFloat(dst []byte, src []float64) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tr := common.OrNop(c.opts.Tracer)
+	blockLen := max(c.opts.BlockLen, 1)
+	for i := 0; i < len(src); i += blockLen {
+		block := src[i:min(i+blockLen, len(src))]
+		chosen := c.best
+		out := c.play(chosen, block)
+		if !c.warm {
+			// 第一块用所有组合压缩一遍作为初始统计，避免按表顺序逐个探索
+			for j := range c.arms {
+				if j == chosen {
+					continue
+				}
+				if altOut := c.play(j, block); altOut != nil && (out == nil || len(altOut) < len(out)) {
+					chosen, out = j, altOut
+				}
+			}
+			c.warm = true
+		} else if len(c.arms) > 1 && c.rng.Float64() < c.opts.TryFraction {
+			if alt := c.candidate(); alt != chosen {
+				if altOut := c.play(alt, block); altOut != nil && (out == nil || len(altOut) < len(out)) {
+					chosen, out = alt, altOut
+				}
+			}
+		}
+		if out == nil {
+			chosen, out = c.safe, RunCompressWithParam(nil, block, c.arms[c.safe].param)
+		}
+		tr.Trace(common.TraceEvent{Stage: common.StageSelect, Name: "adaptive", InBytes: len(block) * 8, OutBytes: len(out),
+			Detail: fmt.Sprintf("block %d %s", i/blockLen, describeParam(c.arms[chosen].param))})
+		dst = binary.AppendUvarint(dst, uint64(len(out)))
+		dst = append(dst, out...)
+		c.decay()
+		c.best = c.leader()
+	}
+	return dst
+}
+
+// play 用第 i 个组合压缩一块并更新其统计，不能还原这一块时返回 nil，收益记为 0
+func (c *AdaptiveCompressor) play(i int, block []float64) []byte {
+	start := time.Now()
+	out, ok := compressChecked(block, c.arms[i].param)
+	c.arms[i].pulls++
+	if !ok {
+		return nil
+	}
+	cost := float64(len(out)) + c.opts.SpeedWeight*float64(time.Since(start).Microseconds())
+	reward := 1 - cost/float64(len(block)*8)
+	c.arms[i].reward += math.Max(0, math.Min(1, reward))
+	return out
+}
+
+// candidate UCB 选出下一个试用的组合，未观测过的组合优先
+func (c *AdaptiveCompressor) candidate() int {
+	total := 0.0
+	for _, arm := range c.arms {
+		total += arm.pulls
+	}
+	best, bestScore := 0, math.Inf(-1)
+	for i, arm := range c.arms {
+		if i == c.best {
+			continue
+		}
+		if arm.pulls == 0 {
+			return i
+		}
+		score := arm.reward/arm.pulls + c.opts.Explore*math.Sqrt(math.Log(total+1)/arm.pulls)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// leader 平均收益最高的组合
+func (c *AdaptiveCompressor) leader() int {
+	best, bestMean := c.best, math.Inf(-1)
+	for i, arm := range c.arms {
+		if arm.pulls == 0 {
+			continue
+		}
+		if mean := arm.reward / arm.pulls; mean > bestMean {
+			best, bestMean = i, mean
+		}
+	}
+	return best
+}
+
+func (c *AdaptiveCompressor) decay() {
+	if c.opts.Decay <= 0 || c.opts.Decay >= 1 {
+		return
+	}
+	for _, arm := range c.arms {
+		arm.pulls *= c.opts.Decay
+		arm.reward *= c.opts.Decay
+	}
+}
+
+// CompressFloatAdaptive 使用新的 AdaptiveCompressor 压缩一次，适合作为普通的 CompressFloat 使用
+func CompressFloatAdaptive(dst []byte, src []float64) []byte {
+	return NewAdaptiveCompressor(DefaultAdaptiveOptions()).CompressFloat(dst, src)
+}
+
+// DecompressFloatAdaptive 解压 AdaptiveCompressor 的输出
+func DecompressFloatAdaptive(dst []float64, src []byte) ([]float64, error) {
+	for block := 0; len(src) > 0; block++ {
+		n, m := binary.Uvarint(src)
+		if m <= 0 || uint64(len(src)-m) < n {
+			return nil, fmt.Errorf("invalid data: block %d is truncated", block)
+		}
+		// 逆变换作用于整个切片，因此每块单独解压后再追加
+		values, err := RunDecompress(nil, src[m:m+int(n)])
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", block, err)
+		}
+		dst = append(dst, values...)
+		src = src[m+int(n):]
+	}
+	return dst, nil
+}
@@ -1,21 +1,40 @@
 package model
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
 	"fmt"
 	"log"
 	"math"
 	"myalgo/common"
+	"os"
+	"runtime"
 	"strconv"
+	"sync"
 )
 
 const segmentLen = 5000 // 每段长度
 var trainDataLen = 0    // 训练集大小
 
 func GetTrainData(dataset int) {
+	getTrainData(dataset, false)
+}
+
+// ResumeTrainData 继续一次被中断的 GetTrainData：保留已写入的完整行，从下一段开始生成
+func ResumeTrainData(dataset int) {
+	getTrainData(dataset, true)
+}
+
+func getTrainData(dataset int, resume bool) {
 	featureFile := "./dataset/train_features.csv"
 	labelFile := "./dataset/train_labels.csv"
-	featWriter := newCSVWriter(featureFile)
-	labelWriter := newCSVWriter(labelFile)
+	done := 0
+	if resume {
+		done = truncateToCommonRows(featureFile, labelFile)
+	}
+	featWriter := openCSVWriter(featureFile, resume)
+	labelWriter := openCSVWriter(labelFile, resume)
 	// 把两种情况统一起来 读取的数据都用numbers表示
 	var numbers []float64
 	var err error
@@ -32,8 +51,11 @@ func GetTrainData(dataset int) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if done > 0 {
+		fmt.Printf("Resume train data from %d / %d \n", done, trainDataLen)
+	}
 
-	for i := 0; i+segmentLen <= len(numbers); i += segmentLen {
+	for i := done * segmentLen; i+segmentLen <= len(numbers); i += segmentLen {
 		segment := numbers[i : i+segmentLen]
 
 		stats := common.AnalyzeTimeSeries((segment))
@@ -55,6 +77,9 @@ func GetTrainData(dataset int) {
 			strconv.Itoa(param[2]),
 			strconv.Itoa(param[3]),
 		})
+		// 每段写完立即落盘，中断后可以用 ResumeTrainData 继续
+		featWriter.Flush()
+		labelWriter.Flush()
 		fmt.Printf("Generate train data: %d / %d \n", i/segmentLen, trainDataLen)
 	}
 
@@ -64,28 +89,148 @@ func GetTrainData(dataset int) {
 	fmt.Println("✅ 已生成训练集:", featureFile, labelFile)
 }
 
-func findBestCombo(segment []float64) []int {
-	bestSize := math.Inf(1)
-	param := make([]int, 4)
-	bestParam := make([]int, 4)
+// openCSVWriter resume 为 true 时追加写入，否则覆盖
+func openCSVWriter(path string, resume bool) *csv.Writer {
+	if !resume {
+		return newCSVWriter(path)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return csv.NewWriter(f)
+}
+
+// truncateToCommonRows 丢弃末尾不完整的行，并将两个文件截断到相同的行数，返回保留的行数
+func truncateToCommonRows(featureFile, labelFile string) int {
+	featLines := completeLines(featureFile)
+	labelLines := completeLines(labelFile)
+	done := min(len(featLines), len(labelLines))
+	for path, lines := range map[string][][]byte{featureFile: featLines, labelFile: labelLines} {
+		if err := os.WriteFile(path, bytes.Join(lines[:done], nil), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return done
+}
+
+// completeLines 读取文件中以换行结尾的行（包含换行符），文件不存在时返回空
+func completeLines(path string) [][]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lines [][]byte
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return lines
+		}
+		lines = append(lines, data[:i+1])
+		data = data[i+1:]
+	}
+}
+
+// comboCandidate 一个待评估的参数组合：data 为三个变换阶段的输出，header 为头部字节数
+type comboCandidate struct {
+	param  []int
+	data   []float64
+	header int
+}
+
+// prepareCombos 按 ranged -> scale -> del 逐级缓存变换结果，同一变换输出由所有压缩器共享
+func prepareCombos(segment []float64) []comboCandidate {
+	var candidates []comboCandidate
 	for ranged := range rangedFunc {
+		rData, rSide := rangedFunc[ranged].transfer(append([]float64(nil), segment...))
 		for scale := range scaleFunc {
+			sData, sSide := scaleFunc[scale].transfer(append([]float64(nil), rData...))
 			for del := range delFunc {
+				dData, dSide := delFunc[del].transfer(append([]float64(nil), sData...))
+				header := 4 + sideSize(rSide) + sideSize(sSide) + sideSize(dSide)
 				for algo := range compressFunc {
-					var dst []byte
-					param[0] = ranged
-					param[1] = scale
-					param[2] = del
-					param[3] = algo
-					dst = RunCompressWithParam(dst, segment, param)
-					size := float64(len(dst))
-					if size < bestSize {
-						bestSize = size
-						bestParam[0], bestParam[1], bestParam[2], bestParam[3] = ranged, scale, del, algo
+					param := []int{ranged, scale, del, algo}
+					if compatibleParam(param) {
+						candidates = append(candidates, comboCandidate{param: param, data: dData, header: header})
 					}
 				}
 			}
 		}
 	}
-	return bestParam
+	return candidates
+}
+
+// sideSize side 数据在头部占用的字节数（uvarint 长度 + 内容）
+func sideSize(side []byte) int {
+	return len(binary.AppendUvarint(nil, uint64(len(side)))) + len(side)
+}
+
+// findBestCombo 并发评估所有合法组合，返回压缩后最小且能按位还原的参数（大小相同时取遍历顺序靠前的组合，与串行结果一致）
+func findBestCombo(segment []float64) []int {
+	candidates := prepareCombos(segment)
+	best, _ := searchCombos(segment, candidates)
+	if best < 0 {
+		return fallbackSelector.Select(segment)
+	}
+	return append([]int(nil), candidates[best].param...)
+}
+
+// searchCombos 返回最优候选的下标（没有可用候选时为 -1）和提前放弃的候选数。
+// 后端压缩时以当前最优大小减去头部作为上限，已写出的字节超过上限时放弃：总大小不小于已写出的部分，放弃它们不影响结果。
+// 只有比当前最优更小的候选才用 compressChecked 校验能否按位还原，与采样选择器和自适应压缩的规则一致
+func searchCombos(segment []float64, candidates []comboCandidate) (best, pruned int) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		bestSize = math.MaxInt
+		next     = make(chan int)
+	)
+	best = -1
+	// better 判断 size 能否取代当前最优，调用方持有 mu
+	better := func(i, size int) bool {
+		return size < bestSize || (size == bestSize && i < best)
+	}
+	for w := 0; w < min(runtime.GOMAXPROCS(0), len(candidates)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				c := candidates[i]
+				backend := compressFunc[c.param[3]]
+				mu.Lock()
+				limit := bestSize - c.header
+				mu.Unlock()
+				out, ok := []byte(nil), limit >= 0
+				if ok && backend.bounded != nil {
+					out, ok = backend.bounded(nil, c.data, limit)
+				} else if ok {
+					out = backend.compress(nil, c.data)
+				}
+				mu.Lock()
+				if !ok {
+					pruned++
+				}
+				improves := ok && better(i, c.header+len(out))
+				mu.Unlock()
+				if !improves {
+					continue
+				}
+				size := c.header + len(out)
+				if _, ok := compressChecked(segment, c.param); !ok {
+					continue
+				}
+				mu.Lock()
+				if better(i, size) {
+					bestSize, best = size, i
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range candidates {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return best, pruned
 }
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// bounded 在上限足够时与 compress 输出相同，上限比输出少 1 字节时放弃
+func TestBoundedBackends(t *testing.T) {
+	data := make([]float64, 3000)
+	for i := range data {
+		data[i] = math.Round((20+math.Sin(float64(i)/10))*100) / 100
+	}
+	for _, backend := range compressFunc {
+		if backend.bounded == nil {
+			continue
+		}
+		full := backend.compress(nil, data)
+		if out, ok := backend.bounded(nil, data, len(full)); !ok || !bytes.Equal(out, full) {
+			t.Errorf("%s: bounded output differs from compress (ok %v, %d vs %d bytes)", backend.algo, ok, len(out), len(full))
+		}
+		if _, ok := backend.bounded(nil, data, len(full)-1); ok {
+			t.Errorf("%s: output of %d bytes accepted with limit %d", backend.algo, len(full), len(full)-1)
+		}
+	}
+}
+
+// 剪枝和校验后的选择与逐个 compressChecked 的穷举结果一致，且有候选被提前放弃。
+// 单线程运行，否则核数多于候选数时所有候选同时开始，没有上限可用
+func TestSearchCombos(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	rng := rand.New(rand.NewSource(1))
+	smooth := make([]float64, 3000)
+	for i := range smooth {
+		smooth[i] = math.Round((20+math.Sin(float64(i)/10))*100) / 100
+	}
+	// 随机位模式（含 NaN）：chimp128 等不能还原的组合不能被选中
+	random := make([]float64, 3000)
+	for i := range random {
+		random[i] = math.Float64frombits(rng.Uint64())
+	}
+	for name, segment := range map[string][]float64{"smooth": smooth, "random": random} {
+		candidates := prepareCombos(segment)
+		expected, expectedSize := -1, math.MaxInt
+		for i, c := range candidates {
+			if out, ok := compressChecked(segment, c.param); ok && len(out) < expectedSize {
+				expected, expectedSize = i, len(out)
+			}
+		}
+		best, pruned := searchCombos(segment, candidates)
+		if best != expected {
+			t.Errorf("%s: chose %v, expected %v", name, candidates[best].param, candidates[expected].param)
+		}
+		if pruned == 0 {
+			t.Errorf("%s: no candidate abandoned out of %d", name, len(candidates))
+		}
+		t.Logf("%s: %d of %d candidates abandoned, best %s (%d bytes)", name, pruned, len(candidates), describeParam(candidates[best].param), expectedSize)
+	}
+}
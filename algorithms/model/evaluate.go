@@ -0,0 +1,195 @@
+package model
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"myalgo/common"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// featurePredictor 可以直接根据特征预测的选择器（如 SelectorModel），用于计算特征重要性
+type featurePredictor interface {
+	Predict(features []float64) []int
+}
+
+// EvalReport 选择器在留出数据集上的评估结果
+type EvalReport struct {
+	Segments int
+	Accuracy float64 // 预测参数与 findBestCombo 完全一致的比例
+	Regret   float64 // 平均大小损失 (预测大小 - 最优大小) / 最优大小
+	// Confusion[i][j] 最优压缩器为 compressFunc[i]、预测为 compressFunc[j] 的段数
+	Confusion [][]int
+	// Importance 每个 flattenStats 特征的置换重要性：打乱该特征后准确率的下降，选择器不支持 Predict 时为 nil
+	Importance []float64
+}
+
+// EvaluateSelector 将 numbers 切分为 segmentLen 长度的段，对比选择器与 findBestCombo 的结果
+func EvaluateSelector(sel Selector, numbers []float64, seed int64) *EvalReport {
+	report := &EvalReport{Confusion: make([][]int, len(compressFunc))}
+	for i := range report.Confusion {
+		report.Confusion[i] = make([]int, len(compressFunc))
+	}
+	var features [][]float64
+	var oracles [][]int
+	correct := 0
+	for i := 0; i+segmentLen <= len(numbers); i += segmentLen {
+		segment := numbers[i : i+segmentLen]
+		oracle := findBestCombo(segment)
+		predicted := sel.Select(segment)
+		if !compatibleParam(predicted) {
+			predicted = fallbackSelector.Select(segment)
+		}
+		if equalParam(predicted, oracle) {
+			correct++
+		}
+		best := len(RunCompressWithParam(nil, segment, oracle))
+		got := len(RunCompressWithParam(nil, segment, predicted))
+		report.Regret += float64(got-best) / float64(best)
+		report.Confusion[oracle[3]][predicted[3]]++
+		features = append(features, flattenStats(common.AnalyzeTimeSeries(segment)))
+		oracles = append(oracles, oracle)
+		report.Segments++
+	}
+	if report.Segments == 0 {
+		return report
+	}
+	report.Accuracy = float64(correct) / float64(report.Segments)
+	report.Regret /= float64(report.Segments)
+	if p, ok := sel.(featurePredictor); ok {
+		report.Importance = permutationImportance(p, features, oracles, seed)
+	}
+	return report
+}
+
+// permutationImportance 逐个打乱特征列，记录准确率的下降
+func permutationImportance(p featurePredictor, features [][]float64, labels [][]int, seed int64) []float64 {
+	accuracy := func(x [][]float64) float64 {
+		correct := 0
+		for i, row := range x {
+			if equalParam(p.Predict(row), labels[i]) {
+				correct++
+			}
+		}
+		return float64(correct) / float64(len(x))
+	}
+	base := accuracy(features)
+	rng := rand.New(rand.NewSource(seed))
+	importance := make([]float64, len(features[0]))
+	shuffled := make([][]float64, len(features))
+	for i, row := range features {
+		shuffled[i] = append([]float64(nil), row...)
+	}
+	for f := range importance {
+		perm := rng.Perm(len(features))
+		for i := range shuffled {
+			shuffled[i][f] = features[perm[i]][f]
+		}
+		importance[f] = base - accuracy(shuffled)
+		for i := range shuffled {
+			shuffled[i][f] = features[i][f]
+		}
+	}
+	return importance
+}
+
+// WriteReport 在 dir 下写入 summary.csv、confusion.csv、importance.csv 和 importance.png
+func (r *EvalReport) WriteReport(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	summary := [][]string{
+		{"metric", "value"},
+		{"segments", strconv.Itoa(r.Segments)},
+		{"accuracy", strconv.FormatFloat(r.Accuracy, 'f', 6, 64)},
+		{"regret", strconv.FormatFloat(r.Regret, 'f', 6, 64)},
+	}
+	if err := writeCSV(filepath.Join(dir, "summary.csv"), summary); err != nil {
+		return err
+	}
+
+	header := []string{"oracle\\predicted"}
+	for _, c := range compressFunc {
+		header = append(header, c.algo)
+	}
+	confusion := [][]string{header}
+	for i, row := range r.Confusion {
+		record := []string{compressFunc[i].algo}
+		for _, n := range row {
+			record = append(record, strconv.Itoa(n))
+		}
+		confusion = append(confusion, record)
+	}
+	if err := writeCSV(filepath.Join(dir, "confusion.csv"), confusion); err != nil {
+		return err
+	}
+
+	if r.Importance == nil {
+		return nil
+	}
+	importance := [][]string{{"feature", "importance"}}
+	for i, v := range r.Importance {
+		importance = append(importance, []string{featureName(i), strconv.FormatFloat(v, 'f', 6, 64)})
+	}
+	if err := writeCSV(filepath.Join(dir, "importance.csv"), importance); err != nil {
+		return err
+	}
+	return r.plotImportance(filepath.Join(dir, "importance.png"), 20)
+}
+
+// writeCSV 创建 path 并写入所有记录
+func writeCSV(path string, records [][]string) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return csv.NewWriter(f).WriteAll(records)
+}
+
+// plotImportance 绘制重要性最高的 top 个特征的柱状图
+func (r *EvalReport) plotImportance(path string, top int) error {
+	order := make([]int, len(r.Importance))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return r.Importance[order[a]] > r.Importance[order[b]] })
+	order = order[:min(top, len(order))]
+
+	values := make(plotter.Values, len(order))
+	names := make([]string, len(order))
+	for i, f := range order {
+		values[i] = r.Importance[f]
+		names[i] = featureName(f)
+	}
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Permutation importance (accuracy %.3f, regret %.3f)", r.Accuracy, r.Regret)
+	p.Y.Label.Text = "accuracy drop"
+	bars, err := plotter.NewBarChart(values, vg.Points(12))
+	if err != nil {
+		return err
+	}
+	p.Add(bars)
+	p.NominalX(names...)
+	p.X.Tick.Label.Rotation = 1.2
+	p.X.Tick.Label.XAlign = -1
+	return p.Save(10*vg.Inch, 5*vg.Inch, path)
+}
+
+func featureName(i int) string {
+	if i < len(featureNames) {
+		return featureNames[i]
+	}
+	return "f" + strconv.Itoa(i)
+}
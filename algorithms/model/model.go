@@ -2,103 +2,146 @@ package model
 
 import (
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
-	"log"
-	"math"
 	"myalgo/common"
-	"os"
-	"os/exec"
 )
 
-const modelDebug = true
+// Options 控制模型压缩流程
+type Options struct {
+	// Selector 参数选择器，为 nil 时使用 DefaultSelector
+	Selector Selector
+	// Tracer 接收参数选择、各变换阶段和后端的事件，为 nil 时静默
+	Tracer common.Tracer
+	// Explain 为 true 时额外运行穷举搜索，并通过 Tracer 报告最优参数以便与预测结果对比；
+	// 设置了 Objective 时同时报告 Pareto 前沿
+	Explain bool
+	// Objective 为 nil 时只看压缩后大小。设置后，预测结果在 Profile 中不满足约束时改用满足约束的组合
+	Objective *Objective
+	// Profile 在参考数据上由 MeasureCombos 测得的结果，用于估计各组合的速度和内存
+	Profile ProfileLookup
+}
+
+// modelHeaderSize 模型压缩数据头部：选择器版本(uint16)
+const modelHeaderSize = 2
 
 func CompressFloat(dst []byte, src []float64) []byte {
-	// 得到数据特征
-	features := common.AnalyzeTimeSeries(src)
-	file, _ := os.Create("features.json")
-	json.NewEncoder(file).Encode(features)
-	file.Close()
-	// run python code
-	cmd := exec.Command("python", "algorithms/model/py/infer_neural_network.py", "--features", "features.json")
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Fatalf("run python infer code failed: %v\nstdout/stderr: %s", err, string(out))
+	return CompressFloatWithOptions(dst, src, Options{})
+}
+
+// CompressFloatWithOptions 使用选择器预测的参数进行压缩，并在头部记录选择器版本
+func CompressFloatWithOptions(dst []byte, src []float64, opts Options) []byte {
+	tr := common.OrNop(opts.Tracer)
+	selector := opts.Selector
+	if selector == nil {
+		selector = DefaultSelector()
+	}
+	result := selector.Select(src)
+	if !compatibleParam(result) {
+		// 例如 MLP 预测出位模式变换配合非 bitExact 的压缩器
+		result = fallbackSelector.Select(src)
 	}
-	var result []int
-	if uerr := json.Unmarshal(out, &result); uerr != nil {
-		log.Fatalf("invalid python output, expect JSON int array [d,r,c], got: %s, err: %v", string(out), uerr)
+	if opts.Objective != nil && opts.Profile != nil {
+		result = opts.Profile.adjust(result, *opts.Objective)
 	}
-	fmt.Print(result)
-	if modelDebug {
+	tr.Trace(common.TraceEvent{Stage: common.StageSelect, Name: "predicted",
+		Detail: fmt.Sprintf("model v%d %s", selector.Version(), describeParam(result))})
+	if opts.Explain && opts.Objective != nil {
+		bestResult, front := FindBestComboWithObjective(src, *opts.Objective)
+		tr.Trace(common.TraceEvent{Stage: common.StageSelect, Name: "oracle", Detail: describeParam(bestResult)})
+		for _, m := range front {
+			tr.Trace(common.TraceEvent{Stage: common.StageSelect, Name: "pareto", InBytes: m.SrcBytes, OutBytes: m.Size, Detail: m.String()})
+		}
+	} else if opts.Explain {
 		bestResult := findBestCombo(src)
-		fmt.Println(" | ", bestResult)
+		tr.Trace(common.TraceEvent{Stage: common.StageSelect, Name: "oracle", Detail: describeParam(bestResult)})
 	}
-	dst = RunCompressWithParam(dst, src, result)
+	dst = binary.LittleEndian.AppendUint16(dst, selector.Version())
+	dst = runCompressWithParam(dst, src, result, tr)
 	return dst
 }
 
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
-	dst, err := RunDecompress(dst, src)
-	if err != nil {
-		fmt.Println(err)
+	if _, err := ModelVersion(src); err != nil {
+		return nil, err
 	}
-	return dst, err
+	return RunDecompress(dst, src[modelHeaderSize:])
+}
+
+// ModelVersion 返回 CompressFloat 输出中记录的选择器版本
+func ModelVersion(src []byte) (uint16, error) {
+	if len(src) < modelHeaderSize {
+		return 0, fmt.Errorf("invalid data: byte slice is too short, received %d bytes, need at least %d", len(src), modelHeaderSize)
+	}
+	return binary.LittleEndian.Uint16(src), nil
 }
 func RunCompressWithParam(dst []byte, src []float64, param []int) []byte {
+	return runCompressWithParam(dst, src, param, common.NopTracer)
+}
 
+// runCompressWithParam 头部格式：4 字节参数，随后依次是三个变换阶段的 side 数据（uvarint 长度 + 内容）
+func runCompressWithParam(dst []byte, src []float64, param []int, tr common.Tracer) []byte {
+	size := len(src) * 8
 	copied := make([]float64, len(src))
 	copy(copied, src)
 
-	ranged, base := rangedFunc[param[0]].transfer(copied)
-	scale, minNum, maxNum := scaleFunc[param[1]].transfer(ranged)
-	del := delFunc[param[2]].transfer(scale)
-
-	dst = append(dst, byte(param[0]))
-	dst = append(dst, byte(param[1]))
-	dst = append(dst, byte(param[2]))
-	dst = append(dst, byte(param[3]))
-	dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(base))
-	dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(minNum))
-	dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(maxNum))
-	dst = compressFunc[param[3]].compress(dst, del)
+	dst = append(dst, byte(param[0]), byte(param[1]), byte(param[2]), byte(param[3]))
+	data := copied
+	in := size
+	for i, st := range []stage{rangedFunc[param[0]], scaleFunc[param[1]], delFunc[param[2]]} {
+		var side []byte
+		data, side = st.transfer(data)
+		// 输出字节数为按有效位宽打包的数据加上写入头部的 side 数据
+		packed := common.PackedFloatBytes(data)
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: st.name, InBytes: in, OutBytes: packed + sideSize(side),
+			Detail: fmt.Sprintf("stage %d, %d side bytes", i, len(side))})
+		in = packed
+		dst = binary.AppendUvarint(dst, uint64(len(side)))
+		dst = append(dst, side...)
+	}
+	start := len(dst)
+	dst = compressFunc[param[3]].compress(dst, data)
+	tr.Trace(common.TraceEvent{Stage: common.StageBackend, Name: compressFunc[param[3]].algo, InBytes: size, OutBytes: len(dst) - start})
 
 	return dst
 }
+
+// describeParam 将参数下标转换为可读的变换/算法名称
+func describeParam(param []int) string {
+	if !compatibleParam(param) {
+		return fmt.Sprint(param)
+	}
+	return fmt.Sprintf("%v ranged=%s scale=%s del=%s algo=%s", param,
+		rangedFunc[param[0]].name, scaleFunc[param[1]].name, delFunc[param[2]].name, compressFunc[param[3]].algo)
+}
 func RunDecompress(dst []float64, src []byte) ([]float64, error) {
-	if len(src) < 11 {
-		return nil, fmt.Errorf("invalid data: byte slice is too short, received %d bytes, need at least 11", len(src))
+	if len(src) < 4 {
+		return nil, fmt.Errorf("invalid data: byte slice is too short, received %d bytes, need at least 4", len(src))
 	}
-
-	param0 := int(src[0])
-	param1 := int(src[1])
-	param2 := int(src[2])
-	param3 := int(src[3])
-
-	if param0 >= len(delFunc) || param1 >= len(rangedFunc) || param2 >= len(compressFunc) {
-		return nil, fmt.Errorf("invalid parameters in data stream: p0=%d, p1=%d, p2=%d", param0, param1, param2)
+	param := []int{int(src[0]), int(src[1]), int(src[2]), int(src[3])}
+	if !compatibleParam(param) {
+		return nil, fmt.Errorf("invalid parameters in data stream: p0=%d, p1=%d, p2=%d, p3=%d", param[0], param[1], param[2], param[3])
 	}
+	stages := []stage{rangedFunc[param[0]], scaleFunc[param[1]], delFunc[param[2]]}
+	sides := make([][]byte, len(stages))
 	offset := 4
-	baseBits := binary.LittleEndian.Uint64(src[offset : offset+8])
-	base := math.Float64frombits(baseBits)
-	offset += 8
-	minNumBits := binary.LittleEndian.Uint64(src[offset : offset+8])
-	minNum := math.Float64frombits(minNumBits)
-	offset += 8
-	maxNumBits := binary.LittleEndian.Uint64(src[offset : offset+8])
-	maxNum := math.Float64frombits(maxNumBits)
-	offset += 8
-	decompressor := compressFunc[param3].decompress
+	for i := range stages {
+		n, m := binary.Uvarint(src[offset:])
+		if m <= 0 || uint64(len(src)-offset-m) < n {
+			return nil, fmt.Errorf("invalid data: side data of %s is truncated", stages[i].name)
+		}
+		offset += m
+		sides[i] = src[offset : offset+int(n)]
+		offset += int(n)
+	}
+	decompressor := compressFunc[param[3]].decompress
 	dst, err := decompressor(dst, src[offset:])
 	if err != nil {
-		return nil, fmt.Errorf("decompression failed using %s: %w", compressFunc[param2].algo, err)
+		return nil, fmt.Errorf("decompression failed using %s: %w", compressFunc[param[3]].algo, err)
+	}
+	for i := len(stages) - 1; i >= 0; i-- {
+		if dst, err = stages[i].reverse(dst, sides[i]); err != nil {
+			return nil, fmt.Errorf("reverse %s failed: %w", stages[i].name, err)
+		}
 	}
-	deltaReverser := delFunc[param2].reverse
-	dst = deltaReverser(dst)
-	scaleReverser := scaleFunc[param1].reverse
-	dst = scaleReverser(dst, minNum, maxNum)
-	rangedReverser := rangedFunc[param0].reverse
-	dst = rangedReverser(dst, base)
-
 	return dst, nil
 }
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+)
+
+// Measurement 一个参数组合在一段数据上的实测结果
+type Measurement struct {
+	Param          []int
+	SrcBytes       int // 原始字节数
+	Size           int // 压缩后字节数
+	CompressTime   time.Duration
+	DecompressTime time.Duration
+	Memory         uint64 // 压缩和解压中较大的一次堆分配字节数
+}
+
+func (m Measurement) Ratio() float64 { return float64(m.SrcBytes) / float64(max(m.Size, 1)) }
+
+// CompressSpeed 压缩速度 MB/s
+func (m Measurement) CompressSpeed() float64 { return speedMBps(m.SrcBytes, m.CompressTime) }
+
+// DecompressSpeed 解压速度 MB/s
+func (m Measurement) DecompressSpeed() float64 { return speedMBps(m.SrcBytes, m.DecompressTime) }
+
+func speedMBps(bytes int, d time.Duration) float64 {
+	if d <= 0 {
+		return math.Inf(1)
+	}
+	return float64(bytes) / 1e6 / d.Seconds()
+}
+
+func (m Measurement) String() string {
+	return fmt.Sprintf("%s ratio=%.3f compress=%.1fMB/s decompress=%.1fMB/s mem=%dB",
+		describeParam(m.Param), m.Ratio(), m.CompressSpeed(), m.DecompressSpeed(), m.Memory)
+}
+
+// Objective 选择目标：先满足约束，再最小化加权代价。各权重为折算成字节的系数，全为 0 时只比较压缩后大小
+type Objective struct {
+	MinCompressSpeed   float64 // MB/s，0 表示不限制
+	MinDecompressSpeed float64 // MB/s，0 表示不限制
+	MaxMemory          uint64  // 字节，0 表示不限制
+
+	SizeWeight       float64 // 每个压缩后字节
+	CompressWeight   float64 // 每微秒压缩耗时
+	DecompressWeight float64 // 每微秒解压耗时
+	MemoryWeight     float64 // 每 KB 堆分配
+}
+
+// Feasible 判断实测结果是否满足约束
+func (o Objective) Feasible(m Measurement) bool {
+	return (o.MinCompressSpeed <= 0 || m.CompressSpeed() >= o.MinCompressSpeed) &&
+		(o.MinDecompressSpeed <= 0 || m.DecompressSpeed() >= o.MinDecompressSpeed) &&
+		(o.MaxMemory == 0 || m.Memory <= o.MaxMemory)
+}
+
+// Score 加权代价，越小越好
+func (o Objective) Score(m Measurement) float64 {
+	sizeWeight := o.SizeWeight
+	if sizeWeight == 0 && o.CompressWeight == 0 && o.DecompressWeight == 0 && o.MemoryWeight == 0 {
+		sizeWeight = 1
+	}
+	return sizeWeight*float64(m.Size) +
+		o.CompressWeight*float64(m.CompressTime.Microseconds()) +
+		o.DecompressWeight*float64(m.DecompressTime.Microseconds()) +
+		o.MemoryWeight*float64(m.Memory)/1024
+}
+
+// Best 选出满足约束且代价最小的结果；没有满足约束的结果时忽略约束
+func (o Objective) Best(ms []Measurement) (Measurement, bool) {
+	best, found, feasible := Measurement{}, false, false
+	for _, m := range ms {
+		f := o.Feasible(m)
+		if feasible && !f {
+			continue
+		}
+		if !found || (f && !feasible) || o.Score(m) < o.Score(best) {
+			best, found, feasible = m, true, f
+		}
+	}
+	return best, feasible
+}
+
+// measureRuns 每个组合计时的次数，取最快的一次以减少调度和 GC 的干扰
+const measureRuns = 5
+
+// MeasureParam 实测一个参数组合的压缩、解压耗时和内存。先做一次按位还原校验作为预热，
+// 不能还原的组合 ok 为 false；耗时取 measureRuns 次中最快的一次
+func MeasureParam(segment []float64, param []int) (m Measurement, ok bool) {
+	m = Measurement{Param: append([]int(nil), param...), SrcBytes: len(segment) * 8}
+	dst, ok := compressChecked(segment, param)
+	if !ok {
+		return m, false
+	}
+	m.Size = len(dst)
+	src := append([]byte(nil), dst...)
+	m.Memory = max(allocBytes(func() { RunCompressWithParam(nil, segment, param) }),
+		allocBytes(func() { RunDecompress(nil, src) }))
+
+	for r := 0; r < measureRuns; r++ {
+		start := time.Now()
+		RunCompressWithParam(nil, segment, param)
+		elapsed := time.Since(start)
+		if r == 0 || elapsed < m.CompressTime {
+			m.CompressTime = elapsed
+		}
+		// 部分压缩器解压时会改写输入，每次解压一份副本，复制不计入耗时
+		src = append(src[:0], dst...)
+		start = time.Now()
+		RunDecompress(nil, src)
+		elapsed = time.Since(start)
+		if r == 0 || elapsed < m.DecompressTime {
+			m.DecompressTime = elapsed
+		}
+	}
+	return m, true
+}
+
+// allocBytes f 执行期间的堆分配字节数
+func allocBytes(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// MeasureCombos 实测所有合法参数组合，跳过不能按位还原的组合
+func MeasureCombos(segment []float64) []Measurement {
+	var ms []Measurement
+	for ranged := range rangedFunc {
+		for scale := range scaleFunc {
+			for del := range delFunc {
+				for algo := range compressFunc {
+					param := []int{ranged, scale, del, algo}
+					if !compatibleParam(param) {
+						continue
+					}
+					if m, ok := MeasureParam(segment, param); ok {
+						ms = append(ms, m)
+					}
+				}
+			}
+		}
+	}
+	return ms
+}
+
+// dominates a 在大小、压缩耗时、解压耗时、内存上都不差于 b，且至少一项更好
+func dominates(a, b Measurement) bool {
+	notWorse := a.Size <= b.Size && a.CompressTime <= b.CompressTime &&
+		a.DecompressTime <= b.DecompressTime && a.Memory <= b.Memory
+	better := a.Size < b.Size || a.CompressTime < b.CompressTime ||
+		a.DecompressTime < b.DecompressTime || a.Memory < b.Memory
+	return notWorse && better
+}
+
+// ParetoFront 返回不被其他结果支配的候选
+func ParetoFront(ms []Measurement) []Measurement {
+	var front []Measurement
+	for i, m := range ms {
+		dominated := false
+		for j, other := range ms {
+			if i != j && dominates(other, m) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, m)
+		}
+	}
+	return front
+}
+
+// FindBestComboWithObjective 穷举搜索版本的 findBestCombo，按 Objective 选择，同时返回 Pareto 前沿
+func FindBestComboWithObjective(segment []float64, obj Objective) ([]int, []Measurement) {
+	ms := MeasureCombos(segment)
+	best, _ := obj.Best(ms)
+	return best.Param, ParetoFront(ms)
+}
+
+// ProfileLookup 在参考数据上测得的各参数组合结果，用于模型选择时估计未实测组合的速度和内存
+type ProfileLookup []Measurement
+
+// find 返回参数组合在 profile 中的结果
+func (p ProfileLookup) find(param []int) (Measurement, bool) {
+	for _, m := range p {
+		if equalParam(m.Param, param) {
+			return m, true
+		}
+	}
+	return Measurement{}, false
+}
+
+// adjust 预测的参数组合在 profile 中不满足约束时，改用 profile 中满足约束且代价最小的组合
+func (p ProfileLookup) adjust(param []int, obj Objective) []int {
+	if m, ok := p.find(param); !ok || obj.Feasible(m) {
+		return param
+	}
+	if best, feasible := obj.Best(p); feasible {
+		return append([]int(nil), best.Param...)
+	}
+	return param
+}
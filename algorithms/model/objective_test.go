@@ -0,0 +1,35 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// chimp128 不能还原随机位模式，实测时应跳过，不能进入 Pareto 前沿
+func TestMeasureSkipsFailedDecode(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]float64, 2000)
+	for i := range data {
+		data[i] = math.Float64frombits(rng.Uint64())
+	}
+	chimp := []int{0, 0, 0, 2}
+	if _, ok := compressChecked(data, chimp); ok {
+		t.Skip("chimp128 restores this data")
+	}
+	if m, ok := MeasureParam(data, chimp); ok {
+		t.Errorf("measured a combination that fails to decode: %s", m)
+	}
+	ms := MeasureCombos(data)
+	if len(ms) == 0 {
+		t.Fatal("no combination measured")
+	}
+	for _, m := range ms {
+		if equalParam(m.Param, chimp) {
+			t.Errorf("MeasureCombos kept %s", m)
+		}
+		if m.CompressTime <= 0 || m.DecompressTime <= 0 {
+			t.Errorf("missing timings: %s", m)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+import argparse
+import json
+import os
+
+import numpy as np
+import torch
+import torch.nn as nn
+
+from infer_neural_network import load_model
+
+
+def fold_network(model):
+    """将 Sequential 中的 Linear + BatchNorm1d 折叠为单个全连接层，Dropout 在推理时忽略"""
+    layers = []
+    modules = list(model.network)
+    i = 0
+    while i < len(modules):
+        m = modules[i]
+        if not isinstance(m, nn.Linear):
+            i += 1
+            continue
+        w = m.weight.detach().cpu().numpy().astype(np.float64)
+        b = m.bias.detach().cpu().numpy().astype(np.float64)
+        activation = "linear"
+        j = i + 1
+        while j < len(modules) and not isinstance(modules[j], nn.Linear):
+            nxt = modules[j]
+            if isinstance(nxt, nn.BatchNorm1d):
+                scale = (nxt.weight.detach().cpu().numpy() /
+                         np.sqrt(nxt.running_var.detach().cpu().numpy() + nxt.eps))
+                w = w * scale[:, None]
+                b = (b - nxt.running_mean.detach().cpu().numpy()) * scale + nxt.bias.detach().cpu().numpy()
+            elif isinstance(nxt, nn.ReLU):
+                activation = "relu"
+            j += 1
+        layers.append({"weights": w.tolist(), "bias": b.tolist(), "activation": activation})
+        i = j
+    return layers
+
+
+def main():
+    here = os.path.dirname(__file__)
+    parser = argparse.ArgumentParser(description="导出神经网络选择器为 Go 可直接加载的 selector.json")
+    parser.add_argument("--model", default=os.path.join(here, "neural_network_model.pth"))
+    parser.add_argument("--config", default=os.path.join(here, "neural_network_config.pkl"))
+    parser.add_argument("--scaler", default=os.path.join(here, "neural_network_scaler.pkl"))
+    parser.add_argument("--version", type=int, required=True, help="写入压缩头部的模型版本号 (1-65534，65535 留给采样选择器)")
+    parser.add_argument("--out", default=os.path.join(here, "..", "selector.json"))
+    args = parser.parse_args()
+    if not 1 <= args.version <= 65534:
+        parser.error("--version 必须在 1-65534 之间，65535 留给 Go 的采样选择器")
+
+    model, scaler = load_model(args.model, args.config, args.scaler, torch.device("cpu"))
+    model.eval()
+    selector = {
+        "version": args.version,
+        "type": "mlp",
+        "feature_mean": scaler.mean_.tolist(),
+        "feature_scale": scaler.scale_.tolist(),
+        "layers": fold_network(model),
+    }
+    with open(args.out, "w") as f:
+        json.dump(selector, f)
+    print(f"✅ 已导出选择器: {args.out}")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// SamplingSelectorVersion 采样选择器写入头部的版本号，训练和导出的模型不能使用这个版本号
+const SamplingSelectorVersion uint16 = math.MaxUint16
+
+// SamplingOptions 采样选择参数
+type SamplingOptions struct {
+	Samples   int // 采样段数，均匀分布在整个数组上（包含首尾）
+	SampleLen int // 每段长度
+	// SpeedWeight 每微秒压缩耗时折算的字节数，0 表示只比较压缩后大小
+	SpeedWeight float64
+}
+
+// DefaultSamplingOptions 默认采样参数
+func DefaultSamplingOptions() SamplingOptions {
+	return SamplingOptions{Samples: 4, SampleLen: 512}
+}
+
+// SamplingSelector 不依赖模型：用每个合法参数组合压缩几个小样本，按比例外推大小后选出最优组合
+type SamplingSelector struct {
+	opts SamplingOptions
+}
+
+func NewSamplingSelector(opts SamplingOptions) *SamplingSelector {
+	return &SamplingSelector{opts: opts}
+}
+
+func (s *SamplingSelector) Version() uint16 { return SamplingSelectorVersion }
+
+// Select 将各段样本拼接后分别压缩前一半和全部，用两者之差估计每个值的边际字节数，
+// 再线性外推到整个数组，避免把压缩器的固定开销（如 huffman 码表）按比例放大
+func (s *SamplingSelector) Select(src []float64) []int {
+	var joined []float64
+	for _, sample := range s.samples(src) {
+		joined = append(joined, sample...)
+	}
+	if len(joined) == 0 {
+		return fallbackSelector.Select(src)
+	}
+	half := joined[:len(joined)/2]
+	rest := float64(len(src) - len(joined))
+
+	bestScore := math.Inf(1)
+	bestParam := fallbackSelector.Select(src)
+	param := make([]int, 4)
+	for ranged := range rangedFunc {
+		for sc := range scaleFunc {
+			for del := range delFunc {
+				for algo := range compressFunc {
+					param[0], param[1], param[2], param[3] = ranged, sc, del, algo
+					if !compatibleParam(param) {
+						continue
+					}
+					start := time.Now()
+					out, ok := compressChecked(joined, param)
+					elapsed := time.Since(start)
+					if !ok {
+						continue
+					}
+					full := float64(len(out))
+					score := full
+					if rest > 0 {
+						halfOut, ok := compressChecked(half, param)
+						if !ok {
+							continue
+						}
+						marginal := (full - float64(len(halfOut))) / float64(len(joined)-len(half))
+						score += max(marginal, 0) * rest
+					}
+					if s.opts.SpeedWeight > 0 {
+						score += s.opts.SpeedWeight * float64(elapsed.Microseconds()) * float64(len(src)) / float64(len(joined))
+					}
+					if score < bestScore {
+						bestScore = score
+						copy(bestParam, param)
+					}
+				}
+			}
+		}
+	}
+	return bestParam
+}
+
+// compressChecked 按 param 压缩并校验能否按位还原，压缩或解压 panic、出错或结果不一致时 ok 为 false。
+// 部分压缩器（如 elf、chimp128）不支持某些值，选择参数时要跳过这样的组合
+func compressChecked(src []float64, param []int) (out []byte, ok bool) {
+	defer func() {
+		if recover() != nil {
+			out, ok = nil, false
+		}
+	}()
+	out = RunCompressWithParam(nil, src, param)
+	// 部分压缩器解压时会改写输入，校验时使用副本
+	got, err := RunDecompress(nil, append([]byte(nil), out...))
+	if err != nil || len(got) != len(src) {
+		return nil, false
+	}
+	for i, v := range src {
+		if math.Float64bits(got[i]) != math.Float64bits(v) {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// samples 分层采样：数据较短时直接使用整个数组（此时 Select 等价于 findBestCombo）
+func (s *SamplingSelector) samples(src []float64) [][]float64 {
+	n, k, l := len(src), max(s.opts.Samples, 1), max(s.opts.SampleLen, 1)
+	if n <= k*l {
+		return [][]float64{src}
+	}
+	if k == 1 {
+		return [][]float64{src[(n-l)/2 : (n-l)/2+l]}
+	}
+	samples := make([][]float64, k)
+	for i := range samples {
+		start := i * (n - l) / (k - 1)
+		samples[i] = src[start : start+l]
+	}
+	return samples
+}
+
+// CompressFloatAuto 使用采样选择器压缩，选中的参数记录在头部，由 DecompressFloat 解压
+func CompressFloatAuto(dst []byte, src []float64) []byte {
+	return CompressFloatWithOptions(dst, src, Options{Selector: NewSamplingSelector(DefaultSamplingOptions())})
+}
@@ -0,0 +1,259 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"myalgo/common"
+	"os"
+	"sync"
+)
+
+// Selector 为一段数据选择压缩参数 [ranged, scale, del, algo]
+type Selector interface {
+	Select(src []float64) []int
+	// Version 写入压缩头部，用于追溯产生该数据的模型
+	Version() uint16
+}
+
+// staticSelector 没有可用模型时使用的固定参数
+type staticSelector struct {
+	param []int
+}
+
+func (s staticSelector) Select(src []float64) []int { return append([]int(nil), s.param...) }
+func (s staticSelector) Version() uint16            { return 0 }
+
+// fallbackSelector 使用 zstd 直接压缩，不做任何变换
+var fallbackSelector Selector = staticSelector{param: []int{0, 0, 0, 4}}
+
+// MLPLayer 全连接层，Weights[i] 为第 i 个输出神经元的权重（BatchNorm 已在导出时折叠）
+type MLPLayer struct {
+	Weights    [][]float64 `json:"weights"`
+	Bias       []float64   `json:"bias"`
+	Activation string      `json:"activation"` // "relu" 或 "linear"
+}
+
+// TreeNode 决策树节点，Feature 为 -1 时是叶子节点
+type TreeNode struct {
+	Feature   int     `json:"f"`
+	Threshold float64 `json:"t,omitempty"` // 特征值 <= Threshold 走左子树
+	Left      int     `json:"l,omitempty"`
+	Right     int     `json:"r,omitempty"`
+	Class     int     `json:"c"` // 叶子节点的类别下标（对应 Classes）
+}
+
+// SelectorModel 可移植的选择器模型，对应 selector.json
+type SelectorModel struct {
+	ModelVersion uint16 `json:"version"`
+	Type         string `json:"type"` // "mlp" 或 "forest"
+	// mlp
+	FeatureMean  []float64  `json:"feature_mean,omitempty"`  // 标准化均值
+	FeatureScale []float64  `json:"feature_scale,omitempty"` // 标准化标准差
+	Layers       []MLPLayer `json:"layers,omitempty"`
+	// forest
+	Classes [][]int      `json:"classes,omitempty"` // 类别下标 -> 参数组合
+	Trees   [][]TreeNode `json:"trees,omitempty"`
+}
+
+// LoadSelectorModel 从 JSON 文件加载选择器模型
+func LoadSelectorModel(path string) (*SelectorModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &SelectorModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("model: invalid selector file %s: %w", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("model: invalid selector file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Save 将模型写为 JSON 文件
+func (m *SelectorModel) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (m *SelectorModel) validate() error {
+	if err := validVersion(m.ModelVersion); err != nil {
+		return err
+	}
+	switch m.Type {
+	case "mlp":
+		return m.validateMLP()
+	case "forest":
+		return m.validateForest()
+	}
+	return fmt.Errorf("unsupported model type %q", m.Type)
+}
+
+// validVersion 0 表示没有模型（固定参数），SamplingSelectorVersion 留给采样选择器
+func validVersion(v uint16) error {
+	if v == 0 || v == SamplingSelectorVersion {
+		return fmt.Errorf("model version %d is reserved, use 1-%d", v, SamplingSelectorVersion-1)
+	}
+	return nil
+}
+
+func (m *SelectorModel) validateForest() error {
+	if len(m.Trees) == 0 || len(m.Classes) == 0 {
+		return fmt.Errorf("empty forest")
+	}
+	for _, c := range m.Classes {
+		if !compatibleParam(c) {
+			return fmt.Errorf("class %v is not a valid parameter combination", c)
+		}
+	}
+	for t, tree := range m.Trees {
+		for i, node := range tree {
+			if node.Feature < 0 {
+				if node.Class < 0 || node.Class >= len(m.Classes) {
+					return fmt.Errorf("tree %d node %d: class %d out of range", t, i, node.Class)
+				}
+			} else if node.Left <= i || node.Right <= i || node.Left >= len(tree) || node.Right >= len(tree) {
+				return fmt.Errorf("tree %d node %d: invalid children", t, i)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SelectorModel) validateMLP() error {
+	if len(m.Layers) == 0 {
+		return fmt.Errorf("no layers")
+	}
+	in := len(m.FeatureMean)
+	if len(m.FeatureScale) != in {
+		return fmt.Errorf("feature_mean has %d entries, feature_scale has %d", in, len(m.FeatureScale))
+	}
+	for i, layer := range m.Layers {
+		if len(layer.Weights) != len(layer.Bias) {
+			return fmt.Errorf("layer %d: %d weight rows, %d biases", i, len(layer.Weights), len(layer.Bias))
+		}
+		for _, row := range layer.Weights {
+			if len(row) != in {
+				return fmt.Errorf("layer %d: expected %d inputs, got %d", i, in, len(row))
+			}
+		}
+		in = len(layer.Bias)
+	}
+	if in != 4 {
+		return fmt.Errorf("expected 4 outputs, got %d", in)
+	}
+	return nil
+}
+
+func (m *SelectorModel) Version() uint16 { return m.ModelVersion }
+
+// Select 计算 flattenStats 特征并预测参数
+func (m *SelectorModel) Select(src []float64) []int {
+	return m.Predict(flattenStats(common.AnalyzeTimeSeries(src)))
+}
+
+// Predict 根据特征预测参数组合
+func (m *SelectorModel) Predict(features []float64) []int {
+	if m.Type == "forest" {
+		return m.predictForest(features)
+	}
+	return m.predictMLP(features)
+}
+
+// predictForest 多棵树投票，票数相同时取下标较小的类别
+func (m *SelectorModel) predictForest(features []float64) []int {
+	votes := make([]int, len(m.Classes))
+	for _, tree := range m.Trees {
+		i := 0
+		for tree[i].Feature >= 0 {
+			v := 0.0
+			if f := tree[i].Feature; f < len(features) {
+				v = finiteFeature(features[f])
+			}
+			if v <= tree[i].Threshold {
+				i = tree[i].Left
+			} else {
+				i = tree[i].Right
+			}
+		}
+		votes[tree[i].Class]++
+	}
+	return append([]int(nil), m.Classes[argmax(votes)]...)
+}
+
+// predictMLP 前向计算，输出四舍五入后裁剪到各参数表的合法范围
+func (m *SelectorModel) predictMLP(features []float64) []int {
+	x := make([]float64, len(m.FeatureMean))
+	for i := range x {
+		v := 0.0
+		if i < len(features) {
+			v = finiteFeature(features[i])
+		}
+		scale := m.FeatureScale[i]
+		if scale == 0 {
+			scale = 1
+		}
+		x[i] = (v - m.FeatureMean[i]) / scale
+	}
+	for _, layer := range m.Layers {
+		y := make([]float64, len(layer.Bias))
+		for j, row := range layer.Weights {
+			sum := layer.Bias[j]
+			for k, w := range row {
+				sum += w * x[k]
+			}
+			if layer.Activation == "relu" && sum < 0 {
+				sum = 0
+			}
+			y[j] = sum
+		}
+		x = y
+	}
+	limits := []int{len(rangedFunc), len(scaleFunc), len(delFunc), len(compressFunc)}
+	param := make([]int, 4)
+	for i := range param {
+		param[i] = clampIndex(int(math.Round(x[i])), limits[i])
+	}
+	return param
+}
+
+func clampIndex(v, n int) int {
+	if v < 0 {
+		return 0
+	}
+	if v >= n {
+		return n - 1
+	}
+	return v
+}
+
+var (
+	defaultSelectorMu sync.RWMutex
+	defaultSelector   = fallbackSelector
+)
+
+// SetDefaultSelector 从 path 加载模型（由 py/export_selector.py 或 TrainSelector 导出）作为 DefaultSelector，
+// 加载失败时返回错误并保留当前的选择器
+func SetDefaultSelector(path string) error {
+	m, err := LoadSelectorModel(path)
+	if err != nil {
+		return err
+	}
+	defaultSelectorMu.Lock()
+	defaultSelector = m
+	defaultSelectorMu.Unlock()
+	return nil
+}
+
+// DefaultSelector 返回 SetDefaultSelector 加载的模型。仓库不附带训练好的模型，
+// 没有加载时使用固定参数（zstd 直接压缩，头部版本为 0）
+func DefaultSelector() Selector {
+	defaultSelectorMu.RLock()
+	defer defaultSelectorMu.RUnlock()
+	return defaultSelector
+}
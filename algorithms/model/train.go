@@ -0,0 +1,286 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"myalgo/common"
+	"sort"
+)
+
+// TrainingSet 带标签的训练集，每行对应一个 segmentLen 长度的数据段
+type TrainingSet struct {
+	Features [][]float64 // flattenStats 特征
+	Labels   [][]int     // findBestCombo 得到的最优参数
+}
+
+// TrainOptions 随机森林训练参数
+type TrainOptions struct {
+	Trees           int     // 树的数量，1 表示单棵决策树（不做自助采样）
+	MaxDepth        int     // 最大深度
+	MinLeaf         int     // 叶子最少样本数
+	FeatureFraction float64 // 每次分裂随机考察的特征比例
+	Folds           int     // 交叉验证折数，<2 表示不做交叉验证
+	Seed            int64
+	Version         uint16 // 导出模型的版本号
+}
+
+// DefaultTrainOptions 默认训练参数
+func DefaultTrainOptions() TrainOptions {
+	return TrainOptions{
+		Trees:           50,
+		MaxDepth:        12,
+		MinLeaf:         2,
+		FeatureFraction: 0.5,
+		Folds:           5,
+		Seed:            114514,
+		Version:         1,
+	}
+}
+
+// DefaultTrainingValues 训练时默认读取的值个数，交叉验证的每一折有 4 段
+func DefaultTrainingValues(opts TrainOptions) int {
+	return segmentLen * 4 * max(opts.Folds, 1)
+}
+
+// finiteFeature NaN、±Inf 特征按 0 处理，训练和预测（包括 MLP）保持一致，否则排序和阈值比较的结果不确定
+func finiteFeature(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+func finiteFeatures(features []float64) []float64 {
+	out := make([]float64, len(features))
+	for i, v := range features {
+		out[i] = finiteFeature(v)
+	}
+	return out
+}
+
+// BuildTrainingSet 将 numbers 切分为 segmentLen 长度的段，计算特征并用 findBestCombo 打标签
+func BuildTrainingSet(numbers []float64) *TrainingSet {
+	set := &TrainingSet{}
+	for i := 0; i+segmentLen <= len(numbers); i += segmentLen {
+		segment := numbers[i : i+segmentLen]
+		set.Features = append(set.Features, finiteFeatures(flattenStats(common.AnalyzeTimeSeries(segment))))
+		set.Labels = append(set.Labels, findBestCombo(segment))
+	}
+	return set
+}
+
+// classIndex 将参数组合映射为分类标签
+func classIndex(classes [][]int, param []int) int {
+	for i, c := range classes {
+		if equalParam(c, param) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalParam(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeLabels 收集出现过的参数组合作为类别
+func encodeLabels(labels [][]int) ([][]int, []int) {
+	var classes [][]int
+	y := make([]int, len(labels))
+	for i, label := range labels {
+		c := classIndex(classes, label)
+		if c < 0 {
+			classes = append(classes, append([]int(nil), label...))
+			c = len(classes) - 1
+		}
+		y[i] = c
+	}
+	return classes, y
+}
+
+// TrainForest 在整个训练集上训练随机森林选择器
+func TrainForest(set *TrainingSet, opts TrainOptions) *SelectorModel {
+	classes, y := encodeLabels(set.Labels)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	all := make([]int, len(y))
+	for i := range all {
+		all[i] = i
+	}
+	x := make([][]float64, len(set.Features))
+	for i, f := range set.Features {
+		x[i] = finiteFeatures(f)
+	}
+	m := &SelectorModel{ModelVersion: opts.Version, Type: "forest", Classes: classes}
+	for t := 0; t < max(opts.Trees, 1); t++ {
+		rows := all
+		if opts.Trees > 1 {
+			rows = make([]int, len(all))
+			for i := range rows {
+				rows[i] = all[rng.Intn(len(all))]
+			}
+		}
+		b := &treeBuilder{x: x, y: y, classes: len(classes), opts: opts, rng: rng}
+		b.build(rows, 0)
+		m.Trees = append(m.Trees, b.nodes)
+	}
+	return m
+}
+
+// CrossValidate k 折交叉验证，返回每一折的 top-1 准确率
+func CrossValidate(set *TrainingSet, opts TrainOptions) []float64 {
+	n := len(set.Labels)
+	if opts.Folds < 2 || n < opts.Folds {
+		return nil
+	}
+	perm := rand.New(rand.NewSource(opts.Seed)).Perm(n)
+	acc := make([]float64, opts.Folds)
+	for fold := 0; fold < opts.Folds; fold++ {
+		train := &TrainingSet{}
+		var test []int
+		for i, idx := range perm {
+			if i%opts.Folds == fold {
+				test = append(test, idx)
+			} else {
+				train.Features = append(train.Features, set.Features[idx])
+				train.Labels = append(train.Labels, set.Labels[idx])
+			}
+		}
+		m := TrainForest(train, opts)
+		correct := 0
+		for _, idx := range test {
+			if equalParam(m.Predict(set.Features[idx]), set.Labels[idx]) {
+				correct++
+			}
+		}
+		acc[fold] = float64(correct) / float64(len(test))
+	}
+	return acc
+}
+
+// TrainSelector 一条命令完成：切分数据打标签、交叉验证、全量训练并导出到 outPath
+func TrainSelector(numbers []float64, opts TrainOptions, outPath string) (*SelectorModel, []float64, error) {
+	if err := validVersion(opts.Version); err != nil {
+		return nil, nil, fmt.Errorf("model: %w", err)
+	}
+	set := BuildTrainingSet(numbers)
+	if len(set.Labels) == 0 {
+		return nil, nil, fmt.Errorf("model: need at least %d values to build a training set, got %d", segmentLen, len(numbers))
+	}
+	acc := CrossValidate(set, opts)
+	m := TrainForest(set, opts)
+	if err := m.Save(outPath); err != nil {
+		return nil, acc, err
+	}
+	return m, acc, nil
+}
+
+// treeBuilder CART 分类树（Gini 不纯度）
+type treeBuilder struct {
+	x       [][]float64
+	y       []int
+	classes int
+	opts    TrainOptions
+	rng     *rand.Rand
+	nodes   []TreeNode
+}
+
+func (b *treeBuilder) build(rows []int, depth int) int {
+	idx := len(b.nodes)
+	counts := b.count(rows)
+	b.nodes = append(b.nodes, TreeNode{Feature: -1, Class: argmax(counts)})
+	if depth >= b.opts.MaxDepth || len(rows) < 2*max(b.opts.MinLeaf, 1) || gini(counts, len(rows)) == 0 {
+		return idx
+	}
+	feature, threshold, ok := b.bestSplit(rows, counts)
+	if !ok {
+		return idx
+	}
+	var left, right []int
+	for _, r := range rows {
+		if b.x[r][feature] <= threshold {
+			left = append(left, r)
+		} else {
+			right = append(right, r)
+		}
+	}
+	b.nodes[idx].Feature = feature
+	b.nodes[idx].Threshold = threshold
+	l := b.build(left, depth+1)
+	r := b.build(right, depth+1)
+	b.nodes[idx].Left, b.nodes[idx].Right = l, r
+	return idx
+}
+
+func (b *treeBuilder) count(rows []int) []int {
+	counts := make([]int, b.classes)
+	for _, r := range rows {
+		counts[b.y[r]]++
+	}
+	return counts
+}
+
+func (b *treeBuilder) bestSplit(rows []int, total []int) (int, float64, bool) {
+	nFeatures := len(b.x[rows[0]])
+	features := b.rng.Perm(nFeatures)
+	if k := int(float64(nFeatures) * b.opts.FeatureFraction); k > 0 && k < nFeatures {
+		features = features[:k]
+	}
+	minLeaf := max(b.opts.MinLeaf, 1)
+	bestScore := gini(total, len(rows))
+	bestFeature, bestThreshold, found := -1, 0.0, false
+	sorted := append([]int(nil), rows...)
+	left := make([]int, b.classes)
+	right := make([]int, b.classes)
+	for _, f := range features {
+		sort.Slice(sorted, func(i, j int) bool { return b.x[sorted[i]][f] < b.x[sorted[j]][f] })
+		for c := range left {
+			left[c], right[c] = 0, total[c]
+		}
+		for i := 0; i < len(sorted)-1; i++ {
+			c := b.y[sorted[i]]
+			left[c]++
+			right[c]--
+			nl, nr := i+1, len(sorted)-i-1
+			cur, next := b.x[sorted[i]][f], b.x[sorted[i+1]][f]
+			if cur == next || nl < minLeaf || nr < minLeaf {
+				continue
+			}
+			score := (float64(nl)*gini(left, nl) + float64(nr)*gini(right, nr)) / float64(len(sorted))
+			if score < bestScore {
+				bestScore, bestFeature, bestThreshold, found = score, f, (cur+next)/2, true
+			}
+		}
+	}
+	return bestFeature, bestThreshold, found
+}
+
+func gini(counts []int, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	g := 1.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		g -= p * p
+	}
+	return g
+}
+
+func argmax(counts []int) int {
+	best := 0
+	for i, c := range counts {
+		if c > counts[best] {
+			best = i
+		}
+	}
+	return best
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/csv"
+	"fmt"
 	"log"
 	"myalgo/algorithms/chimp128"
 	"myalgo/algorithms/elf"
@@ -12,43 +13,62 @@ import (
 	"os"
 )
 
-var rangedFunc = []struct {
-	ranged   string
-	transfer func(src []float64) ([]float64, float64)
-	reverse  func(src []float64, base float64) []float64
-}{
-	{"null", nullRangedFunc, nullRangedRecoverFunc},
-	// {"log", common.LogedArr, common.LogedRecover},
-	// {"ranged", common.RangedArr, common.RangedRecover},
+// 三个变换阶段依次执行：ranged -> scale -> del。transfer 可以原地修改输入，
+// 返回的 side 数据写入压缩头部，reverse 据此按位精确还原。
+// bitPattern 表示输出可能是任意位模式（NaN、非规格数），只能配合 bitExact 的压缩器。
+// common 中基于浮点运算的 LogedArr、MinMaxRangedArr、ZScoreNormArr、DeltaArr 不能按位还原，因此不在搜索空间中。
+type stage struct {
+	name       string
+	transfer   func(src []float64) ([]float64, []byte)
+	reverse    func(src []float64, side []byte) ([]float64, error)
+	bitPattern bool
 }
-var scaleFunc = []struct {
-	scale    string
-	transfer func(src []float64) ([]float64, float64, float64)
-	reverse  func(src []float64, minNum, maxNum float64) []float64
-}{
-	{"null", nullScaleFunc, nullScaleRecoverFunc},
-	// {"zscore", common.ZScoreNormArr, common.ZScoreNormRecover},
-	// {"minmax", common.MinMaxRangedArr, common.MinMaxRangedRecover},
+
+var rangedFunc = []stage{
+	{"null", nullFunc, nullRecoverFunc, false},
+	{"decimal", common.DecimalScaleArr, common.DecimalScaleRecover, false},
 }
-var delFunc = []struct {
-	del      string
-	transfer func(src []float64) []float64
-	reverse  func(src []float64) []float64
-}{
-	{"null", nullFunc, nullFunc},
-	// {"delta", common.DeltaArr, common.DeltaRecover},
-	// {"deltaOfdelta", common.DeltaOfDeltaArr, common.DeltaOfDeltaRecover},
+var scaleFunc = []stage{
+	{"null", nullFunc, nullRecoverFunc, false},
+	{"byteTranspose", withoutSide(common.ByteTransposeArr), withoutSideRecover(common.ByteTransposeRecover), true},
+	{"bitTranspose", withoutSide(common.BitTransposeArr), withoutSideRecover(common.BitTransposeRecover), true},
 }
+var delFunc = []stage{
+	{"null", nullFunc, nullRecoverFunc, false},
+	{"intDelta", common.IntDeltaArr, common.IntDeltaRecover, false},
+	{"xorDelta", withoutSide(common.XorDeltaArr), withoutSideRecover(common.XorDeltaRecover), true},
+}
+
+// bitExact 表示压缩器对任意位模式都能无损还原。
+// bounded 在输出超过 limit 字节时提前停止并返回 false，否则输出与 compress 相同；
+// 为 nil 表示压缩器一次性产生输出（huffman 先建码表，zstd 调用 C 库），不能提前停止
 var compressFunc = []struct {
 	algo       string
 	compress   func(dst []byte, src []float64) []byte
 	decompress func(dst []float64, src []byte) ([]float64, error)
+	bitExact   bool
+	bounded    func(dst []byte, src []float64, limit int) ([]byte, bool)
 }{
-	{"huffman", huffman.CompressFloat, huffman.DecompressFloat},
-	{"elf", elf.CompressFloat, elf.DecompressFloat},
-	{"chimp128", chimp128.CompressFloat, chimp128.DecompressFloat},
-	{"fpc", fpc.CompressFloat, fpc.DecompressFloat},
-	{"zstd", zstd.CompressFloat, zstd.DecompressFloat},
+	{"huffman", huffman.CompressFloat, huffman.DecompressFloat, false, nil},
+	{"elf", elf.CompressFloat, elf.DecompressFloat, false, elf.CompressFloatBounded},
+	{"chimp128", chimp128.CompressFloat, chimp128.DecompressFloat, false, chimp128.CompressFloatBounded},
+	{"fpc", fpc.CompressFloat, fpc.DecompressFloat, true, fpc.CompressFloatBounded},
+	{"zstd", zstd.CompressFloat, zstd.DecompressFloat, true, nil},
+}
+
+// compatibleParam 判断参数组合是否合法：下标在范围内，且产生位模式的变换只配合 bitExact 的压缩器
+func compatibleParam(param []int) bool {
+	if len(param) != 4 {
+		return false
+	}
+	limits := []int{len(rangedFunc), len(scaleFunc), len(delFunc), len(compressFunc)}
+	for i, v := range param {
+		if v < 0 || v >= limits[i] {
+			return false
+		}
+	}
+	bitPattern := rangedFunc[param[0]].bitPattern || scaleFunc[param[1]].bitPattern || delFunc[param[2]].bitPattern
+	return !bitPattern || compressFunc[param[3]].bitExact
 }
 
 func newCSVWriter(path string) *csv.Writer {
@@ -58,6 +78,27 @@ func newCSVWriter(path string) *csv.Writer {
 	}
 	return csv.NewWriter(f)
 }
+
+// featureNames flattenStats 每一维特征的名称，顺序与 flattenStats 一致
+var featureNames = func() []string {
+	names := []string{"min", "max", "mean", "median", "std_dev", "variance",
+		"skewness", "kurtosis", "range", "iqr", "q1", "q3",
+		"unique_count", "unique_ratio", "zero_count", "zero_ratio", "integer_count", "integer_ratio"}
+	for _, prefix := range []string{"diff", "diff2"} {
+		for _, name := range []string{"min", "max", "mean", "std_dev", "range", "zero_ratio", "unique_count", "unique_ratio"} {
+			names = append(names, prefix+"_"+name)
+		}
+	}
+	names = append(names, "monotonicity", "smoothness", "change_points",
+		"max_run_length", "avg_run_length", "run_count", "constant_run_ratio",
+		"avg_set_bits", "sign_changes", "mantissa_entropy", "exponent_range", "common_exponent",
+		"entropy", "percentile_95", "percentile_5")
+	for lag := 1; lag <= 10; lag++ {
+		names = append(names, fmt.Sprintf("autocorr_%d", lag))
+	}
+	return append(names, "periodicity", "periodic_score")
+}()
+
 func flattenStats(s *common.TimeSeriesStats) []float64 {
 	var v []float64
 	// 基本统计
@@ -125,18 +166,17 @@ func flattenStats(s *common.TimeSeriesStats) []float64 {
 
 	return v
 }
-func nullFunc(src []float64) []float64 {
-	return src
+func nullFunc(src []float64) ([]float64, []byte) {
+	return src, nil
 }
-func nullRangedFunc(src []float64) ([]float64, float64) {
-	return src, 0
+func nullRecoverFunc(src []float64, side []byte) ([]float64, error) {
+	return src, nil
 }
-func nullRangedRecoverFunc(src []float64, base float64) []float64 {
-	return src
-}
-func nullScaleFunc(src []float64) ([]float64, float64, float64) {
-	return src, 0, 0
+
+// withoutSide 适配不需要 side 数据的变换
+func withoutSide(f func([]float64) []float64) func([]float64) ([]float64, []byte) {
+	return func(src []float64) ([]float64, []byte) { return f(src), nil }
 }
-func nullScaleRecoverFunc(src []float64, minNum, maxNum float64) []float64 {
-	return src
+func withoutSideRecover(f func([]float64) []float64) func([]float64, []byte) ([]float64, error) {
+	return func(src []float64, side []byte) ([]float64, error) { return f(src), nil }
 }
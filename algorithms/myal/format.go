@@ -0,0 +1,549 @@
+package myal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/algorithms/registry"
+	"myalgo/common"
+	"sort"
+)
+
+// 不依赖 registry 的整数编码：按值域位宽直接打包
+const bitpackName = "bitpack"
+
+// 浮点数编码模式
+const (
+	floatModeRaw     byte = 0 // 直接使用浮点压缩算法
+	floatModeDecimal byte = 1 // 按 Precision 缩放为整数，无法精确缩放的值作为异常
+)
+
+// 枚举编码时频率表的总量上限
+const enumFreqScale = 1 << 16
+
+// normalize 返回补全后的语义副本：整数补全值域，枚举补全字典和直方图，不修改调用方的结构
+func (as *ApplicationSemantics) normalize(src []uint64) *ApplicationSemantics {
+	c := *as
+	switch c.DataType {
+	case Int64:
+		if len(c.DataRange) != 2 || !inRange(src, c.DataRange[0], c.DataRange[1]) {
+			c.DataRange = valueRange(src)
+		}
+	case Enum:
+		if len(c.EnumValue) == 0 {
+			c.EnumValue = distinctValues(src)
+		}
+		if len(c.DataHistogram) != len(c.EnumValue) {
+			c.DataHistogram = histogram(src, c.EnumValue)
+		}
+	}
+	return &c
+}
+
+func inRange(src []uint64, lo, hi uint64) bool {
+	for _, v := range src {
+		if v < lo || v > hi {
+			return false
+		}
+	}
+	return true
+}
+
+func valueRange(src []uint64) []uint64 {
+	if len(src) == 0 {
+		return []uint64{0, 0}
+	}
+	lo, hi := src[0], src[0]
+	for _, v := range src {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return []uint64{lo, hi}
+}
+
+func distinctValues(src []uint64) []uint64 {
+	seen := make(map[uint64]bool)
+	var values []uint64
+	for _, v := range src {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+func histogram(src []uint64, enum []uint64) []uint64 {
+	index := enumIndex(enum)
+	hist := make([]uint64, len(enum))
+	for _, v := range src {
+		if i, ok := index[v]; ok {
+			hist[i]++
+		}
+	}
+	return hist
+}
+
+func enumIndex(enum []uint64) map[uint64]int {
+	index := make(map[uint64]int, len(enum))
+	for i, v := range enum {
+		if _, ok := index[v]; !ok {
+			index[v] = i
+		}
+	}
+	return index
+}
+
+// appendSemantics 序列化语义信息
+func appendSemantics(dst []byte, as *ApplicationSemantics) []byte {
+	dst = binary.AppendUvarint(dst, as.TimeSeriesID)
+	dst = appendString(dst, string(as.DataType))
+	dst = appendUint64s(dst, as.DataRange)
+	dst = binary.AppendUvarint(dst, as.Precision)
+	dst = appendUint64s(dst, as.DataHistogram)
+	dst = appendUint64s(dst, as.EnumValue)
+	dst = appendStrings(dst, as.FloatCompressor)
+	dst = appendStrings(dst, as.IntegerCompressor)
+	return dst
+}
+
+func readSemantics(r *reader) *ApplicationSemantics {
+	return &ApplicationSemantics{
+		TimeSeriesID:      r.uvarint(),
+		DataType:          DataType(r.string()),
+		DataRange:         r.uint64s(),
+		Precision:         r.uvarint(),
+		DataHistogram:     r.uint64s(),
+		EnumValue:         r.uint64s(),
+		FloatCompressor:   r.strings(),
+		IntegerCompressor: r.strings(),
+	}
+}
+
+func appendString(dst []byte, s string) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(s)))
+	return append(dst, s...)
+}
+
+func appendStrings(dst []byte, ss []string) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(ss)))
+	for _, s := range ss {
+		dst = appendString(dst, s)
+	}
+	return dst
+}
+
+func appendUint64s(dst []byte, vs []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(vs)))
+	for _, v := range vs {
+		dst = binary.AppendUvarint(dst, v)
+	}
+	return dst
+}
+
+// reader 顺序读取头部字段，出错后的读取都返回零值，调用方最后检查 err
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("truncated varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	b := r.bytes(1)
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.fail("need %d bytes, %d left", n, len(r.buf))
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// count 读取元素个数，并按每个元素至少 1 字节检查剩余长度，避免异常数据导致超大分配
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail("count %d exceeds remaining %d bytes", n, len(r.buf))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+func (r *reader) strings() []string {
+	n := r.count()
+	if n == 0 {
+		return nil
+	}
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = r.string()
+	}
+	return ss
+}
+
+func (r *reader) uint64s() []uint64 {
+	n := r.count()
+	if n == 0 {
+		return nil
+	}
+	vs := make([]uint64, n)
+	for i := range vs {
+		vs[i] = r.uvarint()
+	}
+	return vs
+}
+
+// appendIntegers 减去最小值后，在 bitpack 和 codecs 中选择输出最小的算法，记录算法名称
+func appendIntegers(dst []byte, src []uint64, dataRange []uint64, codecs []string) []byte {
+	lo, hi := dataRange[0], dataRange[1]
+	offset := make([]uint64, len(src))
+	for i, v := range src {
+		offset[i] = v - lo
+	}
+	bestName, best := bitpackName, appendBitpack(nil, offset, bits.Len64(hi-lo))
+	for _, name := range codecs {
+		codec, ok := registry.Integer(name)
+		if !ok || !codec.Supports(offset) {
+			continue
+		}
+		if out, ok := codec.RoundTrip(offset); ok && len(out) < len(best) {
+			bestName, best = name, out
+		}
+	}
+	dst = binary.AppendUvarint(dst, lo)
+	dst = appendString(dst, bestName)
+	dst = binary.AppendUvarint(dst, uint64(len(best)))
+	return append(dst, best...)
+}
+
+func readIntegers(r *reader, n int) ([]uint64, error) {
+	lo := r.uvarint()
+	name := r.string()
+	payload := r.bytes(r.uvarint())
+	if r.err != nil {
+		return nil, r.err
+	}
+	var values []uint64
+	var err error
+	if name == bitpackName {
+		values, err = readBitpack(payload, n)
+	} else if codec, ok := registry.Integer(name); ok {
+		values, err = codec.Decompress(nil, payload)
+	} else {
+		err = fmt.Errorf("unknown integer codec %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("%s decoded %d values, expected %d", name, len(values), n)
+	}
+	for i := range values {
+		values[i] += lo
+	}
+	return values, nil
+}
+
+// appendBitpack 以 width 位定长打包，高位在前
+func appendBitpack(dst []byte, src []uint64, width int) []byte {
+	dst = append(dst, byte(width))
+	var acc uint64
+	filled := 0
+	for _, v := range src {
+		for left := width; left > 0; {
+			take := min(left, 64-filled)
+			chunk := (v >> (left - take)) & (1<<take - 1)
+			acc |= chunk << (64 - filled - take)
+			filled += take
+			left -= take
+			if filled == 64 {
+				dst = binary.BigEndian.AppendUint64(dst, acc)
+				acc, filled = 0, 0
+			}
+		}
+	}
+	for filled > 0 {
+		dst = append(dst, byte(acc>>56))
+		acc <<= 8
+		filled -= min(filled, 8)
+	}
+	return dst
+}
+
+func readBitpack(src []byte, n int) ([]uint64, error) {
+	if len(src) < 1 || src[0] > 64 {
+		return nil, fmt.Errorf("invalid bitpack header")
+	}
+	width := int(src[0])
+	src = src[1:]
+	if uint64(len(src))*8 < uint64(width)*uint64(n) {
+		return nil, fmt.Errorf("bitpack payload too short")
+	}
+	if width == 0 {
+		// 位宽为 0 时 n 不受 payload 约束，逐步追加，损坏的 n 不会一次分配巨大的切片
+		var values []uint64
+		for i := 0; i < n; i++ {
+			values = append(values, 0)
+		}
+		return values, nil
+	}
+	values := make([]uint64, n)
+	pos := 0
+	for i := range values {
+		var v uint64
+		for b := 0; b < width; b++ {
+			bit := src[pos>>3] >> (7 - pos&7) & 1
+			v = v<<1 | uint64(bit)
+			pos++
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// appendFloats 比较直接压缩和十进制缩放两种方式，选择输出较小的一种
+func appendFloats(dst []byte, src []uint64, as *ApplicationSemantics) []byte {
+	best := appendRawFloats(nil, src, as.FloatCompressor)
+	if as.Precision > 0 {
+		if decimal, ok := appendDecimalFloats(nil, src, as); ok && len(decimal) < len(best) {
+			best = decimal
+		}
+	}
+	return append(dst, best...)
+}
+
+func appendRawFloats(dst []byte, src []uint64, codecs []string) []byte {
+	values := make([]float64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64frombits(v)
+	}
+	var bestName string
+	var best []byte
+	for _, name := range codecs {
+		codec, ok := registry.Float(name)
+		if !ok {
+			continue
+		}
+		if out, ok := codec.RoundTrip(values); ok && (best == nil || len(out) < len(best)) {
+			bestName, best = name, out
+		}
+	}
+	if best == nil {
+		// 列表中没有可用算法时退回 zstd
+		codec, _ := registry.Float("zstd")
+		bestName, best = codec.Name, codec.Compress(nil, values)
+	}
+	dst = append(dst, floatModeRaw)
+	dst = appendString(dst, bestName)
+	dst = binary.AppendUvarint(dst, uint64(len(best)))
+	return append(dst, best...)
+}
+
+// appendDecimalFloats 按 Precision 将值缩放为整数（zigzag 编码），无法按位还原的值记录原始位模式
+func appendDecimalFloats(dst []byte, src []uint64, as *ApplicationSemantics) ([]byte, bool) {
+	e := int(as.Precision)
+	scaled := make([]uint64, len(src))
+	var idx []uint64
+	var raw []uint64
+	for i, v := range src {
+		k, ok := common.DecimalScaleInt(math.Float64frombits(v), e)
+		if !ok {
+			idx = append(idx, uint64(i))
+			raw = append(raw, v)
+			continue
+		}
+		scaled[i] = zigzag(k)
+	}
+	if len(idx) > len(src)/2 {
+		return nil, false
+	}
+	dst = append(dst, floatModeDecimal)
+	dst = appendIntegers(dst, scaled, valueRange(scaled), as.IntegerCompressor)
+	dst = binary.AppendUvarint(dst, uint64(len(idx)))
+	prev := uint64(0)
+	for i, k := range idx {
+		dst = binary.AppendUvarint(dst, k-prev)
+		dst = binary.LittleEndian.AppendUint64(dst, raw[i])
+		prev = k
+	}
+	return dst, true
+}
+
+func readFloats(r *reader, n int, as *ApplicationSemantics) ([]uint64, error) {
+	switch mode := r.byte(); mode {
+	case floatModeRaw:
+		name := r.string()
+		payload := r.bytes(r.uvarint())
+		if r.err != nil {
+			return nil, r.err
+		}
+		codec, ok := registry.Float(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown float codec %q", name)
+		}
+		values, err := codec.Decompress(nil, payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != n {
+			return nil, fmt.Errorf("%s decoded %d values, expected %d", name, len(values), n)
+		}
+		out := make([]uint64, n)
+		for i, v := range values {
+			out[i] = math.Float64bits(v)
+		}
+		return out, nil
+	case floatModeDecimal:
+		scaled, err := readIntegers(r, n)
+		if err != nil {
+			return nil, err
+		}
+		e := int(as.Precision)
+		out := make([]uint64, n)
+		for i, k := range scaled {
+			out[i] = math.Float64bits(common.DecimalUnscaleInt(unzigzag(k), e))
+		}
+		count := r.count()
+		prev := uint64(0)
+		for i := 0; i < count; i++ {
+			prev += r.uvarint()
+			raw := r.bytes(8)
+			if r.err != nil {
+				return nil, r.err
+			}
+			if prev >= uint64(n) {
+				return nil, fmt.Errorf("exception index %d out of range", prev)
+			}
+			out[prev] = binary.LittleEndian.Uint64(raw)
+		}
+		return out, r.err
+	default:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, fmt.Errorf("unknown float mode %d", mode)
+	}
+}
+
+func zigzag(v int64) uint64   { return uint64(v<<1) ^ uint64(v>>63) }
+func unzigzag(u uint64) int64 { return int64(u>>1) ^ -int64(u&1) }
+
+// enumFrequencies 将直方图缩放为累计频率表，最后一个符号为转义符（不在字典中的值）
+func enumFrequencies(hist []uint64) ([]uint32, uint32) {
+	var sum uint64
+	for _, h := range hist {
+		sum += h
+	}
+	cum := make([]uint32, len(hist)+2)
+	for i, h := range hist {
+		f := uint64(1)
+		if sum > 0 {
+			f = max(1, h*enumFreqScale/sum)
+		}
+		cum[i+1] = cum[i] + uint32(f)
+	}
+	cum[len(hist)+1] = cum[len(hist)] + 1
+	return cum, cum[len(hist)+1]
+}
+
+// appendEnum 将值映射为字典下标，用直方图作为静态模型做算术编码；字典外的值单独记录
+func appendEnum(dst []byte, src []uint64, as *ApplicationSemantics) []byte {
+	index := enumIndex(as.EnumValue)
+	cum, total := enumFrequencies(as.DataHistogram)
+	escape := len(as.EnumValue)
+	enc := rangeCoding.NewRangeEncoder()
+	var escaped []uint64
+	for _, v := range src {
+		s, ok := index[v]
+		if !ok {
+			s = escape
+			escaped = append(escaped, v)
+		}
+		enc.Encode(cum[s], cum[s+1], total)
+	}
+	payload := enc.Finish()
+	dst = binary.AppendUvarint(dst, uint64(len(payload)))
+	dst = append(dst, payload...)
+	return appendUint64s(dst, escaped)
+}
+
+// enumLimit payload 最多能编码的符号数：转义符号的频率至少为 1，每个符号至少占 log2(total/maxFreq) 位，
+// 再为编码器结尾输出的位留出余量
+func enumLimit(payloadLen int, cum []uint32, total uint32) int {
+	var maxFreq uint32
+	for i := 0; i+1 < len(cum); i++ {
+		maxFreq = max(maxFreq, cum[i+1]-cum[i])
+	}
+	minBits := math.Log2(float64(total) / float64(maxFreq))
+	limit := float64(payloadLen*8+64) / minBits
+	if limit >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(limit) + 1
+}
+
+func readEnum(r *reader, n int, as *ApplicationSemantics) ([]uint64, error) {
+	if len(as.DataHistogram) != len(as.EnumValue) {
+		return nil, fmt.Errorf("histogram has %d entries, enum has %d", len(as.DataHistogram), len(as.EnumValue))
+	}
+	payload := r.bytes(r.uvarint())
+	escaped := r.uint64s()
+	if r.err != nil {
+		return nil, r.err
+	}
+	cum, total := enumFrequencies(as.DataHistogram)
+	if limit := enumLimit(len(payload), cum, total); n > limit {
+		return nil, fmt.Errorf("%d values exceed the %d that a %d byte payload can hold", n, limit, len(payload))
+	}
+	escape := len(as.EnumValue)
+	dec := rangeCoding.NewRangeDecoder(payload)
+	out := make([]uint64, n)
+	next := 0
+	for i := range out {
+		s := dec.DecodeSymbol(cum, total)
+		if s != escape {
+			out[i] = as.EnumValue[s]
+			continue
+		}
+		if next >= len(escaped) {
+			return nil, fmt.Errorf("missing escaped value at %d", i)
+		}
+		out[i] = escaped[next]
+		next++
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package myal
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// 单一符号的枚举和位宽为 0 的 bitpack 几乎不占 payload，值个数上限不能拒绝正常数据
+func TestLowEntropyRoundTrip(t *testing.T) {
+	constant := make([]uint64, 1_000_000)
+	for i := range constant {
+		constant[i] = 7
+	}
+	skewed := make([]uint64, 200_000)
+	for i := range skewed {
+		if i%5000 == 0 {
+			skewed[i] = 3
+		}
+	}
+	cases := []struct {
+		name string
+		src  []uint64
+		as   *ApplicationSemantics
+	}{
+		{"enum one symbol", constant, &ApplicationSemantics{DataType: Enum}},
+		{"enum skewed", skewed, &ApplicationSemantics{DataType: Enum}},
+		{"bitpack width 0", constant, &ApplicationSemantics{DataType: Int64}},
+	}
+	for _, c := range cases {
+		out := CompressWithSemantics(nil, c.src, c.as)
+		got, err := Decompress(nil, out)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if len(got) != len(c.src) {
+			t.Fatalf("%s: got %d values, expected %d", c.name, len(got), len(c.src))
+		}
+		for i := range c.src {
+			if got[i] != c.src[i] {
+				t.Fatalf("%s: value %d: got %d, expected %d", c.name, i, got[i], c.src[i])
+			}
+		}
+		t.Logf("%s: %d values into %d bytes", c.name, len(c.src), len(out))
+	}
+}
+
+// 头部中损坏的值个数返回错误，而不是分配巨大的切片。位宽为 0 的 bitpack 只能用总数上限拒绝
+func TestCorruptCount(t *testing.T) {
+	src := []uint64{7, 7, 7, 7}
+	cases := []struct {
+		dt     DataType
+		counts []uint64
+	}{
+		{Enum, []uint64{1 << 26, 1 << 40, 1<<64 - 1}},
+		{Int64, []uint64{1 << 40, 1<<64 - 1}},
+	}
+	for _, c := range cases {
+		as := (&ApplicationSemantics{DataType: c.dt}).normalize(src)
+		valid := CompressWithSemantics(nil, src, as)
+		header := appendSemantics(nil, as)
+		rest := valid[len(header)+1:] // 4 个值的 uvarint 占 1 字节
+		for _, n := range c.counts {
+			corrupt := binary.AppendUvarint(append([]byte(nil), header...), n)
+			corrupt = append(corrupt, rest...)
+			if _, err := Decompress(nil, corrupt); err == nil {
+				t.Errorf("%s: count %d accepted", c.dt, n)
+			}
+		}
+	}
+}
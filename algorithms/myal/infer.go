@@ -0,0 +1,282 @@
+package myal
+
+import (
+	"fmt"
+	"math"
+	"myalgo/algorithms/numerical"
+	"myalgo/common"
+	"strings"
+)
+
+// inferSampleLen 统计特征和约束只在前 inferSampleLen 个值上计算（DetectConstraints 的离散步长检测是平方复杂度），
+// 值域、整数判断和枚举字典仍然扫描全部数据
+const inferSampleLen = 4096
+
+// maxEnumValues 枚举字典的大小上限，字典和直方图都写在头部
+const maxEnumValues = 4096
+
+// maxPrecision 推断的最大十进制精度，minDecimalHit 为采用十进制模式所需的最低命中率
+const (
+	maxPrecision  = 10
+	minDecimalHit = 0.9
+)
+
+// maxIntValue 按整数压缩时 float64 能精确表示的最大值
+const maxIntValue = 1 << 53
+
+// Inference 从数据推断出的语义信息。调用方可以在压缩前检查和修改 Semantics
+type Inference struct {
+	Semantics   *ApplicationSemantics
+	Confidence  float64  // [0, 1]，越高表示分类越可靠
+	Reasons     []string // 分类和算法选择的依据
+	Stats       *common.TimeSeriesStats
+	Constraints *numerical.NumericalConstraints
+}
+
+func (inf *Inference) String() string {
+	as := inf.Semantics
+	var b strings.Builder
+	fmt.Fprintf(&b, "type=%s confidence=%.2f range=%v precision=%d enum=%d\n",
+		as.DataType, inf.Confidence, as.DataRange, as.Precision, len(as.EnumValue))
+	fmt.Fprintf(&b, "float compressors: %v\ninteger compressors: %v\n", as.FloatCompressor, as.IntegerCompressor)
+	for _, r := range inf.Reasons {
+		fmt.Fprintf(&b, "- %s\n", r)
+	}
+	return b.String()
+}
+
+func (inf *Inference) reason(format string, args ...any) {
+	inf.Reasons = append(inf.Reasons, fmt.Sprintf(format, args...))
+}
+
+// InferSemantics 结合 AnalyzeTimeSeries 和 DetectConstraints 推断数据类型、值域、精度、直方图和候选压缩算法
+func InferSemantics(values []float64) *Inference {
+	sample := values[:min(len(values), inferSampleLen)]
+	inf := &Inference{
+		Semantics:   &ApplicationSemantics{DataType: Float64},
+		Stats:       common.AnalyzeTimeSeries(sample),
+		Constraints: numerical.DetectConstraints(sample),
+	}
+	if len(values) == 0 {
+		inf.reason("empty input")
+		inf.Semantics.FloatCompressor = []string{"zstd"}
+		return inf
+	}
+	// 样本越少越不可靠
+	sampleFactor := float64(len(sample)) / float64(len(sample)+64)
+
+	enum := inferEnum(values)
+	nc := inf.Constraints
+	switch {
+	case enum != nil && (nc.HasConstraint(numerical.ConstraintEnumeration) || len(enum) <= len(values)/100):
+		inf.inferEnum(values, enum)
+		inf.Confidence = 0.5 + 0.5*(1-float64(len(enum))/float64(max(len(values)/100, 1)))
+	case allIntegers(values):
+		inf.inferInt(values)
+		inf.Confidence = 1
+	default:
+		inf.inferFloat(values)
+	}
+	inf.Confidence = math.Max(0, math.Min(1, inf.Confidence*sampleFactor))
+	return inf
+}
+
+// inferEnum 统计不同值，超过 maxEnumValues 时返回 nil
+func inferEnum(values []float64) []uint64 {
+	seen := make(map[uint64]bool)
+	for _, v := range values {
+		bits := math.Float64bits(v)
+		if !seen[bits] {
+			if len(seen) == maxEnumValues {
+				return nil
+			}
+			seen[bits] = true
+		}
+	}
+	enum := make([]uint64, 0, len(seen))
+	for bits := range seen {
+		enum = append(enum, bits)
+	}
+	return distinctValues(enum)
+}
+
+// isUnsignedInt 判断 v 能否无损转换为 uint64 再转回
+func isUnsignedInt(v float64) bool {
+	return v == math.Trunc(v) && v >= 0 && v <= maxIntValue && !math.Signbit(v)
+}
+
+func allIntegers(values []float64) bool {
+	for _, v := range values {
+		if !isUnsignedInt(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (inf *Inference) inferEnum(values []float64, enum []uint64) {
+	as := inf.Semantics
+	as.DataType = Enum
+	as.EnumValue = enum
+	as.DataHistogram = histogram(bitsOf(values), enum)
+	inf.reason("%d distinct values in %d (at most 1%%), encoded as enum", len(enum), len(values))
+}
+
+func (inf *Inference) inferInt(values []float64) {
+	as := inf.Semantics
+	as.DataType = Int64
+	src, _ := as.Encode(values)
+	as.DataRange = valueRange(src)
+	as.IntegerCompressor = proposeIntegerCompressors(inf)
+	inf.reason("all values are non-negative integers in %v", as.DataRange)
+}
+
+func (inf *Inference) inferFloat(values []float64) {
+	as := inf.Semantics
+	nc, stats := inf.Constraints, inf.Stats
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	as.DataRange = []uint64{math.Float64bits(lo), math.Float64bits(hi)}
+
+	// DetectConstraints 的精度只作为起点，实际精度以能否按位还原为准
+	hint := 1
+	if nc.HasConstraint(numerical.ConstraintPrecision) && nc.Precision > 0 {
+		hint = nc.Precision
+	}
+	sample := values[:min(len(values), inferSampleLen)]
+	if e, hit := decimalPrecision(sample, hint); e > 0 {
+		as.Precision = uint64(e)
+		inf.Confidence = hit
+		inf.reason("decimal precision %d recovers %.1f%% of sampled values exactly", e, hit*100)
+	} else {
+		inf.Confidence = math.Max(0.5, 1-stats.IntegerRatio)
+		inf.reason("no decimal precision detected, integer ratio %.2f", stats.IntegerRatio)
+	}
+
+	as.FloatCompressor = proposeFloatCompressors(inf, lo, hi)
+	if as.Precision > 0 {
+		as.IntegerCompressor = proposeIntegerCompressors(inf)
+	}
+}
+
+// decimalPrecision 从 hint 开始寻找最小的十进制指数，使至少 minDecimalHit 的值能按位还原，返回指数和命中率
+func decimalPrecision(sample []float64, hint int) (int, float64) {
+	hitRatio := func(e int) float64 {
+		hit := 0
+		for _, v := range sample {
+			if _, ok := common.DecimalScaleInt(v, e); ok {
+				hit++
+			}
+		}
+		return float64(hit) / float64(len(sample))
+	}
+	for e := min(hint, maxPrecision); e > 1 && hitRatio(e-1) >= minDecimalHit; e-- {
+		hint = e - 1
+	}
+	for e := max(min(hint, maxPrecision), 1); e <= maxPrecision; e++ {
+		if hit := hitRatio(e); hit >= minDecimalHit {
+			return e, hit
+		}
+	}
+	return 0, 0
+}
+
+// proposeFloatCompressors 按平滑度、重复度和值域挑选浮点压缩算法，按优先级排列
+func proposeFloatCompressors(inf *Inference, lo, hi float64) []string {
+	stats := inf.Stats
+	var codecs []string
+	if stats.DiffStats != nil && stats.DiffStats.ZeroRatio > 0.3 {
+		codecs = append(codecs, "gorilla", "chimp")
+		inf.reason("%.0f%% of deltas are zero, prefer xor codecs", stats.DiffStats.ZeroRatio*100)
+	}
+	codecs = append(codecs, "chimp128")
+	// elf 在极端数值上会退化，只在十进制数据且值域合理时使用
+	if inf.Semantics.Precision > 0 && math.Abs(lo) < 1e15 && math.Abs(hi) < 1e15 {
+		codecs = append(codecs, "elf")
+	}
+	if stats.UniqueRatio < 0.1 {
+		codecs = append(codecs, "zstd")
+		inf.reason("unique ratio %.3f, add zstd", stats.UniqueRatio)
+	}
+	return codecs
+}
+
+// proposeIntegerCompressors 按游程挑选整数压缩算法
+func proposeIntegerCompressors(inf *Inference) []string {
+	codecs := []string{"pfor", "simple8b"}
+	if rl := inf.Stats.RunLength; rl != nil && rl.AvgRunLength > 4 {
+		codecs = append(codecs, "lz4")
+		inf.reason("average run length %.1f, add lz4", rl.AvgRunLength)
+	}
+	return append(codecs, "zstd")
+}
+
+func bitsOf(values []float64) []uint64 {
+	src := make([]uint64, len(values))
+	for i, v := range values {
+		src[i] = math.Float64bits(v)
+	}
+	return src
+}
+
+// Encode 按语义把浮点数转换为 CompressWithSemantics 的输入：整数为值本身，浮点数和枚举为位模式
+func (as *ApplicationSemantics) Encode(values []float64) ([]uint64, error) {
+	if as.DataType != Int64 {
+		return bitsOf(values), nil
+	}
+	src := make([]uint64, len(values))
+	for i, v := range values {
+		if !isUnsignedInt(v) {
+			return nil, fmt.Errorf("myal: value %v at %d is not a non-negative integer", v, i)
+		}
+		src[i] = uint64(v)
+	}
+	return src, nil
+}
+
+// Decode 还原 Encode
+func (as *ApplicationSemantics) Decode(dst []float64, src []uint64) []float64 {
+	for _, v := range src {
+		if as.DataType == Int64 {
+			dst = append(dst, float64(v))
+		} else {
+			dst = append(dst, math.Float64frombits(v))
+		}
+	}
+	return dst
+}
+
+// CompressInferred 推断语义后压缩 values。review 不为 nil 时在压缩前调用，可以修改 Semantics，返回错误则放弃压缩
+func CompressInferred(dst []byte, values []float64, review func(*Inference) error) ([]byte, error) {
+	inf := InferSemantics(values)
+	if review != nil {
+		if err := review(inf); err != nil {
+			return nil, err
+		}
+	}
+	src, err := inf.Semantics.Encode(values)
+	if err != nil {
+		return nil, err
+	}
+	return CompressWithSemantics(dst, src, inf.Semantics), nil
+}
+
+// CompressFloat 以推断出的语义压缩浮点数。没有 review 时推断出的语义总能编码输入，出错说明推断有误
+func CompressFloat(dst []byte, src []float64) []byte {
+	out, err := CompressInferred(dst, src, nil)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// DecompressFloat 还原 CompressFloat 和 CompressInferred
+func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	values, as, err := DecompressWithSemantics(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return as.Decode(dst, values), nil
+}
@@ -1,7 +1,9 @@
 package myal
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 )
 
 type DataType string
@@ -58,20 +60,73 @@ func PrintDetail(as ApplicationSemantics) {
 	fmt.Println("EnumValue:", as.EnumValue)
 }
 
+// FloatCompress 以默认语义压缩 float64 的位模式
 func FloatCompress(dst []byte, src []uint64) []byte {
-	return dst
+	return CompressWithSemantics(dst, src, NewDefaultApplicationSemantics())
 }
 
+// IntegerCompress 以默认语义压缩整数
 func IntegerCompress(dst []byte, src []uint64) []byte {
-	return dst
+	as := NewDefaultApplicationSemantics()
+	as.DataType = Int64
+	return CompressWithSemantics(dst, src, as)
 }
 
 func Compress(dst []byte, src []uint64) []byte {
+	return CompressWithSemantics(dst, src, NewDefaultApplicationSemantics())
+}
 
-	return dst
+// CompressWithSemantics 按 DataType 分派：浮点数（src 为 Float64bits）、整数或枚举。
+// 语义信息写入输出头部，Decompress 不需要额外参数
+func CompressWithSemantics(dst []byte, src []uint64, as *ApplicationSemantics) []byte {
+	as = as.normalize(src)
+	dst = appendSemantics(dst, as)
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	if len(src) == 0 {
+		return dst
+	}
+	switch as.DataType {
+	case Int64:
+		return appendIntegers(dst, src, as.DataRange, as.IntegerCompressor)
+	case Enum:
+		return appendEnum(dst, src, as)
+	}
+	return appendFloats(dst, src, as)
 }
 
 func Decompress(dst []uint64, src []byte) ([]uint64, error) {
+	dst, _, err := DecompressWithSemantics(dst, src)
+	return dst, err
+}
 
-	return dst, nil
+// DecompressWithSemantics 解压并返回压缩时使用的语义信息
+func DecompressWithSemantics(dst []uint64, src []byte) ([]uint64, *ApplicationSemantics, error) {
+	r := &reader{buf: src}
+	as := readSemantics(r)
+	n := r.uvarint()
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("myal: invalid header: %w", r.err)
+	}
+	if n > math.MaxInt32 {
+		return nil, nil, fmt.Errorf("myal: invalid value count %d", n)
+	}
+	if n == 0 {
+		return dst, as, nil
+	}
+	var values []uint64
+	var err error
+	switch as.DataType {
+	case Int64:
+		values, err = readIntegers(r, int(n))
+	case Enum:
+		values, err = readEnum(r, int(n), as)
+	case Float64:
+		values, err = readFloats(r, int(n), as)
+	default:
+		err = fmt.Errorf("unknown data type %q", as.DataType)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("myal: %w", err)
+	}
+	return append(dst, values...), as, nil
 }
@@ -14,65 +14,74 @@ import (
 	lz4codec "myalgo/algorithms/lz4"
 	snappycodec "myalgo/algorithms/snappy"
 	xzcodec "myalgo/algorithms/xz"
+	"myalgo/common"
 
 	"github.com/valyala/gozstd"
 )
 
 type (
-	compressWithConstraintsFunc   func([]byte, []float64, *NumericalConstraints) []byte
-	decompressWithConstraintsFunc func([]float64, []byte, *NumericalConstraints) ([]float64, error)
-	uint64BackendCompressor       func([]byte, []uint64) []byte
-	uint64BackendDecompressor     func([]uint64, []byte) ([]uint64, error)
+	uint64BackendCompressor   func([]byte, []uint64) []byte
+	uint64BackendDecompressor func([]uint64, []byte) ([]uint64, error)
 )
 
 // CompressFloat 压缩 float64 数组（包装函数，自动检测约束）
 func CompressFloat(dst []byte, src []float64) []byte {
-	return compressFloatEntry(dst, src, CompressFloatWithConstraints)
+	return compressFloatEntry(dst, src, BackendZstd)
 }
 
 // CompressFloatLZ4 提供与 CompressFloat 相同接口、以 LZ4 为后端
 func CompressFloatLZ4(dst []byte, src []float64) []byte {
-	return compressFloatEntry(dst, src, CompressFloatWithConstraintsLZ4)
+	return compressFloatEntry(dst, src, BackendLZ4)
 }
 
 // CompressFloatSnappy 提供与 CompressFloat 相同接口、以 Snappy 为后端
 func CompressFloatSnappy(dst []byte, src []float64) []byte {
-	return compressFloatEntry(dst, src, CompressFloatWithConstraintsSnappy)
+	return compressFloatEntry(dst, src, BackendSnappy)
 }
 
 // CompressFloatBrotli 提供与 CompressFloat 相同接口、以 Brotli 为后端
 func CompressFloatBrotli(dst []byte, src []float64) []byte {
-	return compressFloatEntry(dst, src, CompressFloatWithConstraintsBrotli)
+	return compressFloatEntry(dst, src, BackendBrotli)
 }
 
 // CompressFloatXZ 提供与 CompressFloat 相同接口、以 XZ 为后端
 func CompressFloatXZ(dst []byte, src []float64) []byte {
-	return compressFloatEntry(dst, src, CompressFloatWithConstraintsXZ)
+	return compressFloatEntry(dst, src, BackendXZ)
+}
+
+// CompressFloatPFOR 提供与 CompressFloat 相同接口、以 PFOR 位打包为后端
+func CompressFloatPFOR(dst []byte, src []float64) []byte {
+	return compressFloatEntry(dst, src, BackendPFOR)
 }
 
 // DecompressFloat 解压缩到 float64 数组（包装函数，从数据中恢复约束）
 func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
-	return decompressFloatEntry(dst, src, DecompressFloatWithConstraints)
+	return decompressFloatEntry(dst, src, BackendZstd)
 }
 
 // DecompressFloatLZ4 提供与 DecompressFloat 相同接口、以 LZ4 为后端
 func DecompressFloatLZ4(dst []float64, src []byte) ([]float64, error) {
-	return decompressFloatEntry(dst, src, DecompressFloatWithConstraintsLZ4)
+	return decompressFloatEntry(dst, src, BackendLZ4)
 }
 
 // DecompressFloatSnappy 提供与 DecompressFloat 相同接口、以 Snappy 为后端
 func DecompressFloatSnappy(dst []float64, src []byte) ([]float64, error) {
-	return decompressFloatEntry(dst, src, DecompressFloatWithConstraintsSnappy)
+	return decompressFloatEntry(dst, src, BackendSnappy)
 }
 
 // DecompressFloatBrotli 提供与 DecompressFloat 相同接口、以 Brotli 为后端
 func DecompressFloatBrotli(dst []float64, src []byte) ([]float64, error) {
-	return decompressFloatEntry(dst, src, DecompressFloatWithConstraintsBrotli)
+	return decompressFloatEntry(dst, src, BackendBrotli)
 }
 
 // DecompressFloatXZ 提供与 DecompressFloat 相同接口、以 XZ 为后端
 func DecompressFloatXZ(dst []float64, src []byte) ([]float64, error) {
-	return decompressFloatEntry(dst, src, DecompressFloatWithConstraintsXZ)
+	return decompressFloatEntry(dst, src, BackendXZ)
+}
+
+// DecompressFloatPFOR 提供与 DecompressFloat 相同接口、以 PFOR 位打包为后端
+func DecompressFloatPFOR(dst []float64, src []byte) ([]float64, error) {
+	return decompressFloatEntry(dst, src, BackendPFOR)
 }
 
 // encodeConstraints 将约束信息编码为字节数组
@@ -204,38 +213,19 @@ func decodeConstraints(data []byte) (*NumericalConstraints, int) {
 	return nc, offset // 返回约束对象和头部大小
 }
 
-// numerical.go CompressFloat 压缩入口 定义数值约束
-func compressFloatEntry(dst []byte, src []float64, handler compressWithConstraintsFunc) []byte {
-	if len(src) == 0 {
-		return dst
-	}
-	definedNC := NewNumericalConstraints()
-	// 用户定义 数值约束
-	definedNC.EnableConstraint(0)
-	definedNC.EnableConstraint(3)
-	definedNC.SetPrecisionConstraint(3)
-	definedNC.SetMonotonicityConstraint(1)
-	//
-	if definedNC.IsConstraintValid() {
-		fmt.Println("使用预定义的数值约束进行压缩")
-		header := encodeConstraints(definedNC)
-		compressed := handler(nil, src, definedNC)
-		return append(header, compressed...)
-	}
-	fmt.Println("自动检测数值约束进行压缩")
-	nc := DetectConstraints(src)
-	header := encodeConstraints(nc)
-	compressed := handler(nil, src, nc)
-	return append(header, compressed...)
-}
-
-func decompressFloatEntry(dst []float64, src []byte, handler decompressWithConstraintsFunc) ([]float64, error) {
-	if len(src) == 0 {
-		return dst, nil
+// numerical.go CompressFloat 压缩入口 定义数值约束。
+// CompressFloatWithOptions 只在后端名称未知时出错，入口只传 backends 表中的常量名称，所以不会 panic；
+// 外部调用方应直接使用 CompressFloatWithOptions 处理错误
+func compressFloatEntry(dst []byte, src []float64, backendName string) []byte {
+	out, err := CompressFloatWithOptions(dst, src, Options{Backend: backendName})
+	if err != nil {
+		panic(err)
 	}
-	nc, headerSize := decodeConstraints(src)
-	compressedData := src[headerSize:]
-	return handler(dst, compressedData, nc)
+	return out
+}
+
+func decompressFloatEntry(dst []float64, src []byte, backendName string) ([]float64, error) {
+	return DecompressFloatWithOptions(dst, src, Options{Backend: backendName})
 }
 
 func CompressFloatWithConstraints(dst []byte, src []float64, nc *NumericalConstraints) []byte {
@@ -244,7 +234,7 @@ func CompressFloatWithConstraints(dst []byte, src []float64, nc *NumericalConstr
 	}
 	// 1. 预处理阶段
 	// 根据约束进行预处理
-	processed := preprocessData(src, nc)
+	processed := preprocessData(src, nc, common.NopTracer)
 
 	// 在 LZ77 前再做一次通用 Delta 编码以增强重复度
 	// deltaEncoded := deltaEncodeForLZ77(processed)
@@ -253,16 +243,14 @@ func CompressFloatWithConstraints(dst []byte, src []float64, nc *NumericalConstr
 	// compressed := huffmanLib.CompressBytes(nil, lz77Bytes)
 
 	// 现改用 zstd 直接处理字节流
-	deltaBytes := uint64SliceToBytes(processed)
-	compressed := gozstd.Compress(nil, deltaBytes)
-	return compressed
+	return compressZstd(nil, processed)
 }
 
 func compressFloatWithConstraintsUint64Backend(dst []byte, src []float64, nc *NumericalConstraints, backend uint64BackendCompressor) []byte {
 	if len(src) == 0 {
 		return dst
 	}
-	processed := preprocessData(src, nc)
+	processed := preprocessData(src, nc, common.NopTracer)
 	return backend(dst[:0], processed)
 }
 
@@ -297,23 +285,7 @@ func DecompressFloatWithConstraints(dst []float64, src []byte, nc *NumericalCons
 	// processedDelta, err := lz77.Decompress(nil, lz77Bytes)
 
 	// 现使用 zstd 直接解压字节流
-	deltaBytes, err := gozstd.Decompress(nil, src)
-	if err != nil {
-		return dst, err
-	}
-	processedDelta, err := bytesToUint64Slice(deltaBytes)
-	if err != nil {
-		return dst, err
-	}
-
-	// 逆 Delta，恢复到预处理阶段的表示
-	// processed := deltaDecodeForLZ77(processedDelta)
-
-	// 根据约束进行后处理，恢复原始数据
-	result := postprocessData(processedDelta, nc)
-
-	dst = append(dst, result...)
-	return dst, nil
+	return decompressFloatWithConstraintsUint64Backend(dst, src, nc, decompressZstd)
 }
 
 func decompressFloatWithConstraintsUint64Backend(dst []float64, src []byte, nc *NumericalConstraints, backend uint64BackendDecompressor) ([]float64, error) {
@@ -324,7 +296,8 @@ func decompressFloatWithConstraintsUint64Backend(dst []float64, src []byte, nc *
 	if err != nil {
 		return dst, err
 	}
-	result := postprocessData(processedDelta, nc)
+	// 根据约束进行后处理，恢复原始数据
+	result := postprocessData(processedDelta, nc, common.NopTracer)
 	dst = append(dst, result...)
 	return dst, nil
 }
@@ -349,12 +322,30 @@ func DecompressFloatWithConstraintsXZ(dst []float64, src []byte, nc *NumericalCo
 	return decompressFloatWithConstraintsUint64Backend(dst, src, nc, xzcodec.Decompress)
 }
 
+// compressZstd 以 zstd 压缩 uint64 数组的小端字节流
+func compressZstd(dst []byte, src []uint64) []byte {
+	return gozstd.Compress(dst, uint64SliceToBytes(src))
+}
+
+// decompressZstd 是 compressZstd 的逆过程，会检查字节对齐
+func decompressZstd(dst []uint64, src []byte) ([]uint64, error) {
+	deltaBytes, err := gozstd.Decompress(nil, src)
+	if err != nil {
+		return dst, err
+	}
+	values, err := bytesToUint64Slice(deltaBytes)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, values...), nil
+}
+
 // preprocessData 根据约束预处理数据
-func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
+func preprocessData(data []float64, nc *NumericalConstraints, tr common.Tracer) []uint64 {
 	result := make([]uint64, len(data))
+	size := len(data) * 8
 
 	if nc.HasConstraint(ConstraintEnumeration) && len(nc.EnumerationValues) > 0 {
-		fmt.Println("✓ 应用枚举值约束: 映射为枚举索引")
 		valueToIndex := make(map[float64]uint64, len(nc.EnumerationValues))
 		for idx, v := range nc.EnumerationValues {
 			valueToIndex[v] = uint64(idx)
@@ -375,6 +366,8 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 			}
 			result[i] = bestIdx
 		}
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "enumeration", InBytes: size, OutBytes: common.PackedBytes(result),
+			Detail: fmt.Sprintf("映射为枚举索引, %d 个枚举值", len(nc.EnumerationValues))})
 		return result
 	}
 
@@ -392,7 +385,6 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 
 	// 如果启用了离散步长约束，优先使用离散步长转换
 	if nc.HasConstraint(ConstraintDiscrete) && nc.DiscreteStep > 0 {
-		fmt.Printf("✓ 应用离散步长约束: 步长 = %.6f, 基数 = %.6f\n", nc.DiscreteStep, nc.MinValue)
 		// 找到基数（最小值）
 		baseValue := nc.MinValue
 
@@ -401,6 +393,8 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 			steps := (v - baseValue) / nc.DiscreteStep
 			result[i] = uint64(int64(steps + 0.5)) // 四舍五入
 		}
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "discrete", InBytes: size, OutBytes: common.PackedBytes(result),
+			Detail: fmt.Sprintf("步长 = %.6f, 基数 = %.6f", nc.DiscreteStep, nc.MinValue)})
 
 		// 打印转换后的值
 		// fmt.Printf("离散步长转换后前 %d 个值: ", n)
@@ -409,15 +403,12 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 		// }
 		// fmt.Println()
 	} else if nc.HasConstraint(ConstraintPrecision) && nc.Precision > 0 {
-		fmt.Printf("✓ 应用精度约束: 小数点后 %d 位\n", nc.Precision)
 		// 如果没有离散步长约束，但有精度约束，进行精度转换
 		// 将浮点数转换为整数（乘以 10^precision）
 		multiplier := 1.0
 		for i := 0; i < nc.Precision; i++ {
 			multiplier *= 10
 		}
-		fmt.Printf("乘数: %.0f\n", multiplier)
-
 		for i, v := range data {
 			// 转换为整数（四舍五入，避免浮点数精度误差）
 			temp := v * multiplier
@@ -427,6 +418,8 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 				result[i] = uint64(int64(temp - 0.5))
 			}
 		}
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "precision", InBytes: size, OutBytes: common.PackedBytes(result),
+			Detail: fmt.Sprintf("小数点后 %d 位, 乘数 %.0f", nc.Precision, multiplier)})
 
 		// 打印转换后的值
 		// fmt.Printf("精度转换后前 %d 个值: ", n)
@@ -435,9 +428,10 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 		// }
 		// fmt.Println()
 	} else {
-		fmt.Println("✓ 无约束，使用浮点数位表示")
 		// 直接使用浮点数的位表示
 		result = float64SliceToUint64(data)
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "raw-bits", InBytes: size, OutBytes: common.PackedBytes(result),
+			Detail: "无约束，使用浮点数位表示"})
 
 		// 打印转换后的值
 		// fmt.Printf("位表示前 %d 个值: ", n)
@@ -449,7 +443,6 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 
 	// 如果启用了单调性约束，转换为 delta 编码
 	if nc.HasConstraint(ConstraintMonotonicity) && len(result) > 1 {
-		fmt.Println("✓ 应用单调性约束: Delta 编码")
 		deltas := make([]uint64, len(result))
 		deltas[0] = result[0]
 
@@ -462,6 +455,8 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 				deltas[i] = uint64(int64(result[i]) - int64(result[i-1]))
 			}
 		}
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "delta", InBytes: common.PackedBytes(result), OutBytes: common.PackedBytes(deltas),
+			Detail: fmt.Sprintf("单调性约束 %d: Delta 编码", nc.Monotonicity)})
 
 		// 打印 delta 编码后的值
 		// fmt.Printf("Delta 编码后前 %d 个值: ", n)
@@ -487,11 +482,12 @@ func preprocessData(data []float64, nc *NumericalConstraints) []uint64 {
 }
 
 // postprocessData 根据约束后处理数据，恢复原始值
-func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
+func postprocessData(data []uint64, nc *NumericalConstraints, tr common.Tracer) []float64 {
 	result := make([]float64, len(data))
+	size := len(data) * 8
 
 	if nc.HasConstraint(ConstraintEnumeration) && len(nc.EnumerationValues) > 0 {
-		fmt.Println("✓ 恢复枚举值约束: 根据索引还原")
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "enumeration", InBytes: common.PackedBytes(data), OutBytes: size, Detail: "根据索引还原"})
 		for i, idx := range data {
 			intIdx := int(idx)
 			if intIdx >= 0 && intIdx < len(nc.EnumerationValues) {
@@ -518,7 +514,6 @@ func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
 	// 如果启用了单调性约束，先恢复 delta 编码
 	processed := data
 	if nc.HasConstraint(ConstraintMonotonicity) && len(data) > 1 {
-		fmt.Println("✓ 恢复 Delta 编码")
 		processed = make([]uint64, len(data))
 		processed[0] = data[0]
 
@@ -526,6 +521,8 @@ func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
 			// 从差值恢复原值
 			processed[i] = uint64(int64(processed[i-1]) + int64(data[i]))
 		}
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "delta", InBytes: common.PackedBytes(data), OutBytes: common.PackedBytes(processed),
+			Detail: "恢复 Delta 编码"})
 
 		// 打印恢复后的值
 		// fmt.Printf("Delta 解码后前 %d 个值: ", n)
@@ -537,7 +534,8 @@ func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
 
 	// 如果启用了离散步长约束，优先使用离散步长恢复
 	if nc.HasConstraint(ConstraintDiscrete) && nc.DiscreteStep > 0 {
-		fmt.Printf("✓ 恢复离散步长约束: 步长 = %.6f, 基数 = %.6f\n", nc.DiscreteStep, nc.MinValue)
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "discrete", InBytes: common.PackedBytes(processed), OutBytes: size,
+			Detail: fmt.Sprintf("恢复离散步长: 步长 = %.6f, 基数 = %.6f", nc.DiscreteStep, nc.MinValue)})
 
 		for i, steps := range processed {
 			result[i] = recoverDiscreteValue(nc.MinValue, nc.DiscreteStep, steps)
@@ -551,7 +549,8 @@ func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
 		// fmt.Println()
 	} else if nc.HasConstraint(ConstraintPrecision) && nc.Precision > 0 {
 		// 如果没有离散步长约束，但有精度约束，进行精度恢复
-		fmt.Printf("✓ 恢复精度约束: 小数点后 %d 位, 除数: %.0f\n", nc.Precision, math.Pow(10, float64(nc.Precision)))
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "precision", InBytes: common.PackedBytes(processed), OutBytes: size,
+			Detail: fmt.Sprintf("恢复精度: 小数点后 %d 位, 除数 %.0f", nc.Precision, math.Pow(10, float64(nc.Precision)))})
 		multiplier := 1.0
 		for i := 0; i < nc.Precision; i++ {
 			multiplier *= 10
@@ -568,7 +567,7 @@ func postprocessData(data []uint64, nc *NumericalConstraints) []float64 {
 		// }
 		// fmt.Println()
 	} else {
-		fmt.Println("✓ 无约束，从位表示恢复浮点数")
+		tr.Trace(common.TraceEvent{Stage: common.StageTransform, Name: "raw-bits", InBytes: common.PackedBytes(processed), OutBytes: size, Detail: "从位表示恢复浮点数"})
 		// 直接从位表示恢复浮点数
 		result = uint64SliceToFloat64(processed)
 
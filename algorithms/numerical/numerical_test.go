@@ -0,0 +1,81 @@
+package numerical
+
+import (
+	"testing"
+
+	"myalgo/common"
+)
+
+// 满足预定义约束（3 位小数、单调递增）的数据
+func monotonicData(n int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = 10 + float64(i)*0.125
+	}
+	return data
+}
+
+// 入口函数只使用 backends 表中的名称，每个后端都不能 panic，且能还原满足约束的数据
+func TestEntries(t *testing.T) {
+	entries := []struct {
+		name       string
+		compress   func([]byte, []float64) []byte
+		decompress func([]float64, []byte) ([]float64, error)
+	}{
+		{BackendZstd, CompressFloat, DecompressFloat},
+		{BackendLZ4, CompressFloatLZ4, DecompressFloatLZ4},
+		{BackendSnappy, CompressFloatSnappy, DecompressFloatSnappy},
+		{BackendBrotli, CompressFloatBrotli, DecompressFloatBrotli},
+		{BackendXZ, CompressFloatXZ, DecompressFloatXZ},
+		{BackendPFOR, CompressFloatPFOR, DecompressFloatPFOR},
+	}
+	if len(entries) != len(backends) {
+		t.Fatalf("%d entries for %d backends", len(entries), len(backends))
+	}
+	data := monotonicData(1000)
+	for _, e := range entries {
+		got, err := e.decompress(nil, e.compress(nil, data))
+		if err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		if len(got) != len(data) {
+			t.Fatalf("%s: got %d values, expected %d", e.name, len(got), len(data))
+		}
+		for i := range data {
+			if got[i] != data[i] {
+				t.Fatalf("%s: value %d: got %v, expected %v", e.name, i, got[i], data[i])
+			}
+		}
+	}
+}
+
+func TestUnknownBackend(t *testing.T) {
+	if _, err := CompressFloatWithOptions(nil, []float64{1}, Options{Backend: "gzip"}); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+	if _, err := DecompressFloatWithOptions(nil, []byte{1}, Options{Backend: "gzip"}); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+// 精度变换后的整数只需要少量位，delta 编码后更少，跟踪事件应报告变小的字节数
+func TestTransformTraceBytes(t *testing.T) {
+	data := monotonicData(1000)
+	var rec common.TraceRecorder
+	if _, err := CompressFloatWithOptions(nil, data, Options{Tracer: &rec}); err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]int{}
+	for _, ev := range rec.Events {
+		if ev.Stage == common.StageTransform {
+			out[ev.Name] = ev.OutBytes
+		}
+	}
+	precision, delta := out["precision"], out["delta"]
+	if precision == 0 || delta == 0 {
+		t.Fatalf("missing transform events: %v", rec.Events)
+	}
+	if precision >= len(data)*8 || delta >= precision {
+		t.Errorf("precision %d bytes, delta %d bytes, input %d bytes", precision, delta, len(data)*8)
+	}
+}
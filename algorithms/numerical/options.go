@@ -0,0 +1,164 @@
+package numerical
+
+import (
+	"fmt"
+	"strings"
+
+	brotlicodec "myalgo/algorithms/brotli"
+	lz4codec "myalgo/algorithms/lz4"
+	"myalgo/algorithms/pfor"
+	snappycodec "myalgo/algorithms/snappy"
+	xzcodec "myalgo/algorithms/xz"
+	"myalgo/common"
+)
+
+// 后端压缩器名称
+const (
+	BackendZstd   = "zstd"
+	BackendLZ4    = "lz4"
+	BackendSnappy = "snappy"
+	BackendBrotli = "brotli"
+	BackendXZ     = "xz"
+	BackendPFOR   = "pfor"
+)
+
+var backends = []struct {
+	name       string
+	compress   uint64BackendCompressor
+	decompress uint64BackendDecompressor
+}{
+	{BackendZstd, compressZstd, decompressZstd},
+	{BackendLZ4, lz4codec.Compress, lz4codec.Decompress},
+	{BackendSnappy, snappycodec.Compress, snappycodec.Decompress},
+	{BackendBrotli, brotlicodec.Compress, brotlicodec.Decompress},
+	{BackendXZ, xzcodec.Compress, xzcodec.Decompress},
+	{BackendPFOR, pfor.Compress, pfor.Decompress},
+}
+
+// Options 控制 numerical 压缩流水线
+type Options struct {
+	// Backend 后端压缩器名称，为空时使用 zstd
+	Backend string
+	// Constraints 预先给定的数值约束，为 nil 时使用预定义约束，预定义约束无效时自动检测
+	Constraints *NumericalConstraints
+	// Tracer 接收各阶段事件，为 nil 时不输出任何内容
+	Tracer common.Tracer
+}
+
+func findBackend(name string) (uint64BackendCompressor, uint64BackendDecompressor, error) {
+	if name == "" {
+		name = BackendZstd
+	}
+	for _, b := range backends {
+		if b.name == name {
+			return b.compress, b.decompress, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("numerical: unknown backend %q", name)
+}
+
+// predefinedConstraints 用户定义的数值约束
+func predefinedConstraints() *NumericalConstraints {
+	nc := NewNumericalConstraints()
+	nc.EnableConstraint(0)
+	nc.EnableConstraint(3)
+	nc.SetPrecisionConstraint(3)
+	nc.SetMonotonicityConstraint(1)
+	return nc
+}
+
+// CompressFloatWithOptions 按 opts 指定的约束、后端和 Tracer 压缩 float64 数组，后端名称未知时返回错误
+func CompressFloatWithOptions(dst []byte, src []float64, opts Options) ([]byte, error) {
+	compress, _, err := findBackend(opts.Backend)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) == 0 {
+		return dst, nil
+	}
+	tr := common.OrNop(opts.Tracer)
+
+	nc, source := opts.Constraints, "options"
+	if nc == nil {
+		nc, source = predefinedConstraints(), "predefined"
+	}
+	if !nc.IsConstraintValid() {
+		nc, source = DetectConstraints(src), "detected"
+	}
+	header := encodeConstraints(nc)
+	tr.Trace(common.TraceEvent{Stage: common.StageConstraints, Name: source, OutBytes: len(header), Detail: nc.Summary()})
+
+	processed := preprocessData(src, nc, tr)
+	// 部分后端（lz4、brotli）不会追加到 dst，单独压缩后再拼接
+	compressed := compress(nil, processed)
+	dst = append(dst, header...)
+	dst = append(dst, compressed...)
+
+	detail := "Options.Backend"
+	if opts.Backend == "" {
+		detail = "默认后端"
+	}
+	tr.Trace(common.TraceEvent{Stage: common.StageBackend, Name: backendName(opts.Backend),
+		InBytes: len(processed) * 8, OutBytes: len(compressed), Detail: detail})
+	return dst, nil
+}
+
+// DecompressFloatWithOptions 解压由 CompressFloatWithOptions 生成的数据，opts.Backend 需与压缩时一致
+func DecompressFloatWithOptions(dst []float64, src []byte, opts Options) ([]float64, error) {
+	if len(src) == 0 {
+		return dst, nil
+	}
+	tr := common.OrNop(opts.Tracer)
+	_, decompress, err := findBackend(opts.Backend)
+	if err != nil {
+		return nil, err
+	}
+	nc, headerSize := decodeConstraints(src)
+	tr.Trace(common.TraceEvent{Stage: common.StageConstraints, Name: "header", InBytes: headerSize, Detail: nc.Summary()})
+
+	processed, err := decompress(nil, src[headerSize:])
+	if err != nil {
+		return nil, err
+	}
+	tr.Trace(common.TraceEvent{Stage: common.StageBackend, Name: backendName(opts.Backend),
+		InBytes: len(src) - headerSize, OutBytes: len(processed) * 8})
+
+	return append(dst, postprocessData(processed, nc, tr)...), nil
+}
+
+func backendName(name string) string {
+	if name == "" {
+		return BackendZstd
+	}
+	return name
+}
+
+// Summary 返回已启用约束的单行描述
+func (nc *NumericalConstraints) Summary() string {
+	var parts []string
+	if nc.HasConstraint(ConstraintPrecision) {
+		parts = append(parts, fmt.Sprintf("precision=%d", nc.Precision))
+	}
+	if nc.HasConstraint(ConstraintRange) {
+		parts = append(parts, fmt.Sprintf("range=[%g,%g]", nc.MinValue, nc.MaxValue))
+	}
+	if nc.HasConstraint(ConstraintEnumeration) {
+		parts = append(parts, fmt.Sprintf("enum=%d", len(nc.EnumerationValues)))
+	}
+	if nc.HasConstraint(ConstraintMonotonicity) {
+		parts = append(parts, fmt.Sprintf("monotonicity=%d", nc.Monotonicity))
+	}
+	if nc.HasConstraint(ConstraintSign) {
+		parts = append(parts, fmt.Sprintf("sign=+%t/-%t", nc.AllowPositive, nc.AllowNegative))
+	}
+	if nc.HasConstraint(ConstraintDiscrete) {
+		parts = append(parts, fmt.Sprintf("step=%g", nc.DiscreteStep))
+	}
+	if nc.HasConstraint(ConstraintSparse) {
+		parts = append(parts, fmt.Sprintf("sparse=%.2f", nc.ZeroRatio))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
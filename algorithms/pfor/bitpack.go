@@ -0,0 +1,56 @@
+package pfor
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// packedWords n 个 width 位的值占用的 64 位字数
+func packedWords(n, width int) int {
+	return (n*width + 63) / 64
+}
+
+// appendPacked 把每个值的低 width 位连续写入 64 位字（小端），width 为 0 时不输出
+func appendPacked(dst []byte, src []uint64, width int) []byte {
+	if width == 0 {
+		return dst
+	}
+	words := make([]uint64, packedWords(len(src), width)+1)
+	mask := ^uint64(0) >> (64 - width)
+	for i, v := range src {
+		pos := i * width
+		k, s := pos/64, uint(pos%64)
+		v &= mask
+		words[k] |= v << s
+		// s 为 0 时移位 64 得到 0，不需要分支
+		words[k+1] |= v >> 1 >> (63 - s)
+	}
+	for _, w := range words[:len(words)-1] {
+		dst = binary.LittleEndian.AppendUint64(dst, w)
+	}
+	return dst
+}
+
+// readPacked 还原 appendPacked，返回剩余的输入。所有位宽共用同一段无分支的解包循环
+func readPacked(dst []uint64, src []byte, width int) ([]byte, error) {
+	n := packedWords(len(dst), width)
+	if len(src) < 8*n {
+		return nil, fmt.Errorf("pfor: packed data is truncated")
+	}
+	if width == 0 {
+		clear(dst)
+		return src, nil
+	}
+	// 多留一个 0 字，跨字读取时不用判断边界
+	words := make([]uint64, n+1)
+	for i := range words[:n] {
+		words[i] = binary.LittleEndian.Uint64(src[8*i:])
+	}
+	mask := ^uint64(0) >> (64 - width)
+	for i := range dst {
+		pos := i * width
+		k, s := pos/64, uint(pos%64)
+		dst[i] = (words[k]>>s | words[k+1]<<1<<(63-s)) & mask
+	}
+	return src[8*n:], nil
+}
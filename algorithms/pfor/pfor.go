@@ -0,0 +1,191 @@
+// Package pfor 实现 FastLanes 风格的 patched frame-of-reference 整数压缩。
+//
+// 数据按 1024 个值一个块编码。每块在 FOR（减去最小值）和 Delta（与前一个值的 zigzag 差）之间选择较小的一种，
+// 再选一个位宽 b 打包低 b 位；超出 b 位的值是异常，只在末尾补写下标和高位。
+package pfor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+const blockSize = 1024
+
+// 块编码方式
+const (
+	modeFOR   byte = 0
+	modeDelta byte = 1
+)
+
+// 每个异常下标的位数
+const exceptionPosBits = 16
+
+// Compress 格式：uvarint 值个数，之后依次是每个块
+func Compress(dst []byte, src []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	prev := uint64(0)
+	residual := make([]uint64, blockSize)
+	for i := 0; i < len(src); i += blockSize {
+		block := src[i:min(i+blockSize, len(src))]
+		residual = residual[:len(block)]
+		dst = appendBlock(dst, block, prev, residual)
+		prev = block[len(block)-1]
+	}
+	return dst
+}
+
+// appendBlock 格式：方式和位宽各 1 字节，uvarint 基准值，按 64 位字打包的低位，
+// uvarint 异常个数，有异常时再写异常高位的位宽、16 位下标和打包的高位
+func appendBlock(dst []byte, block []uint64, prev uint64, residual []uint64) []byte {
+	base := block[0]
+	for _, v := range block {
+		base = min(base, v)
+	}
+	for i, v := range block {
+		residual[i] = v - base
+	}
+	mode := modeFOR
+	width, cost := chooseWidth(residual)
+
+	// Delta 方式：zigzag 后的差值再减去最小值
+	deltas := make([]uint64, len(block))
+	for i, v := range block {
+		deltas[i] = zigzag(v - prev)
+		prev = v
+	}
+	deltaBase := deltas[0]
+	for _, d := range deltas {
+		deltaBase = min(deltaBase, d)
+	}
+	for i := range deltas {
+		deltas[i] -= deltaBase
+	}
+	if w, c := chooseWidth(deltas); c < cost {
+		mode, width, base = modeDelta, w, deltaBase
+		residual = deltas
+	}
+
+	dst = append(dst, mode, byte(width))
+	dst = binary.AppendUvarint(dst, base)
+	dst = appendPacked(dst, residual, width)
+
+	var excPos []uint64
+	var excHigh []uint64
+	excWidth := 0
+	for i, r := range residual {
+		if width < 64 && r>>width != 0 {
+			excPos = append(excPos, uint64(i))
+			excHigh = append(excHigh, r>>width)
+			excWidth = max(excWidth, bits.Len64(r>>width))
+		}
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(excPos)))
+	if len(excPos) > 0 {
+		dst = append(dst, byte(excWidth))
+		dst = appendPacked(dst, excPos, exceptionPosBits)
+		dst = appendPacked(dst, excHigh, excWidth)
+	}
+	return dst
+}
+
+// chooseWidth 按位长直方图选择代价最小的打包位宽，代价包含异常的下标和高位
+func chooseWidth(residual []uint64) (int, int) {
+	var hist [65]int
+	for _, r := range residual {
+		hist[bits.Len64(r)]++
+	}
+	maxLen := 64
+	for maxLen > 0 && hist[maxLen] == 0 {
+		maxLen--
+	}
+	bestWidth, bestCost := maxLen, len(residual)*maxLen
+	exceptions := 0
+	for b := maxLen - 1; b >= 0; b-- {
+		exceptions += hist[b+1]
+		cost := len(residual)*b + exceptions*(exceptionPosBits+maxLen-b) + 8
+		if cost < bestCost {
+			bestWidth, bestCost = b, cost
+		}
+	}
+	return bestWidth, bestCost
+}
+
+func zigzag(d uint64) uint64   { return d<<1 ^ uint64(int64(d)>>63) }
+func unzigzag(u uint64) uint64 { return u>>1 ^ -(u & 1) }
+
+// Decompress 还原 Compress
+func Decompress(dst []uint64, src []byte) ([]uint64, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 {
+		return nil, fmt.Errorf("pfor: invalid header")
+	}
+	src = src[m:]
+	prev := uint64(0)
+	block := make([]uint64, blockSize)
+	var err error
+	for remaining := n; remaining > 0; remaining -= uint64(len(block)) {
+		block = block[:min(remaining, blockSize)]
+		if src, err = readBlock(block, src, prev); err != nil {
+			return nil, err
+		}
+		prev = block[len(block)-1]
+		dst = append(dst, block...)
+	}
+	return dst, nil
+}
+
+func readBlock(block []uint64, src []byte, prev uint64) ([]byte, error) {
+	if len(src) < 2 {
+		return nil, fmt.Errorf("pfor: block header is truncated")
+	}
+	mode, width := src[0], int(src[1])
+	if mode > modeDelta || width > 64 {
+		return nil, fmt.Errorf("pfor: invalid block header")
+	}
+	base, m := binary.Uvarint(src[2:])
+	if m <= 0 {
+		return nil, fmt.Errorf("pfor: invalid block base")
+	}
+	src, err := readPacked(block, src[2+m:], width)
+	if err != nil {
+		return nil, err
+	}
+
+	count, m := binary.Uvarint(src)
+	if m <= 0 || count > uint64(len(block)) {
+		return nil, fmt.Errorf("pfor: invalid exception count")
+	}
+	src = src[m:]
+	if count > 0 {
+		if len(src) < 1 || int(src[0]) > 64-width {
+			return nil, fmt.Errorf("pfor: invalid exception width")
+		}
+		excWidth := int(src[0])
+		pos := make([]uint64, count)
+		high := make([]uint64, count)
+		if src, err = readPacked(pos, src[1:], exceptionPosBits); err != nil {
+			return nil, err
+		}
+		if src, err = readPacked(high, src, excWidth); err != nil {
+			return nil, err
+		}
+		for i, p := range pos {
+			if p >= uint64(len(block)) {
+				return nil, fmt.Errorf("pfor: exception position %d out of range", p)
+			}
+			block[p] |= high[i] << width
+		}
+	}
+
+	for i, r := range block {
+		block[i] = r + base
+	}
+	if mode == modeDelta {
+		for i, d := range block {
+			prev += unzigzag(d)
+			block[i] = prev
+		}
+	}
+	return src, nil
+}
@@ -0,0 +1,99 @@
+package pfor
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// blockHeader 返回第一个块的编码方式和位宽
+func blockHeader(t *testing.T, out []byte) (byte, int) {
+	t.Helper()
+	_, m := binary.Uvarint(out)
+	if len(out) < m+2 {
+		t.Fatalf("%d bytes, no block header", len(out))
+	}
+	return out[m], int(out[m+1])
+}
+
+func TestCompress(t *testing.T) {
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, Compress, Decompress, c.Data)
+	}
+}
+
+// 随机位模式需要完整的 64 位，没有异常
+func TestWidth64(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]uint64, blockSize)
+	for i := range data {
+		data[i] = rng.Uint64()
+	}
+	data[0], data[1] = 0, math.MaxUint64
+	out := codectest.RoundTripUint64s(t, "random", Compress, Decompress, data)
+	if _, width := blockHeader(t, out); width != 64 {
+		t.Errorf("width %d, expected 64", width)
+	}
+}
+
+// 异常位于块首尾、高位占满剩余位宽（MaxUint64）或只多出 1 位
+func TestExceptions(t *testing.T) {
+	data := make([]uint64, blockSize)
+	for i := range data {
+		data[i] = uint64(i % 8)
+	}
+	data[0] = math.MaxUint64
+	data[blockSize-1] = math.MaxUint64 - 1
+	data[100] = 8
+	data[200] = 15
+	out := codectest.RoundTripUint64s(t, "exceptions", Compress, Decompress, data)
+	mode, width := blockHeader(t, out)
+	if mode != modeFOR || width != 3 {
+		t.Errorf("mode %d width %d, expected FOR with 3 bits", mode, width)
+	}
+	// 异常高位的位宽在值个数、块头部（基准值 0 占 1 字节）、打包的低位和异常个数之后，解压时不能超过 64-width
+	pos := len(binary.AppendUvarint(nil, uint64(len(data)))) + 3 + packedWords(blockSize, width)*8 + 1
+	if out[pos] != byte(64-width) {
+		t.Fatalf("exception width %d, expected %d", out[pos], 64-width)
+	}
+	corrupt := append([]byte(nil), out...)
+	corrupt[pos]++
+	if _, err := Decompress(nil, corrupt); err == nil {
+		t.Error("expected an error for an exception width over 64 bits")
+	}
+}
+
+// Delta 方式：上一个块的最后一个值延续到下一个块，差值跨过 0 时按 zigzag 编码
+func TestDelta(t *testing.T) {
+	for _, n := range []int{blockSize - 1, blockSize, blockSize + 1, 3 * blockSize} {
+		data := make([]uint64, n)
+		for i := range data {
+			data[i] = math.MaxUint64 - 500 + uint64(i)*3 // 从第 167 个值开始回绕
+		}
+		out := codectest.RoundTripUint64s(t, "wrapping counter", Compress, Decompress, data)
+		if mode, width := blockHeader(t, out); mode != modeDelta || width != 0 {
+			t.Errorf("%d values: mode %d width %d, expected Delta with 0 bits", n, mode, width)
+		}
+	}
+}
+
+// 异常只补写高位，小值块中的少量大值不应让整块按 64 位打包
+func TestCompressOutliers(t *testing.T) {
+	data := make([]uint64, blockSize)
+	for i := range data {
+		data[i] = uint64(i % 16)
+	}
+	data[10], data[500] = math.MaxUint64, 1<<40
+	if out := Compress(nil, data); len(out) > blockSize {
+		t.Errorf("%d values with 2 outliers compressed into %d bytes", len(data), len(out))
+	}
+}
+
+func TestDecompressAppends(t *testing.T) {
+	got, err := Decompress([]uint64{7}, Compress(nil, []uint64{1, 2, 3}))
+	if err != nil || len(got) != 4 || got[0] != 7 || got[3] != 3 {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
@@ -1,75 +1,144 @@
+// Package pongo 把浮点数尾数中表示小数部分的位替换为十进制小数的整数表示（位反转后从高位写起），
+// 十进制小数位数少的数据擦除后尾部全是 0，再交给 XOR 类算法压缩
 package pongo
 
 import (
 	"fmt"
 	"math"
+	"math/bits"
 	"strconv"
 	"strings"
 )
 
-// reverseBits 对 uint64 进行位反转
-func reverseBits(n uint64) uint64 {
-	var result uint64
-	for i := 0; i < 64; i++ {
-		result = (result << 1) | (n & 1)
-		n >>= 1
-	}
-	return result
-}
-
-// Eraser 将浮点数的小数部分用整数二进制表示替换 IEEE754 尾数
-// 根据指数计算哪些位是整数部分，哪些位是小数部分
-// exp = exponent - 1023, 前 12+exp 位保留(符号+指数+整数尾数)，后面替换为小数的整数表示
-func Eraser(value float64) float64 {
-	// 获取原始二进制
-	originalBits := math.Float64bits(value)
-
-	// 提取指数部分 (11位)
-	exponentBits := (originalBits >> 52) & 0x7FF
-	exp := int(exponentBits) - 1023 // 实际指数
-
-	// 分离整数和小数部分
-	intPart := math.Floor(math.Abs(value))
-	fracPart := math.Abs(value) - intPart
+// maxDecimals 小数部分作为 uint64 最多的十进制位数
+const maxDecimals = 19
 
-	// 将小数部分转换为整数
-	// 例如：0.18 -> "18" -> 整数 18
-	// 使用字符串方式提取小数部分，避免浮点精度问题
-	fracInt := uint64(0)
-
-	// 将浮点数转换为字符串，提取小数点后的数字
-	str := strconv.FormatFloat(fracPart, 'f', -1, 64)
-	parts := strings.Split(str, ".")
-	if len(parts) == 2 {
-		// 将小数部分字符串转换为整数
-		fracInt, _ = strconv.ParseUint(parts[1], 10, 64)
+var pow10 = func() (p [maxDecimals + 1]uint64) {
+	p[0] = 1
+	for i := 1; i <= maxDecimals; i++ {
+		p[i] = p[i-1] * 10
 	}
+	return
+}()
 
-	// 计算需要保留和替换的位数
-	// 前 12+exp 位：1位符号 + 11位指数 + exp位整数尾数
-	// 后 52-exp 位：小数部分尾数，用 fracInt 替换
-	preserveBits := 12 + exp // 符号位 + 指数位 + 整数部分尾数位
-	replaceBits := 52 - exp  // 小数部分尾数位
-
-	// 对 fracInt 进行位反转
-	// 例如：18 = 0b10010 -> reverse -> 0b01001000...
-	reversedFrac := reverseBits(fracInt)
-
-	// 保留前 preserveBits 位，后面用 reversedFrac 右移填充
-	preserveMask := (uint64(0xFFFFFFFFFFFFFFFF) << uint(64-preserveBits))
-	preservedPart := originalBits & preserveMask
+// fraction 返回 |v| 最短十进制表示的小数部分及其位数，例如 0.05 返回 5 和 2，小数超过 maxDecimals 位时 ok 为 false
+func fraction(v float64) (frac uint64, digits int, ok bool) {
+	s := strconv.FormatFloat(math.Abs(v), 'f', -1, 64)
+	_, fs, found := strings.Cut(s, ".")
+	if !found {
+		return 0, 0, true
+	}
+	if len(fs) > maxDecimals {
+		return 0, 0, false
+	}
+	frac, err := strconv.ParseUint(fs, 10, 64)
+	return frac, len(fs), err == nil
+}
 
-	// 将 reversedFrac 右移 12+exp 位
-	replacedPart := reversedFrac >> uint(preserveBits)
+// layout 返回保留的高位数（符号、指数和整数部分的尾数），指数 >= 52 的值没有小数位，和 0、非规格化数、Inf/NaN 一样不能擦除
+func layout(u uint64) (keep int, ok bool) {
+	e := int(u >> 52 & 0x7ff)
+	if e == 0 || e-1023 >= 52 {
+		return 0, false
+	}
+	return 12 + max(e-1023, 0), true
+}
 
-	erasedBits := preservedPart | replacedPart
+// Erase 按 decimals 位小数把 v 的小数部分尾数替换为小数的整数表示，绝对值小于 1 时整个尾数都被替换。
+// 小数位数超过 decimals、整数表示放不下或无法精确还原时返回原位模式和 false
+func Erase(v float64, decimals int) (uint64, bool) {
+	u := math.Float64bits(v)
+	keep, ok := layout(u)
+	if !ok || decimals < 0 || decimals > maxDecimals {
+		return u, false
+	}
+	frac, digits, ok := fraction(v)
+	if !ok || digits > decimals {
+		return u, false
+	}
+	hi, scaled := bits.Mul64(frac, pow10[decimals-digits])
+	if hi != 0 || scaled>>(64-keep) != 0 {
+		return u, false
+	}
+	erased := u&^(1<<(64-keep)-1) | bits.Reverse64(scaled)>>keep
+	if r, err := Restore(erased, decimals); err != nil || math.Float64bits(r) != u {
+		return u, false
+	}
+	return erased, true
+}
 
-	// 打印调试信息
-	fmt.Printf("原始值: %.2f, exp=%d, 保留前%d位, 替换后%d位\n", value, exp, preserveBits, replaceBits)
-	fmt.Printf("%064b:%f\n", originalBits, value)
-	fmt.Printf("%064b:%f\n", erasedBits, math.Float64frombits(erasedBits))
-	fmt.Printf("小数整数:   %d (二进制: %b)\n", fracInt, fracInt)
-	fmt.Println()
+// Restore 还原 Erase：整数部分取自保留的尾数位，小数部分按 decimals 位补零后与整数部分一起按十进制解析
+func Restore(erased uint64, decimals int) (float64, error) {
+	keep, ok := layout(erased)
+	if !ok || decimals < 0 || decimals > maxDecimals {
+		return 0, fmt.Errorf("pongo: invalid erased value %#x with %d decimals", erased, decimals)
+	}
+	frac := bits.Reverse64(erased << keep)
+	if frac >= pow10[decimals] {
+		return 0, fmt.Errorf("pongo: fraction %d exceeds %d decimals", frac, decimals)
+	}
+	// 绝对值小于 1 时整数部分为 0
+	var integer uint64
+	if erased>>52&0x7ff >= 1023 {
+		integer = (1<<52 | erased&(1<<52-1)) >> (52 - (keep - 12))
+	}
+	s := strconv.AppendUint(make([]byte, 0, 48), integer, 10)
+	if decimals > 0 {
+		s = append(s, '.')
+		fs := strconv.AppendUint(nil, frac, 10)
+		for i := len(fs); i < decimals; i++ {
+			s = append(s, '0')
+		}
+		s = append(s, fs...)
+	}
+	v, err := strconv.ParseFloat(string(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if erased>>63 == 1 {
+		v = -v
+	}
+	return v, nil
+}
 
-	return math.Float64frombits(erasedBits)
+// ChooseDecimals 选择能擦除最多值的小数位数，相同时取较小的
+func ChooseDecimals(src []float64) int {
+	// candidate 可擦除的值的小数部分、小数位数和可替换的位数，按更多位数补零后整数表示可能放不下
+	type candidate struct {
+		frac   uint64
+		digits int
+		room   int
+	}
+	var cands []candidate
+	var seen [maxDecimals + 1]bool
+	for _, v := range src {
+		u := math.Float64bits(v)
+		keep, ok := layout(u)
+		if !ok {
+			continue
+		}
+		if frac, digits, ok := fraction(v); ok {
+			cands = append(cands, candidate{frac, digits, 64 - keep})
+			seen[digits] = true
+		}
+	}
+	best, bestCount := 0, -1
+	for d := 0; d <= maxDecimals; d++ {
+		if !seen[d] {
+			continue
+		}
+		count := 0
+		for _, c := range cands {
+			if c.digits > d {
+				continue
+			}
+			if hi, scaled := bits.Mul64(c.frac, pow10[d-c.digits]); hi == 0 && scaled>>c.room == 0 {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = d, count
+		}
+	}
+	return best
 }
@@ -0,0 +1,54 @@
+package pongo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEraseRestore(t *testing.T) {
+	for _, v := range []float64{20.18, -3.75, 0.05, 1.5, 123.456, 0.001} {
+		u, ok := Erase(v, 3)
+		if !ok {
+			t.Errorf("Erase(%v, 3) not erased", v)
+			continue
+		}
+		if r, err := Restore(u, 3); err != nil || r != v {
+			t.Errorf("Restore(Erase(%v)) = %v, %v", v, r, err)
+		}
+	}
+	// 小数位数超过 3、没有小数位、不是有限的非零规格化数
+	for _, v := range []float64{1e-10, 0.1234, 1e300, 0, math.NaN(), math.Inf(1), 5e-324} {
+		if u, ok := Erase(v, 3); ok || u != math.Float64bits(v) {
+			t.Errorf("Erase(%v, 3) = %#x, %v, expected unchanged", v, u, ok)
+		}
+	}
+}
+
+func TestCompressFloat(t *testing.T) {
+	// 两位小数的数据，混入不能擦除的特殊值
+	data := make([]float64, 1000)
+	for i := range data {
+		data[i] = math.Round((20+math.Sin(float64(i)/10))*100) / 100
+	}
+	data[10], data[20], data[30] = math.NaN(), math.Inf(-1), math.Copysign(0, -1)
+	data[40], data[50], data[60] = 1e300, -5e-324, 0.123456789012345678
+
+	out := CompressFloat(nil, data)
+	got, err := DecompressFloat(nil, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(data) {
+		t.Fatalf("got %d values, expected %d", len(got), len(data))
+	}
+	for i := range data {
+		if math.Float64bits(got[i]) != math.Float64bits(data[i]) {
+			t.Errorf("value %d: got %v, expected %v", i, got[i], data[i])
+		}
+	}
+	// 解压不能改写输入
+	if again, err := DecompressFloat(nil, out); err != nil || len(again) != len(data) {
+		t.Fatalf("second decompression: %d values, %v", len(again), err)
+	}
+	t.Logf("compressed %d values into %d bytes", len(data), len(out))
+}
@@ -0,0 +1,162 @@
+package predict
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"myalgo/common"
+	"strings"
+)
+
+// Codec 一个预测器和一种残差的组合
+type Codec struct {
+	Name         string
+	NewPredictor func() Predictor
+	Residual     Residual
+}
+
+// Parse 解析 "预测器+残差" 形式的组合名
+func Parse(name string) (Codec, error) {
+	p, r, ok := strings.Cut(name, "+")
+	if !ok {
+		return Codec{}, fmt.Errorf("predict: invalid codec name %q", name)
+	}
+	c := Codec{Name: name}
+	for _, e := range predictorTable {
+		if e.Name == p {
+			c.NewPredictor = e.New
+		}
+	}
+	for _, e := range residualTable {
+		if e.Name == r {
+			c.Residual = e.Residual
+		}
+	}
+	if c.NewPredictor == nil || c.Residual == nil {
+		return Codec{}, fmt.Errorf("predict: unknown codec %q", name)
+	}
+	return c, nil
+}
+
+// MustParse 同 Parse，名称无效时 panic
+func MustParse(name string) Codec {
+	c, err := Parse(name)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Codecs 所有预测器和残差的组合
+func Codecs() []Codec {
+	var cs []Codec
+	for _, p := range predictorTable {
+		for _, r := range residualTable {
+			cs = append(cs, Codec{p.Name + "+" + r.Name, p.New, r.Residual})
+		}
+	}
+	return cs
+}
+
+// Compress 格式：uvarint 值个数，之后是残差位流。每个残差：
+// 0 表示残差为 0；10 表示沿用上一个窗口，只写窗口内的位；11 后接 6 位前导零个数、6 位有效位长度减 1 和有效位
+func (c Codec) Compress(dst []byte, src []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	bs := &common.ByteWrapper{Stream: &dst, Count: 0}
+	p := c.NewPredictor()
+	prevLeading, prevTrailing := -1, 0
+	for _, v := range src {
+		r := c.Residual.Residual(v, p.PredictNext())
+		p.Update(v)
+		if r == 0 {
+			bs.AppendBit(common.Zero)
+			continue
+		}
+		bs.AppendBit(common.One)
+		leading, trailing := bits.LeadingZeros64(r), bits.TrailingZeros64(r)
+		if prevLeading >= 0 && leading >= prevLeading && trailing >= prevTrailing {
+			bs.AppendBit(common.Zero)
+			bs.AppendBits(r>>prevTrailing, 64-prevLeading-prevTrailing)
+			continue
+		}
+		prevLeading, prevTrailing = leading, trailing
+		sig := 64 - leading - trailing
+		bs.AppendBit(common.One)
+		bs.AppendBits(uint64(leading), 6)
+		bs.AppendBits(uint64(sig-1), 6)
+		bs.AppendBits(r>>trailing, sig)
+	}
+	return dst
+}
+
+// Decompress 还原 Compress，结果追加到 dst
+func (c Codec) Decompress(dst []uint64, src []byte) ([]uint64, error) {
+	n, k := binary.Uvarint(src)
+	// 每个值至少占 1 位
+	if k <= 0 || n > uint64(len(src)-k)*8 {
+		return nil, fmt.Errorf("predict: invalid header")
+	}
+	// ByteWrapper 读取时会改写输入
+	stream := append([]byte(nil), src[k:]...)
+	bs := &common.ByteWrapper{Stream: &stream, Count: 8}
+	p := c.NewPredictor()
+	leading, trailing := -1, 0
+	for i := uint64(0); i < n; i++ {
+		pred := p.PredictNext()
+		r := uint64(0)
+		nonZero, err := bs.ReadBit()
+		if err != nil {
+			return nil, err
+		}
+		if nonZero {
+			newWindow, err := bs.ReadBit()
+			if err != nil {
+				return nil, err
+			}
+			if newWindow {
+				l, err := bs.ReadBits(6)
+				if err != nil {
+					return nil, err
+				}
+				sig, err := bs.ReadBits(6)
+				if err != nil {
+					return nil, err
+				}
+				if l+sig+1 > 64 {
+					return nil, fmt.Errorf("predict: invalid window at value %d", i)
+				}
+				leading, trailing = int(l), 64-int(l)-int(sig+1)
+			} else if leading < 0 {
+				return nil, fmt.Errorf("predict: window reused before defined at value %d", i)
+			}
+			if r, err = bs.ReadBits(64 - leading - trailing); err != nil {
+				return nil, err
+			}
+			r <<= trailing
+		}
+		v := c.Residual.Restore(r, pred)
+		p.Update(v)
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+func (c Codec) CompressFloat(dst []byte, src []float64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
+	}
+	return c.Compress(dst, values)
+}
+
+func (c Codec) DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	values, err := c.Decompress(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range values {
+		dst = append(dst, math.Float64frombits(u))
+	}
+	return dst, nil
+}
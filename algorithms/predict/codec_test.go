@@ -0,0 +1,133 @@
+package predict
+
+import (
+	"math"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// seasonalData 周期为 24 的数据
+func seasonalData(n int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = float64(i%24) * 1.5
+	}
+	return data
+}
+
+// 每个预测器和残差的组合都能按位还原
+func TestCodecs(t *testing.T) {
+	cases := append(codectest.Floats(), codectest.FloatCase{Name: "seasonal", Data: seasonalData(1000)})
+	for _, c := range Codecs() {
+		for _, tc := range cases {
+			codectest.RoundTripFloats(t, c.Name+"/"+tc.Name, c.CompressFloat, c.DecompressFloat, tc.Data)
+		}
+	}
+}
+
+// 每个预测器在它所针对的数据上预热之后预测准确，残差全为 0，每个值只占 1 位
+func TestExactPredictions(t *testing.T) {
+	constant := make([]float64, 1000)
+	ramp := make([]float64, 1000)
+	pattern := make([]float64, 1000)
+	for i := range constant {
+		constant[i] = 2.5
+		ramp[i] = float64(3 * i)
+		pattern[i] = []float64{1.25, -7, 1e10, 0.1, 3}[i%5]
+	}
+	cases := []struct {
+		predictor string
+		data      []float64
+		warmup    int // 预测可能出错的值个数
+	}{
+		{"previous", constant, 1},
+		{"linear", ramp, 2},
+		{"fcm", pattern, 20},
+		{"dfcm", pattern, 20},
+		{"seasonal24", seasonalData(1000), 24},
+		{"avg4", constant, 4},
+	}
+	if len(cases) != len(predictorTable) {
+		t.Fatalf("%d cases for %d predictors", len(cases), len(predictorTable))
+	}
+	for _, c := range cases {
+		p := MustParse(c.predictor + "+xor").NewPredictor()
+		for i, v := range c.data {
+			u := math.Float64bits(v)
+			if pred := p.PredictNext(); i >= c.warmup && pred != u {
+				t.Fatalf("%s: value %d: predicted %v, expected %v", c.predictor, i, math.Float64frombits(pred), v)
+			}
+			p.Update(u)
+		}
+	}
+}
+
+// 滑动平均在 Inf/NaN 移出窗口后恢复，不会一直预测 NaN
+func TestMovingAverageRecovers(t *testing.T) {
+	for _, special := range []float64{math.Inf(1), math.NaN(), math.MaxFloat64} {
+		p := newMovingAverage(4)
+		for _, v := range []float64{1, special, math.Inf(-1), 1, 1, 1, 1} {
+			p.Update(math.Float64bits(v))
+		}
+		if got := math.Float64frombits(p.PredictNext()); got != 1 {
+			t.Errorf("after %v: predicted %v, expected 1", special, got)
+		}
+	}
+}
+
+// 整数相减的残差在差值回绕时也能还原，差为 ±1 时 zigzag 后只有 1~2 位
+func TestSubResidual(t *testing.T) {
+	pairs := [][2]uint64{
+		{0, math.MaxUint64}, {math.MaxUint64, 0}, {1 << 63, 0}, {0, 1 << 63},
+		{math.Float64bits(1), math.Float64bits(math.Nextafter(1, 2))},
+		{math.Float64bits(1), math.Float64bits(math.Nextafter(1, 0))},
+	}
+	var r subResidual
+	for _, p := range pairs {
+		v, pred := p[0], p[1]
+		if got := r.Restore(r.Residual(v, pred), pred); got != v {
+			t.Errorf("Restore(Residual(%#x, %#x)) = %#x", v, pred, got)
+		}
+	}
+	if got := r.Residual(math.Float64bits(1), math.Float64bits(math.Nextafter(1, 2))); got != 1 {
+		t.Errorf("residual of the next float below %d, expected 1", got)
+	}
+	if got := r.Residual(math.Float64bits(math.Nextafter(1, 2)), math.Float64bits(1)); got != 2 {
+		t.Errorf("residual of the next float above %d, expected 2", got)
+	}
+}
+
+// 第一个残差沿用不存在的窗口，或窗口超出 64 位时返回错误
+func TestDecompressInvalidWindow(t *testing.T) {
+	c := MustParse("previous+xor")
+	for _, src := range [][]byte{
+		{1, 0b10000000},                         // 10：沿用窗口
+		{1, 0b11111111, 0b00000100, 0b00000000}, // 11，前导零 63，有效位 2
+	} {
+		if _, err := c.Decompress(nil, src); err == nil {
+			t.Errorf("%08b: expected an error", src[1:])
+		}
+	}
+}
+
+// 周期数据上季节预测器的残差几乎全为 0
+func TestSeasonal(t *testing.T) {
+	data := seasonalData(1000)
+	seasonal := MustParse("seasonal24+xor").CompressFloat(nil, data)
+	previous := MustParse("previous+xor").CompressFloat(nil, data)
+	if len(seasonal) >= len(previous) {
+		t.Errorf("seasonal24+xor %d bytes, previous+xor %d bytes", len(seasonal), len(previous))
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range []string{"previous", "previous+", "unknown+xor", "fcm+div"} {
+		if _, err := Parse(name); err == nil {
+			t.Errorf("Parse(%q): expected an error", name)
+		}
+	}
+	c, err := Parse("dfcm+sub")
+	if err != nil || c.Name != "dfcm+sub" {
+		t.Errorf("Parse(\"dfcm+sub\") = %v, %v", c.Name, err)
+	}
+}
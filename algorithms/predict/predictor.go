@@ -0,0 +1,80 @@
+// Package predict 把预测器和残差组合成压缩算法：预测器给出下一个值的预测，残差把实际值和预测值变成前导、尾部零较多的差，
+// 再由共用的残差编码器按前导零/有效位窗口写出。组合名形如 "fcm+xor"、"linear+sub"。
+package predict
+
+import (
+	"math"
+	"myalgo/algorithms/fpc"
+)
+
+// Predictor 根据已编码的值预测下一个值的位模式，压缩和解压两端按相同顺序调用，fpc 的 FCM/DFCM 预测器直接满足该接口
+type Predictor interface {
+	PredictNext() uint64
+	Update(v uint64)
+}
+
+// previous 预测为上一个值，与 Gorilla 相同
+type previous struct{ last uint64 }
+
+func (p *previous) PredictNext() uint64 { return p.last }
+func (p *previous) Update(v uint64)     { p.last = v }
+
+// linear 按最近两个值做线性外推 2a-b，在 float64 上计算
+type linear struct{ a, b float64 }
+
+func (p *linear) PredictNext() uint64 { return math.Float64bits(2*p.a - p.b) }
+func (p *linear) Update(v uint64)     { p.a, p.b = math.Float64frombits(v), p.a }
+
+// seasonal 预测为 lag 个值之前的值
+type seasonal struct {
+	history []uint64
+	pos     int
+}
+
+func newSeasonal(lag int) *seasonal { return &seasonal{history: make([]uint64, lag)} }
+
+func (p *seasonal) PredictNext() uint64 { return p.history[p.pos] }
+func (p *seasonal) Update(v uint64) {
+	p.history[p.pos] = v
+	p.pos = (p.pos + 1) % len(p.history)
+}
+
+// movingAverage 最近 window 个值的平均，在 float64 上计算
+type movingAverage struct {
+	window []float64
+	pos    int
+	sum    float64
+}
+
+func newMovingAverage(n int) *movingAverage { return &movingAverage{window: make([]float64, n)} }
+
+func (p *movingAverage) PredictNext() uint64 {
+	return math.Float64bits(p.sum / float64(len(p.window)))
+}
+
+func (p *movingAverage) Update(v uint64) {
+	f := math.Float64frombits(v)
+	p.sum += f - p.window[p.pos]
+	p.window[p.pos] = f
+	p.pos = (p.pos + 1) % len(p.window)
+	// 遇到 Inf/NaN 后累加和无法再恢复，重新求和
+	if math.IsNaN(p.sum) || math.IsInf(p.sum, 0) {
+		p.sum = 0
+		for _, w := range p.window {
+			p.sum += w
+		}
+	}
+}
+
+// predictorTable 名称和构造函数，每次压缩或解压都新建一个预测器
+var predictorTable = []struct {
+	Name string
+	New  func() Predictor
+}{
+	{"previous", func() Predictor { return &previous{} }},
+	{"linear", func() Predictor { return &linear{} }},
+	{"fcm", func() Predictor { return fpc.NewFcmPredictor(1 << 16) }},
+	{"dfcm", func() Predictor { return fpc.NewDfcmPredictor(1 << 16) }},
+	{"seasonal24", func() Predictor { return newSeasonal(24) }},
+	{"avg4", func() Predictor { return newMovingAverage(4) }},
+}
@@ -0,0 +1,33 @@
+package predict
+
+// Residual 实际值与预测值之间可逆的差
+type Residual interface {
+	Residual(v, pred uint64) uint64
+	Restore(r, pred uint64) uint64
+}
+
+// xorResidual 按位异或，相同的高位和低位都变成 0
+type xorResidual struct{}
+
+func (xorResidual) Residual(v, pred uint64) uint64 { return v ^ pred }
+func (xorResidual) Restore(r, pred uint64) uint64  { return r ^ pred }
+
+// subResidual 位模式作为整数相减后 zigzag，预测接近时差的绝对值小、前导零多
+type subResidual struct{}
+
+func (subResidual) Residual(v, pred uint64) uint64 {
+	d := v - pred
+	return d<<1 ^ uint64(int64(d)>>63)
+}
+
+func (subResidual) Restore(r, pred uint64) uint64 {
+	return pred + (r>>1 ^ -(r & 1))
+}
+
+var residualTable = []struct {
+	Name     string
+	Residual Residual
+}{
+	{"xor", xorResidual{}},
+	{"sub", subResidual{}},
+}
@@ -0,0 +1,415 @@
+package rangeCoding
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 自适应上下文建模的范围编码：概率在编码过程中更新，流中不保存频率表。
+// 编码引擎与 LZMA 相同，32 位 range 按字节规范化，进位通过 cache 传播。
+
+const (
+	rcTopValue = 1 << 24
+
+	// 二进制模式：11 位概率，每次向观测值移动 1/32
+	probBits  = 11
+	probInit  = 1 << (probBits - 1)
+	probShift = 5
+
+	// 符号模式：每次加 freqIncrement，总数超过 freqLimit 时减半
+	freqIncrement = 32
+	freqLimit     = 1 << 16
+)
+
+// ContextFunc 根据字节在流中的位置返回附加上下文，取值范围 [0, AdaptiveOptions.Contexts)
+type ContextFunc func(i int) int
+
+// BytePosition 以字节在 8 字节值中的位置作为上下文，适合小端序列化的 float64/uint64
+func BytePosition(i int) int { return i % 8 }
+
+// AdaptiveOptions 自适应模型的配置。Order 和 Binary 写入流中，Context 需在解压时提供相同的函数
+type AdaptiveOptions struct {
+	Order    int  // 以前 Order 个字节作为上下文，0~2
+	Binary   bool // true 时按位编码（每个字节 8 个二元决策），false 时直接编码 256 个符号
+	Context  ContextFunc
+	Contexts int // Context 的取值个数，Context 为 nil 时忽略
+}
+
+// FloatOptions float64 数据的默认配置：order-1 加字节位置上下文的二进制模型
+var FloatOptions = AdaptiveOptions{Order: 1, Binary: true, Context: BytePosition, Contexts: 8}
+
+func (o AdaptiveOptions) contexts() int {
+	if o.Context == nil {
+		return 1
+	}
+	return max(o.Contexts, 1)
+}
+
+// context 第 i 个字节的上下文编号，Context 的返回值超出 [0, Contexts) 时返回错误
+func (o AdaptiveOptions) context(i int, data []byte) (int, error) {
+	ctx := 0
+	for k := 1; k <= o.Order; k++ {
+		ctx <<= 8
+		if i >= k {
+			ctx |= int(data[i-k])
+		}
+	}
+	if o.Context != nil {
+		c := o.Context(i)
+		if c < 0 || c >= o.contexts() {
+			return 0, fmt.Errorf("rangeCoding: context %d of byte %d is out of range [0, %d)", c, i, o.contexts())
+		}
+		ctx = ctx*o.contexts() + c
+	}
+	return ctx, nil
+}
+
+// adaptiveEncoder LZMA 风格的范围编码器
+type adaptiveEncoder struct {
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int
+	out       []byte
+}
+
+func newAdaptiveEncoder(dst []byte) *adaptiveEncoder {
+	return &adaptiveEncoder{rng: math.MaxUint32, cacheSize: 1, out: dst}
+}
+
+func (e *adaptiveEncoder) shiftLow() {
+	if uint32(e.low) < 0xFF000000 || e.low >= 1<<32 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for ; e.cacheSize > 0; e.cacheSize-- {
+			e.out = append(e.out, temp+carry)
+			temp = 0xFF
+		}
+		e.cache = byte(e.low >> 24)
+	}
+	e.cacheSize++
+	e.low = (e.low & 0x00FFFFFF) << 8
+}
+
+func (e *adaptiveEncoder) normalize() {
+	for e.rng < rcTopValue {
+		e.rng <<= 8
+		e.shiftLow()
+	}
+}
+
+func (e *adaptiveEncoder) encodeBit(p *uint16, bit int) {
+	bound := (e.rng >> probBits) * uint32(*p)
+	if bit == 0 {
+		e.rng = bound
+		*p += (1<<probBits - *p) >> probShift
+	} else {
+		e.low += uint64(bound)
+		e.rng -= bound
+		*p -= *p >> probShift
+	}
+	e.normalize()
+}
+
+func (e *adaptiveEncoder) encodeFreq(start, size, total uint32) {
+	e.rng /= total
+	e.low += uint64(start) * uint64(e.rng)
+	e.rng *= size
+	e.normalize()
+}
+
+func (e *adaptiveEncoder) finish() []byte {
+	for i := 0; i < 5; i++ {
+		e.shiftLow()
+	}
+	return e.out
+}
+
+type adaptiveDecoder struct {
+	code uint32
+	rng  uint32
+	in   []byte
+}
+
+func newAdaptiveDecoder(in []byte) *adaptiveDecoder {
+	d := &adaptiveDecoder{rng: math.MaxUint32, in: in}
+	for i := 0; i < 5; i++ {
+		d.code = d.code<<8 | uint32(d.next())
+	}
+	return d
+}
+
+// next 输入结束后补 0，由调用方按长度停止
+func (d *adaptiveDecoder) next() byte {
+	if len(d.in) == 0 {
+		return 0
+	}
+	b := d.in[0]
+	d.in = d.in[1:]
+	return b
+}
+
+func (d *adaptiveDecoder) normalize() {
+	for d.rng < rcTopValue {
+		d.rng <<= 8
+		d.code = d.code<<8 | uint32(d.next())
+	}
+}
+
+func (d *adaptiveDecoder) decodeBit(p *uint16) int {
+	bound := (d.rng >> probBits) * uint32(*p)
+	var bit int
+	if d.code < bound {
+		d.rng = bound
+		*p += (1<<probBits - *p) >> probShift
+	} else {
+		d.code -= bound
+		d.rng -= bound
+		*p -= *p >> probShift
+		bit = 1
+	}
+	d.normalize()
+	return bit
+}
+
+// decodeFreq 返回当前编码值落在 [0, total) 中的位置，之后需调用 consume
+func (d *adaptiveDecoder) decodeFreq(total uint32) uint32 {
+	d.rng /= total
+	return min(d.code/d.rng, total-1)
+}
+
+func (d *adaptiveDecoder) consume(start, size uint32) {
+	d.code -= start * d.rng
+	d.rng *= size
+	d.normalize()
+}
+
+// freqTable 符号模式下一个上下文的自适应频率
+type freqTable struct {
+	freq  [256]uint32
+	total uint32
+}
+
+func newFreqTable() *freqTable {
+	t := &freqTable{total: 256}
+	for i := range t.freq {
+		t.freq[i] = 1
+	}
+	return t
+}
+
+func (t *freqTable) update(s int) {
+	t.freq[s] += freqIncrement
+	t.total += freqIncrement
+	if t.total > freqLimit {
+		t.total = 0
+		for i, f := range t.freq {
+			t.freq[i] = (f + 1) / 2
+			t.total += t.freq[i]
+		}
+	}
+}
+
+// bitTree 二进制模式下一个上下文的 255 个节点概率（按已编码的高位索引）
+type bitTree [256]uint16
+
+func newBitTree() *bitTree {
+	t := new(bitTree)
+	for i := range t {
+		t[i] = probInit
+	}
+	return t
+}
+
+// adaptiveModel 按上下文延迟分配的概率表
+type adaptiveModel struct {
+	opts  AdaptiveOptions
+	freqs []*freqTable
+	trees []*bitTree
+}
+
+func newAdaptiveModel(opts AdaptiveOptions) *adaptiveModel {
+	n := opts.contexts() << (8 * opts.Order)
+	m := &adaptiveModel{opts: opts}
+	if opts.Binary {
+		m.trees = make([]*bitTree, n)
+	} else {
+		m.freqs = make([]*freqTable, n)
+	}
+	return m
+}
+
+func (m *adaptiveModel) freq(ctx int) *freqTable {
+	if m.freqs[ctx] == nil {
+		m.freqs[ctx] = newFreqTable()
+	}
+	return m.freqs[ctx]
+}
+
+func (m *adaptiveModel) tree(ctx int) *bitTree {
+	if m.trees[ctx] == nil {
+		m.trees[ctx] = newBitTree()
+	}
+	return m.trees[ctx]
+}
+
+func (o AdaptiveOptions) validate() error {
+	if o.Order < 0 || o.Order > 2 {
+		return fmt.Errorf("rangeCoding: unsupported context order %d", o.Order)
+	}
+	if o.Context != nil && o.Contexts <= 0 {
+		return fmt.Errorf("rangeCoding: invalid context count %d", o.Contexts)
+	}
+	return nil
+}
+
+// CompressBytesAdaptive 格式：uvarint 原始长度，1 字节模型标志（低 2 位 Order，第 3 位 Binary），uvarint 附加上下文个数，编码数据。
+// opts 无效时返回错误
+func CompressBytesAdaptive(dst []byte, src []byte, opts AdaptiveOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	flags := byte(opts.Order)
+	if opts.Binary {
+		flags |= 1 << 2
+	}
+	dst = append(dst, flags)
+	dst = binary.AppendUvarint(dst, uint64(opts.contexts()))
+
+	m := newAdaptiveModel(opts)
+	enc := newAdaptiveEncoder(dst)
+	for i, b := range src {
+		ctx, err := opts.context(i, src)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Binary {
+			t := m.tree(ctx)
+			node := 1
+			for k := 7; k >= 0; k-- {
+				bit := int(b>>k) & 1
+				enc.encodeBit(&t[node], bit)
+				node = node<<1 | bit
+			}
+			continue
+		}
+		t := m.freq(ctx)
+		start := uint32(0)
+		for _, f := range t.freq[:b] {
+			start += f
+		}
+		enc.encodeFreq(start, t.freq[b], t.total)
+		t.update(int(b))
+	}
+	return enc.finish(), nil
+}
+
+// DecompressBytesAdaptive 还原 CompressBytesAdaptive。Order 和 Binary 从流中读取，opts 只提供 Context
+func DecompressBytesAdaptive(dst []byte, src []byte, opts AdaptiveOptions) ([]byte, error) {
+	n, m := binary.Uvarint(src)
+	if m <= 0 || len(src) < m+1 {
+		return dst, fmt.Errorf("rangeCoding: invalid adaptive header")
+	}
+	flags := src[m]
+	opts.Order, opts.Binary = int(flags&3), flags&(1<<2) != 0
+	if err := opts.validate(); err != nil {
+		return dst, err
+	}
+	contexts, k := binary.Uvarint(src[m+1:])
+	if k <= 0 || contexts != uint64(opts.contexts()) {
+		return dst, fmt.Errorf("rangeCoding: stream uses %d extra contexts, options provide %d", contexts, opts.contexts())
+	}
+	// 每个输入字节最多编码约 2^16 个符号，防止伪造的长度耗尽内存
+	if n > uint64(len(src))<<16 {
+		return dst, fmt.Errorf("rangeCoding: invalid length %d", n)
+	}
+
+	model := newAdaptiveModel(opts)
+	dec := newAdaptiveDecoder(src[m+1+k:])
+	start := len(dst)
+	for i := 0; i < int(n); i++ {
+		ctx, err := opts.context(i, dst[start:])
+		if err != nil {
+			return dst, err
+		}
+		if opts.Binary {
+			t := model.tree(ctx)
+			node := 1
+			for node < 256 {
+				node = node<<1 | dec.decodeBit(&t[node])
+			}
+			dst = append(dst, byte(node))
+			continue
+		}
+		t := model.freq(ctx)
+		target := dec.decodeFreq(t.total)
+		s, cum := 0, uint32(0)
+		for cum+t.freq[s] <= target {
+			cum += t.freq[s]
+			s++
+		}
+		dec.consume(cum, t.freq[s])
+		t.update(s)
+		dst = append(dst, byte(s))
+	}
+	return dst, nil
+}
+
+// CompressAdaptive 以 FloatOptions 压缩 uint64 数组
+func CompressAdaptive(dst []byte, src []uint64) []byte {
+	buf := make([]byte, len(src)*8)
+	for i, u := range src {
+		binary.LittleEndian.PutUint64(buf[i*8:], u)
+	}
+	return compressFloatOptions(dst, buf)
+}
+
+// CompressFloatAdaptive 以 FloatOptions 压缩 float64 数组
+func CompressFloatAdaptive(dst []byte, src []float64) []byte {
+	buf := make([]byte, len(src)*8)
+	for i, v := range src {
+		binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(v))
+	}
+	return compressFloatOptions(dst, buf)
+}
+
+// compressFloatOptions 以 FloatOptions 压缩，FloatOptions 被改为无效配置时 panic
+func compressFloatOptions(dst []byte, buf []byte) []byte {
+	out, err := CompressBytesAdaptive(dst, buf, FloatOptions)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// DecompressAdaptive 还原 CompressAdaptive
+func DecompressAdaptive(dst []uint64, src []byte) ([]uint64, error) {
+	buf, err := DecompressBytesAdaptive(nil, src, FloatOptions)
+	if err != nil {
+		return dst, err
+	}
+	if len(buf)%8 != 0 {
+		return dst, fmt.Errorf("rangeCoding: invalid payload size %d", len(buf))
+	}
+	for i := 0; i < len(buf); i += 8 {
+		dst = append(dst, binary.LittleEndian.Uint64(buf[i:]))
+	}
+	return dst, nil
+}
+
+// DecompressFloatAdaptive 还原 CompressFloatAdaptive
+func DecompressFloatAdaptive(dst []float64, src []byte) ([]float64, error) {
+	buf, err := DecompressBytesAdaptive(nil, src, FloatOptions)
+	if err != nil {
+		return dst, err
+	}
+	if len(buf)%8 != 0 {
+		return dst, fmt.Errorf("rangeCoding: invalid payload size %d", len(buf))
+	}
+	for i := 0; i < len(buf); i += 8 {
+		dst = append(dst, math.Float64frombits(binary.LittleEndian.Uint64(buf[i:])))
+	}
+	return dst, nil
+}
@@ -0,0 +1,148 @@
+package rangeCoding
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompressFloatAdaptive(t *testing.T) {
+	for _, c := range codectest.Floats() {
+		codectest.RoundTripFloats(t, c.Name, CompressFloatAdaptive, DecompressFloatAdaptive, c.Data)
+	}
+}
+
+func TestCompressAdaptive(t *testing.T) {
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, CompressAdaptive, DecompressAdaptive, c.Data)
+	}
+}
+
+// roundTripBytes 按 opts 压缩并还原 src
+func roundTripBytes(t *testing.T, name string, src []byte, opts AdaptiveOptions) []byte {
+	t.Helper()
+	out, err := CompressBytesAdaptive(nil, src, opts)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	got, err := DecompressBytesAdaptive(nil, out, opts)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if string(got) != string(src) {
+		t.Fatalf("%s: output differs", name)
+	}
+	return out
+}
+
+// order-2 的 2^16 个上下文全部被用到，每个上下文的表都从初始状态开始
+func TestOrder2AllContexts(t *testing.T) {
+	// 依次写出所有字节对，每一对都作为前两个字节出现过
+	src := make([]byte, 0, 1<<17+1)
+	for i := 0; i < 1<<16; i++ {
+		src = append(src, byte(i>>8), byte(i))
+	}
+	src = append(src, 0)
+	seen := make([]bool, 1<<16)
+	covered := 0
+	for i := 2; i < len(src); i++ {
+		if ctx := int(src[i-2])<<8 | int(src[i-1]); !seen[ctx] {
+			seen[ctx] = true
+			covered++
+		}
+	}
+	if covered != len(seen) {
+		t.Fatalf("data covers %d of %d order-2 contexts", covered, len(seen))
+	}
+	for _, binary := range []bool{false, true} {
+		roundTripBytes(t, "all contexts", src, AdaptiveOptions{Order: 2, Binary: binary})
+	}
+}
+
+// 同一个 order-2 上下文反复出现时频率总数多次超过 freqLimit 而减半，只出现过一次的符号频率不会减到 0
+func TestOrder2FrequencyRescale(t *testing.T) {
+	src := make([]byte, 0, 300000)
+	for i := 0; i < 256; i++ {
+		src = append(src, 0, 0, byte(i))
+	}
+	for len(src) < cap(src)-3*256 {
+		src = append(src, 0)
+	}
+	for i := 0; i < 256; i++ {
+		src = append(src, 0, 0, byte(i))
+	}
+	for _, binary := range []bool{false, true} {
+		out := roundTripBytes(t, "rescale", src, AdaptiveOptions{Order: 2, Binary: binary})
+		if len(out) > len(src)/30 {
+			t.Errorf("binary %v: %d bytes compressed into %d", binary, len(src), len(out))
+		}
+	}
+}
+
+// 伪造的长度超过每个输入字节 2^16 个符号时不分配内存
+func TestDecompressBytesAdaptiveInvalidLength(t *testing.T) {
+	out, err := CompressBytesAdaptive(nil, []byte{1, 2, 3}, AdaptiveOptions{Order: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, m := binary.Uvarint(out)
+	forged := binary.AppendUvarint(nil, uint64(len(out))<<20)
+	forged = append(forged, out[m:]...)
+	if _, err := DecompressBytesAdaptive(nil, forged, AdaptiveOptions{}); err == nil {
+		t.Error("expected an error for a forged length")
+	}
+}
+
+// 每种阶数、二进制和符号模式、有无附加上下文都能还原
+func TestCompressBytesAdaptive(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	src := make([]byte, 5000)
+	for i := range src {
+		src[i] = byte(rng.Intn(16)) + byte(i%8)*16
+	}
+	for order := 0; order <= 2; order++ {
+		for _, binary := range []bool{false, true} {
+			for _, ctx := range []ContextFunc{nil, BytePosition} {
+				opts := AdaptiveOptions{Order: order, Binary: binary, Context: ctx, Contexts: 8}
+				out, err := CompressBytesAdaptive(nil, src, opts)
+				if err != nil {
+					t.Fatalf("%+v: %v", opts, err)
+				}
+				got, err := DecompressBytesAdaptive(nil, out, opts)
+				if err != nil {
+					t.Fatalf("%+v: %v", opts, err)
+				}
+				if string(got) != string(src) {
+					t.Fatalf("order %d binary %v context %v: output differs", order, binary, ctx != nil)
+				}
+			}
+		}
+	}
+}
+
+func TestCompressBytesAdaptiveInvalidOptions(t *testing.T) {
+	src := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	for _, opts := range []AdaptiveOptions{
+		{Order: 3},
+		{Order: -1},
+		{Context: BytePosition},
+		{Context: BytePosition, Contexts: 4},
+		{Context: func(i int) int { return -i }, Contexts: 8},
+	} {
+		if _, err := CompressBytesAdaptive(nil, src, opts); err == nil {
+			t.Errorf("order %d contexts %d: expected an error", opts.Order, opts.Contexts)
+		}
+	}
+}
+
+// 解压时 Context 的返回值超出范围同样返回错误
+func TestDecompressBytesAdaptiveInvalidContext(t *testing.T) {
+	out, err := CompressBytesAdaptive(nil, []byte{1, 2, 3}, AdaptiveOptions{Context: BytePosition, Contexts: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecompressBytesAdaptive(nil, out, AdaptiveOptions{Context: func(int) int { return 8 }, Contexts: 8}); err == nil {
+		t.Error("expected an error")
+	}
+}
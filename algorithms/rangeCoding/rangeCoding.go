@@ -136,12 +136,17 @@ func (d *RangeDecoder) readBit() uint32 {
 
 // Decode 根据累计频率表解码一个符号
 func (d *RangeDecoder) Decode(cumFreq []uint32, total uint32) byte {
+	return byte(d.DecodeSymbol(cumFreq[:headerFreqCount+1], total))
+}
+
+// DecodeSymbol 根据任意大小的累计频率表（长度为符号数+1）解码一个符号
+func (d *RangeDecoder) DecodeSymbol(cumFreq []uint32, total uint32) int {
 	if total == 0 {
 		panic("rangeCoding: invalid total frequency")
 	}
 	rangeVal := d.high - d.low + 1
 	value := uint32(((d.code-d.low+1)*uint64(total) - 1) / rangeVal)
-	symbol := sort.Search(headerFreqCount, func(i int) bool {
+	symbol := sort.Search(len(cumFreq)-1, func(i int) bool {
 		return cumFreq[i+1] > value
 	})
 	lowCount := cumFreq[symbol]
@@ -162,7 +167,7 @@ func (d *RangeDecoder) Decode(cumFreq []uint32, total uint32) byte {
 			d.high -= firstQtr
 			d.code -= firstQtr
 		default:
-			return byte(symbol)
+			return symbol
 		}
 		d.low <<= 1
 		d.high = (d.high << 1) | 1
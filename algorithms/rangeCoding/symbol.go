@@ -0,0 +1,69 @@
+package rangeCoding
+
+// 供其他算法使用的自适应二进制范围编码接口：调用方自己维护上下文，
+// 把控制码、前导零个数这类小符号交给 SymbolModel 编码，其余数据另行保存。
+
+// SymbolModel 取值 [0, 2^bits) 的自适应符号模型，按位二叉树编码
+type SymbolModel struct {
+	bits  int
+	probs []uint16
+}
+
+func NewSymbolModel(bits int) *SymbolModel {
+	m := &SymbolModel{bits: bits, probs: make([]uint16, 1<<bits)}
+	for i := range m.probs {
+		m.probs[i] = probInit
+	}
+	return m
+}
+
+// NewSymbolModels n 个相互独立的模型，按上下文下标选用
+func NewSymbolModels(n, bits int) []*SymbolModel {
+	ms := make([]*SymbolModel, n)
+	for i := range ms {
+		ms[i] = NewSymbolModel(bits)
+	}
+	return ms
+}
+
+// Encoder 编码结果追加到创建时传入的 dst
+type Encoder struct {
+	enc *adaptiveEncoder
+}
+
+func NewEncoder(dst []byte) *Encoder {
+	return &Encoder{newAdaptiveEncoder(dst)}
+}
+
+// Encode 从高位到低位编码 v 的低 m.bits 位
+func (e *Encoder) Encode(m *SymbolModel, v int) {
+	node := 1
+	for k := m.bits - 1; k >= 0; k-- {
+		bit := v >> k & 1
+		e.enc.encodeBit(&m.probs[node], bit)
+		node = node<<1 | bit
+	}
+}
+
+// Finish 输出剩余字节并返回 dst
+func (e *Encoder) Finish() []byte {
+	return e.enc.finish()
+}
+
+// Decoder 输入结束后按 0 继续解码，调用方需自己记录符号个数
+type Decoder struct {
+	dec *adaptiveDecoder
+}
+
+func NewDecoder(src []byte) *Decoder {
+	return &Decoder{newAdaptiveDecoder(src)}
+}
+
+// Decode 还原 Encoder.Encode
+func (d *Decoder) Decode(m *SymbolModel) int {
+	node := 1
+	for node < len(m.probs) {
+		node = node<<1 | d.dec.decodeBit(&m.probs[node])
+	}
+	return node - len(m.probs)
+}
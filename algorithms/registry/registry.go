@@ -0,0 +1,266 @@
+// Package registry 按名称查找压缩算法，供需要在运行时选择算法的组合压缩器（如 myal）使用。
+// 表中的 Compress 都会把结果追加到 dst 之后。
+package registry
+
+import (
+	"math"
+	"myalgo/algorithms/alp"
+	"myalgo/algorithms/ans"
+	"myalgo/algorithms/brotli"
+	"myalgo/algorithms/chimp"
+	"myalgo/algorithms/chimp128"
+	"myalgo/algorithms/elf"
+	"myalgo/algorithms/fpc"
+	"myalgo/algorithms/gorillaz"
+	"myalgo/algorithms/huffman"
+	"myalgo/algorithms/huffmanLib"
+	"myalgo/algorithms/lz4"
+	"myalgo/algorithms/lz77"
+	"myalgo/algorithms/lzw"
+	"myalgo/algorithms/pfor"
+	"myalgo/algorithms/pongo"
+	"myalgo/algorithms/predict"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/algorithms/rle"
+	"myalgo/algorithms/shuffle"
+	"myalgo/algorithms/simple8b"
+	"myalgo/algorithms/snappy"
+	"myalgo/algorithms/tsxor"
+	"myalgo/algorithms/xz"
+	"myalgo/algorithms/zstd"
+)
+
+// FloatCodec 浮点数压缩算法
+type FloatCodec struct {
+	Name       string
+	Compress   func(dst []byte, src []float64) []byte
+	Decompress func(dst []float64, src []byte) ([]float64, error)
+}
+
+// IntegerCodec 整数压缩算法
+type IntegerCodec struct {
+	Name       string
+	Compress   func(dst []byte, src []uint64) []byte
+	Decompress func(dst []uint64, src []byte) ([]uint64, error)
+	// MaxValue 支持的最大值，0 表示不限制
+	MaxValue uint64
+}
+
+// Supports 判断 src 中的值是否都在算法支持的范围内
+func (c IntegerCodec) Supports(src []uint64) bool {
+	if c.MaxValue == 0 {
+		return true
+	}
+	for _, v := range src {
+		if v > c.MaxValue {
+			return false
+		}
+	}
+	return true
+}
+
+var floatCodecs = []FloatCodec{
+	{"chimp", nonEmptyFloat(chimp.CompressFloat), copyInputFloat(chimp.DecompressFloat)},
+	{"chimp128", chimp128.CompressFloat, chimp128.DecompressFloat},
+	{"chimp(entropy)", chimp.CompressFloatEntropy, chimp.DecompressFloatEntropy},
+	{"chimp128(entropy)", chimp128.CompressFloatEntropy, chimp128.DecompressFloatEntropy},
+	{"elf", elf.CompressFloat, elf.DecompressFloat},
+	{"fpc", fpc.CompressFloat, fpc.DecompressFloat},
+	{"gorilla", nonEmptyFloat(gorillaz.CompressFloat), copyInputFloat(gorillaz.DecompressFloat)},
+	{"gorilla(entropy)", gorillaz.CompressFloatEntropy, gorillaz.DecompressFloatEntropy},
+	{"huffman", huffman.CompressFloat, huffman.DecompressFloat},
+	{"huffmanLib", huffmanLib.CompressFloat, huffmanLib.DecompressFloat},
+	{"ans", ans.CompressFloat, ans.DecompressFloat},
+	{"rangeCoding", rangeCoding.CompressFloat, rangeCoding.DecompressFloat},
+	{"rangeCodingAdaptive", rangeCoding.CompressFloatAdaptive, rangeCoding.DecompressFloatAdaptive},
+	{"lz77", lz77.CompressFloat, lz77.DecompressFloat},
+	{"lz77Aligned", lz77.CompressFloatAligned, lz77.DecompressFloat},
+	{"zstd", zstd.CompressFloat, zstd.DecompressFloat},
+	{"lz4", appendFloat(lz4.CompressFloat), lz4.DecompressFloat},
+	{"snappy", snappy.CompressFloat, snappy.DecompressFloat},
+	{"brotli", appendFloat(brotli.CompressFloat), brotli.DecompressFloat},
+	{"xz", appendFloat(xz.CompressFloat), xz.DecompressFloat},
+	{"alp", alp.CompressFloat, alp.DecompressFloat},
+	{"pongo", pongo.CompressFloat, pongo.DecompressFloat},
+}
+
+// 字节重排加通用后端的组合，如 "bss+zstd"、"xor+bitshuffle+lz4"
+func init() {
+	for _, c := range shuffle.Codecs() {
+		floatCodecs = append(floatCodecs, FloatCodec{c.Name(), c.CompressFloat, c.DecompressFloat})
+	}
+	// 预测器和残差的组合，如 "fcm+xor"、"linear+sub"
+	for _, c := range predict.Codecs() {
+		floatCodecs = append(floatCodecs, FloatCodec{c.Name, c.CompressFloat, c.DecompressFloat})
+		integerCodecs = append(integerCodecs, IntegerCodec{c.Name, c.Compress, c.Decompress, 0})
+	}
+	// 长游程单独编码，其余交给内层算法，如 "rle+alp"
+	for _, name := range rleInnerCodecs {
+		c, _ := Float(name)
+		h := rle.Hybrid{Compress: c.Compress, Decompress: c.Decompress}
+		floatCodecs = append(floatCodecs, FloatCodec{"rle+" + name, h.CompressFloat, h.DecompressFloat})
+	}
+	// Elf 擦除后交给 XOR 类算法，如 "elf+chimp128(entropy)"
+	for _, name := range elfInnerCodecs {
+		c, _ := Float(name)
+		s := elf.Stage{Compress: c.Compress, Decompress: c.Decompress}
+		floatCodecs = append(floatCodecs, FloatCodec{"elf+" + name, s.CompressFloat, s.DecompressFloat})
+	}
+}
+
+// rleInnerCodecs 与游程抽取组合的内层算法，原版 chimp128 会丢值，使用 chimp128(entropy)
+var rleInnerCodecs = []string{"gorilla", "chimp128(entropy)", "alp", "zstd"}
+
+// elfInnerCodecs 与 Elf 擦除组合的内层算法，必须能按位还原擦除后的值。
+// 原版 chimp128 在随机位模式和 NaN 上会丢值，使用 chimp128(entropy)
+var elfInnerCodecs = []string{"gorilla", "chimp", "chimp128(entropy)"}
+
+var integerCodecs = []IntegerCodec{
+	{"simple8b", simple8b.Compress, simple8b.Decompress, 0},
+	{"chimp", nonEmptyInteger(chimp.Compress), copyInputInteger(chimp.Decompress), 0},
+	{"chimp(entropy)", chimp.CompressEntropy, chimp.DecompressEntropy, 0},
+	{"chimp128(entropy)", chimp128.CompressEntropy, chimp128.DecompressEntropy, 0},
+	{"fpc", fpc.Compress, fpc.Decompress, 0},
+	{"gorilla", nonEmptyInteger(gorillaz.Compress), copyInputInteger(gorillaz.Decompress), 0},
+	{"gorilla(entropy)", gorillaz.CompressEntropy, gorillaz.DecompressEntropy, 0},
+	{"huffmanLib", huffmanLib.Compress, huffmanLib.Decompress, 0},
+	{"ans", ans.Compress, ans.Decompress, 0},
+	{"rangeCoding", rangeCoding.Compress, rangeCoding.Decompress, 0},
+	{"rangeCodingAdaptive", rangeCoding.CompressAdaptive, rangeCoding.DecompressAdaptive, 0},
+	{"lz77", lz77.Compress, lz77.Decompress, 0},
+	{"lz77Aligned", lz77.CompressAligned, lz77.Decompress, 0},
+	{"lzw", lzw.Compress, lzw.Decompress, 0},
+	{"tsxor", nonEmptyInteger(tsxor.Compress), copyInputInteger(tsxor.Decompress), 0},
+	{"zstd", zstd.Compress, zstd.Decompress, 0},
+	{"lz4", appendInteger(lz4.Compress), lz4.Decompress, 0},
+	{"snappy", snappy.Compress, snappy.Decompress, 0},
+	{"brotli", appendInteger(brotli.Compress), brotli.Decompress, 0},
+	{"xz", appendInteger(xz.Compress), xz.Decompress, 0},
+	{"pfor", pfor.Compress, pfor.Decompress, 0},
+	{"rle", rle.Compress, rle.Decompress, 0},
+}
+
+// appendFloat 适配不会追加到 dst 的压缩函数
+func appendFloat(f func([]byte, []float64) []byte) func([]byte, []float64) []byte {
+	return func(dst []byte, src []float64) []byte { return append(dst, f(nil, src)...) }
+}
+
+func appendInteger(f func([]byte, []uint64) []byte) func([]byte, []uint64) []byte {
+	return func(dst []byte, src []uint64) []byte { return append(dst, f(nil, src)...) }
+}
+
+// nonEmptyFloat 适配不支持空输入的压缩函数（gorilla、chimp），空输入压缩为空
+func nonEmptyFloat(f func([]byte, []float64) []byte) func([]byte, []float64) []byte {
+	return func(dst []byte, src []float64) []byte {
+		if len(src) == 0 {
+			return dst
+		}
+		return f(dst, src)
+	}
+}
+
+// copyInputFloat 适配解压时会改写输入的函数（gorilla、chimp），解压输入的副本，空输入解压为空
+func copyInputFloat(f func([]float64, []byte) ([]float64, error)) func([]float64, []byte) ([]float64, error) {
+	return func(dst []float64, src []byte) ([]float64, error) {
+		if len(src) == 0 {
+			return dst, nil
+		}
+		return f(dst, append([]byte(nil), src...))
+	}
+}
+
+func nonEmptyInteger(f func([]byte, []uint64) []byte) func([]byte, []uint64) []byte {
+	return func(dst []byte, src []uint64) []byte {
+		if len(src) == 0 {
+			return dst
+		}
+		return f(dst, src)
+	}
+}
+
+func copyInputInteger(f func([]uint64, []byte) ([]uint64, error)) func([]uint64, []byte) ([]uint64, error) {
+	return func(dst []uint64, src []byte) ([]uint64, error) {
+		if len(src) == 0 {
+			return dst, nil
+		}
+		return f(dst, append([]byte(nil), src...))
+	}
+}
+
+// RoundTrip 压缩 src 并校验能否按位还原，部分算法不支持 NaN、Inf 等特殊值（甚至会 panic），不能还原时 ok 为 false
+func (c FloatCodec) RoundTrip(src []float64) (out []byte, ok bool) {
+	defer func() {
+		if recover() != nil {
+			out, ok = nil, false
+		}
+	}()
+	out = c.Compress(nil, src)
+	got, err := c.Decompress(nil, append([]byte(nil), out...))
+	if err != nil || len(got) != len(src) {
+		return nil, false
+	}
+	for i, v := range src {
+		if math.Float64bits(got[i]) != math.Float64bits(v) {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// RoundTrip 压缩 src 并校验能否还原，部分算法对大值或特定分布会出错（甚至 panic），不能还原时 ok 为 false
+func (c IntegerCodec) RoundTrip(src []uint64) (out []byte, ok bool) {
+	defer func() {
+		if recover() != nil {
+			out, ok = nil, false
+		}
+	}()
+	out = c.Compress(nil, src)
+	got, err := c.Decompress(nil, append([]byte(nil), out...))
+	if err != nil || len(got) != len(src) {
+		return nil, false
+	}
+	for i, v := range src {
+		if got[i] != v {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// Float 按名称查找浮点数压缩算法
+func Float(name string) (FloatCodec, bool) {
+	for _, c := range floatCodecs {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return FloatCodec{}, false
+}
+
+// Integer 按名称查找整数压缩算法
+func Integer(name string) (IntegerCodec, bool) {
+	for _, c := range integerCodecs {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return IntegerCodec{}, false
+}
+
+// FloatNames 所有已注册的浮点数压缩算法名称
+func FloatNames() []string {
+	names := make([]string, len(floatCodecs))
+	for i, c := range floatCodecs {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// IntegerNames 所有已注册的整数压缩算法名称
+func IntegerNames() []string {
+	names := make([]string, len(integerCodecs))
+	for i, c := range integerCodecs {
+		names[i] = c.Name
+	}
+	return names
+}
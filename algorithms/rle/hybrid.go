@@ -0,0 +1,188 @@
+package rle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"myalgo/common"
+)
+
+const (
+	// minRunThreshold 自动选择时最短的抽取游程，长度为 2 的游程省下的一个值抵不过游程头部
+	minRunThreshold = 3
+	// minInRunRatio 处在游程中的值少于这个比例时不抽取游程
+	minInRunRatio = 0.1
+)
+
+// Hybrid 把长的常数游程编码为 (值, 长度)，其余片段按原顺序拼接后交给内层 float 算法，适合平台较多的传感器数据
+type Hybrid struct {
+	Compress   func(dst []byte, src []float64) []byte
+	Decompress func(dst []float64, src []byte) ([]float64, error)
+	// Threshold 长度不小于它的游程才单独编码，0 表示在 CandidateThresholds 中选输出最小的
+	Threshold int
+}
+
+// CandidateThresholds 根据游程统计给出候选的最短抽取长度，没有值得抽取的游程时返回 nil。
+// 合适的阈值取决于内层算法：Gorilla 的重复值只占 1 位，只值得抽取长平台；Chimp128、ALP 的重复值仍占不少位，
+// 短游程也值得抽取。候选为 minRunThreshold、常数游程（长度至少为 2）的平均长度以及两者的几何平均
+func CandidateThresholds(rs *common.RunLengthStats) []int {
+	if rs == nil || rs.InRunRatio < minInRunRatio || rs.MaxRunLength < minRunThreshold || rs.ConstantRunRatio == 0 {
+		return nil
+	}
+	// 值个数为 AvgRunLength*RunCount，其中 InRunRatio 的值处在 ConstantRunRatio*RunCount 个常数游程中
+	avg := rs.InRunRatio * rs.AvgRunLength / rs.ConstantRunRatio
+	hi := min(max(int(math.Ceil(avg)), minRunThreshold), rs.MaxRunLength)
+	mid := int(math.Round(math.Sqrt(float64(minRunThreshold * hi))))
+	candidates := []int{minRunThreshold}
+	for _, t := range []int{mid, hi} {
+		if t > candidates[len(candidates)-1] {
+			candidates = append(candidates, t)
+		}
+	}
+	return candidates
+}
+
+// runStats 由 RunLengthEncode 的结果直接计算游程统计，n 为值个数。按位模式比较，与实际抽取的游程一致
+func runStats(runs []uint64, n int) *common.RunLengthStats {
+	if n == 0 {
+		return nil
+	}
+	rs := &common.RunLengthStats{RunCount: len(runs) / 2}
+	constantRuns, inRun := 0, 0
+	for i := 1; i < len(runs); i += 2 {
+		length := int(runs[i])
+		rs.MaxRunLength = max(rs.MaxRunLength, length)
+		if length > 1 {
+			constantRuns++
+			inRun += length
+		}
+	}
+	rs.AvgRunLength = float64(n) / float64(rs.RunCount)
+	rs.ConstantRunRatio = float64(constantRuns) / float64(rs.RunCount)
+	rs.InRunRatio = float64(inRun) / float64(n)
+	return rs
+}
+
+// CompressFloat 格式：uvarint 值个数，uvarint 游程个数，每个游程的 uvarint 间隔（之前的剩余值个数）和 uvarint 长度，
+// uvarint 长度加内层算法压缩的游程值，内层算法压缩的剩余值（没有剩余值时为空）。
+// Threshold 为 0 时每个候选阈值都压缩一遍
+func (h Hybrid) CompressFloat(dst []byte, src []float64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = math.Float64bits(v)
+	}
+	runs := RunLengthEncode(values)
+	if h.Threshold > 0 {
+		return h.compressRuns(dst, len(src), runs, h.Threshold)
+	}
+	candidates := CandidateThresholds(runStats(runs, len(src)))
+	if candidates == nil {
+		return h.compressRuns(dst, len(src), runs, math.MaxInt)
+	}
+	var best []byte
+	for _, threshold := range candidates {
+		if out := h.compressRuns(nil, len(src), runs, threshold); best == nil || len(out) < len(best) {
+			best = out
+		}
+	}
+	return append(dst, best...)
+}
+
+// compressRuns 抽取长度不小于 threshold 的游程，total 为值个数
+func (h Hybrid) compressRuns(dst []byte, total int, runs []uint64, threshold int) []byte {
+	var header []byte
+	var runValues, rest []float64
+	count, gap := 0, 0
+	for i := 0; i < len(runs); i += 2 {
+		v, n := math.Float64frombits(runs[i]), int(runs[i+1])
+		if n < threshold {
+			for k := 0; k < n; k++ {
+				rest = append(rest, v)
+			}
+			gap += n
+			continue
+		}
+		header = binary.AppendUvarint(header, uint64(gap))
+		header = binary.AppendUvarint(header, uint64(n))
+		runValues = append(runValues, v)
+		count++
+		gap = 0
+	}
+
+	dst = binary.AppendUvarint(dst, uint64(total))
+	dst = binary.AppendUvarint(dst, uint64(count))
+	dst = append(dst, header...)
+	var inner []byte
+	if len(runValues) > 0 {
+		inner = h.Compress(nil, runValues)
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(inner)))
+	dst = append(dst, inner...)
+	if len(rest) > 0 {
+		dst = h.Compress(dst, rest)
+	}
+	return dst
+}
+
+// DecompressFloat 还原 CompressFloat，结果追加到 dst
+func (h Hybrid) DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 {
+		return nil, fmt.Errorf("rle: invalid hybrid header")
+	}
+	src = src[k:]
+	count, k := binary.Uvarint(src)
+	// 每个游程至少占 2 字节
+	if k <= 0 || count > uint64(len(src)) {
+		return nil, fmt.Errorf("rle: invalid run count")
+	}
+	src = src[k:]
+	gaps, lengths := make([]uint64, count), make([]uint64, count)
+	// total 游程及之前的值个数，inRuns 游程中的值个数
+	total, inRuns := uint64(0), uint64(0)
+	for i := range gaps {
+		var k1, k2 int
+		gaps[i], k1 = binary.Uvarint(src)
+		if k1 > 0 {
+			lengths[i], k2 = binary.Uvarint(src[k1:])
+		}
+		if k1 <= 0 || k2 <= 0 {
+			return nil, fmt.Errorf("rle: truncated run %d", i)
+		}
+		src = src[k1+k2:]
+		total += gaps[i] + lengths[i]
+		inRuns += lengths[i]
+		if total > n {
+			return nil, fmt.Errorf("rle: run %d exceeds %d values", i, n)
+		}
+	}
+
+	size, k := binary.Uvarint(src)
+	if k <= 0 || size > uint64(len(src)-k) {
+		return nil, fmt.Errorf("rle: invalid run value length")
+	}
+	var runValues, rest []float64
+	var err error
+	if size > 0 {
+		if runValues, err = h.Decompress(nil, src[k:k+int(size)]); err != nil {
+			return nil, err
+		}
+	}
+	if tail := src[k+int(size):]; len(tail) > 0 {
+		if rest, err = h.Decompress(nil, tail); err != nil {
+			return nil, err
+		}
+	}
+	if uint64(len(runValues)) != count || uint64(len(rest)) != n-inRuns {
+		return nil, fmt.Errorf("rle: got %d run values and %d other values, expected %d and %d", len(runValues), len(rest), count, n-inRuns)
+	}
+
+	for i, v := range runValues {
+		dst = append(dst, rest[:gaps[i]]...)
+		rest = rest[gaps[i]:]
+		for j := uint64(0); j < lengths[i]; j++ {
+			dst = append(dst, v)
+		}
+	}
+	return append(dst, rest...), nil
+}
@@ -0,0 +1,113 @@
+package rle
+
+import (
+	"encoding/binary"
+	"math"
+	"myalgo/algorithms/chimp128"
+	"myalgo/algorithms/zstd"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// inners 两种内层算法：重复值占位很少的 chimp128(entropy) 和通用字节压缩 zstd
+var inners = []Hybrid{
+	{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy},
+	{Compress: zstd.CompressFloat, Decompress: zstd.DecompressFloat},
+}
+
+// plateaus 长度为 1~12 的平台交替出现
+func plateaus(n int) []float64 {
+	data := make([]float64, 0, n)
+	for i := 0; len(data) < n; i++ {
+		for k := 0; k < i%12+1 && len(data) < n; k++ {
+			data = append(data, 20+float64(i%7)/4)
+		}
+	}
+	return data
+}
+
+// runCount 头部记录的抽取游程个数
+func runCount(t *testing.T, out []byte) int {
+	t.Helper()
+	_, m := binary.Uvarint(out)
+	count, k := binary.Uvarint(out[m:])
+	if m <= 0 || k <= 0 {
+		t.Fatalf("invalid header %x", out)
+	}
+	return int(count)
+}
+
+func TestHybrid(t *testing.T) {
+	cases := append(codectest.Floats(), codectest.FloatCase{Name: "plateaus", Data: plateaus(3000)})
+	for _, inner := range inners {
+		for _, threshold := range []int{0, 1, minRunThreshold, 8, math.MaxInt} {
+			h := inner
+			h.Threshold = threshold
+			for _, c := range cases {
+				codectest.RoundTripFloats(t, c.Name, h.CompressFloat, h.DecompressFloat, c.Data)
+			}
+		}
+	}
+}
+
+// 长度恰好等于阈值的游程被抽取，短 1 的留在剩余值中
+func TestThresholdBoundary(t *testing.T) {
+	data := []float64{1, 1, 1, 1, 2, 3, 3, 3, 4, 5, 5}
+	for _, c := range []struct{ threshold, runs int }{{2, 3}, {3, 2}, {4, 1}, {5, 0}} {
+		h := inners[0]
+		h.Threshold = c.threshold
+		out := codectest.RoundTripFloats(t, "threshold", h.CompressFloat, h.DecompressFloat, data)
+		if got := runCount(t, out); got != c.runs {
+			t.Errorf("threshold %d: %d runs, expected %d", c.threshold, got, c.runs)
+		}
+	}
+}
+
+// 游程长度等于值个数（整个输入是一个游程）或最后一个游程恰好结束在最后一个值时没有剩余值；
+// 伪造的游程长度超过值个数时返回错误
+func TestRunAtCountLimit(t *testing.T) {
+	nan := math.Float64frombits(0x7ff8000000000001)
+	for _, data := range [][]float64{
+		{nan, nan, nan},
+		append(make([]float64, 1000), 1),
+		append([]float64{1, 2}, make([]float64, 1000)...),
+	} {
+		for _, inner := range inners {
+			h := inner
+			h.Threshold = minRunThreshold
+			out := codectest.RoundTripFloats(t, "run at the end", h.CompressFloat, h.DecompressFloat, data)
+			if runCount(t, out) != 1 {
+				t.Fatalf("%d runs, expected 1", runCount(t, out))
+			}
+			// 头部依次是值个数、游程个数（1 占 1 字节）、间隔和长度，把长度改成值个数加 1
+			_, m := binary.Uvarint(out)
+			pos := m + 1
+			_, k := binary.Uvarint(out[pos:])
+			pos += k
+			length, k := binary.Uvarint(out[pos:])
+			forged := append(binary.AppendUvarint(append([]byte(nil), out[:pos]...), uint64(len(data))+1), out[pos+k:]...)
+			if _, err := h.DecompressFloat(nil, forged); err == nil {
+				t.Errorf("run of %d values forged to %d: expected an error", length, len(data)+1)
+			}
+		}
+	}
+}
+
+func TestCandidateThresholds(t *testing.T) {
+	if c := CandidateThresholds(runStats(RunLengthEncode([]uint64{1, 2, 3, 4}), 4)); c != nil {
+		t.Errorf("no runs: candidates %v", c)
+	}
+	values := make([]uint64, 3000)
+	for i, v := range plateaus(len(values)) {
+		values[i] = math.Float64bits(v)
+	}
+	c := CandidateThresholds(runStats(RunLengthEncode(values), len(values)))
+	if len(c) == 0 || c[0] != minRunThreshold {
+		t.Fatalf("candidates %v", c)
+	}
+	for i := 1; i < len(c); i++ {
+		if c[i] <= c[i-1] || c[i] > 12 {
+			t.Errorf("candidates %v", c)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package rle
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 // 游程编码实现
 func RunLengthEncode(src []uint64) []uint64 {
 	if len(src) == 0 {
@@ -46,3 +51,52 @@ func RunLengthDecode(src []uint64, length int) []uint64 {
 
 	return result
 }
+
+// Compress 格式：uvarint 值个数，uvarint 游程个数，之后每个游程是 uvarint 值和 uvarint 长度
+func Compress(dst []byte, src []uint64) []byte {
+	runs := RunLengthEncode(src)
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	dst = binary.AppendUvarint(dst, uint64(len(runs)/2))
+	for _, v := range runs {
+		dst = binary.AppendUvarint(dst, v)
+	}
+	return dst
+}
+
+// Decompress 还原 Compress，结果追加到 dst。游程长度之和必须等于值个数，出错时返回 nil
+func Decompress(dst []uint64, src []byte) ([]uint64, error) {
+	total, k := binary.Uvarint(src)
+	if k <= 0 {
+		return nil, fmt.Errorf("rle: invalid header")
+	}
+	src = src[k:]
+	n, k := binary.Uvarint(src)
+	// 每个游程至少占 2 字节，长度至少为 1
+	if k <= 0 || n > uint64(len(src)) || n > total {
+		return nil, fmt.Errorf("rle: invalid run count")
+	}
+	src = src[k:]
+	remaining := total
+	for i := uint64(0); i < n; i++ {
+		v, k1 := binary.Uvarint(src)
+		if k1 <= 0 {
+			return nil, fmt.Errorf("rle: truncated run %d", i)
+		}
+		count, k2 := binary.Uvarint(src[k1:])
+		if k2 <= 0 {
+			return nil, fmt.Errorf("rle: truncated run %d", i)
+		}
+		if count > remaining {
+			return nil, fmt.Errorf("rle: run %d exceeds %d values", i, total)
+		}
+		remaining -= count
+		src = src[k1+k2:]
+		for ; count > 0; count-- {
+			dst = append(dst, v)
+		}
+	}
+	if remaining != 0 {
+		return nil, fmt.Errorf("rle: runs cover %d values, expected %d", total-remaining, total)
+	}
+	return dst, nil
+}
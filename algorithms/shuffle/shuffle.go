@@ -0,0 +1,160 @@
+// Package shuffle 在通用字节压缩算法之前重排 float64 的字节或位，让符号、指数等高度重复的部分连续出现。
+// 组合名形如 "bss+zstd"、"bitshuffle+lz4"、"xor+bss+zstd"：可选的 xor 差分，一种重排方式，一个后端
+package shuffle
+
+import (
+	"fmt"
+	"math"
+	"myalgo/algorithms/brotli"
+	"myalgo/algorithms/lz4"
+	"myalgo/algorithms/snappy"
+	"myalgo/algorithms/xz"
+	"myalgo/algorithms/zstd"
+	"myalgo/common"
+	"strings"
+)
+
+// xorPrefix 组合名中表示先做 xor 差分的前缀
+const xorPrefix = "xor"
+
+// Transform 长度不变、原地修改的重排
+type Transform struct {
+	Name    string
+	Forward func([]float64) []float64
+	Inverse func([]float64) []float64
+}
+
+// Backend 通用字节压缩算法，通过整数接口传入小端序列化后的字节
+type Backend struct {
+	Name       string
+	Compress   func(dst []byte, src []uint64) []byte
+	Decompress func(dst []uint64, src []byte) ([]uint64, error)
+}
+
+var transforms = []Transform{
+	// Parquet BYTE_STREAM_SPLIT 式的按字节拆流，高位字节的流在前
+	{"bss", common.ByteTransposeArr, common.ByteTransposeRecover},
+	{"bitshuffle", BitShuffle, BitUnshuffle},
+}
+
+var backends = []Backend{
+	{"zstd", zstd.Compress, zstd.Decompress},
+	{"lz4", lz4.Compress, lz4.Decompress},
+	{"snappy", snappy.Compress, snappy.Decompress},
+	{"brotli", brotli.Compress, brotli.Decompress},
+	{"xz", xz.Compress, xz.Decompress},
+}
+
+// Codec 一个重排方式和后端的组合
+type Codec struct {
+	XorDelta  bool
+	Transform Transform
+	Backend   Backend
+}
+
+func (c Codec) Name() string {
+	name := c.Transform.Name + "+" + c.Backend.Name
+	if c.XorDelta {
+		name = xorPrefix + "+" + name
+	}
+	return name
+}
+
+// Parse 解析组合名
+func Parse(name string) (Codec, error) {
+	parts := strings.Split(name, "+")
+	var c Codec
+	if len(parts) == 3 && parts[0] == xorPrefix {
+		c.XorDelta = true
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		return Codec{}, fmt.Errorf("shuffle: invalid codec name %q", name)
+	}
+	var ok bool
+	if c.Transform, ok = findTransform(parts[0]); !ok {
+		return Codec{}, fmt.Errorf("shuffle: unknown transform %q", parts[0])
+	}
+	if c.Backend, ok = findBackend(parts[1]); !ok {
+		return Codec{}, fmt.Errorf("shuffle: unknown backend %q", parts[1])
+	}
+	return c, nil
+}
+
+// MustParse 同 Parse，名称无效时 panic
+func MustParse(name string) Codec {
+	c, err := Parse(name)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func findTransform(name string) (Transform, bool) {
+	for _, t := range transforms {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Transform{}, false
+}
+
+func findBackend(name string) (Backend, bool) {
+	for _, b := range backends {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Backend{}, false
+}
+
+// Codecs 所有重排方式、后端以及是否 xor 差分的组合
+func Codecs() []Codec {
+	var cs []Codec
+	for _, xorDelta := range []bool{false, true} {
+		for _, t := range transforms {
+			for _, b := range backends {
+				cs = append(cs, Codec{xorDelta, t, b})
+			}
+		}
+	}
+	return cs
+}
+
+// CompressFloat 空输入不输出任何字节
+func (c Codec) CompressFloat(dst []byte, src []float64) []byte {
+	if len(src) == 0 {
+		return dst
+	}
+	values := append([]float64(nil), src...)
+	if c.XorDelta {
+		values = common.XorDeltaArr(values)
+	}
+	values = c.Transform.Forward(values)
+	words := make([]uint64, len(values))
+	for i, v := range values {
+		words[i] = math.Float64bits(v)
+	}
+	// 部分后端不会追加到 dst
+	return append(dst, c.Backend.Compress(nil, words)...)
+}
+
+// DecompressFloat 还原 CompressFloat
+func (c Codec) DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	if len(src) == 0 {
+		return dst, nil
+	}
+	words, err := c.Backend.Decompress(nil, src)
+	if err != nil {
+		return nil, fmt.Errorf("shuffle: %s: %w", c.Name(), err)
+	}
+	values := make([]float64, len(words))
+	for i, w := range words {
+		values[i] = math.Float64frombits(w)
+	}
+	values = c.Transform.Inverse(values)
+	if c.XorDelta {
+		values = common.XorDeltaRecover(values)
+	}
+	return append(dst, values...), nil
+}
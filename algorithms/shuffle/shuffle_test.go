@@ -0,0 +1,86 @@
+package shuffle
+
+import (
+	"math"
+	"math/rand"
+	"myalgo/common"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// shuffleData n 个随机位模式，前几个值是 NaN、-0 和 Inf
+func shuffleData(n int) []float64 {
+	rng := rand.New(rand.NewSource(int64(n)))
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = math.Float64frombits(rng.Uint64())
+	}
+	copy(data, []float64{math.NaN(), math.Copysign(0, -1), math.Inf(-1)})
+	return data
+}
+
+// 不满 8 个值、恰好 8 个、多 1 个，以及 bitshuffle 块的边界
+var lengths = []int{0, 1, 7, 8, 9, bitshuffleBlock - 1, bitshuffleBlock, bitshuffleBlock + 1, 2*bitshuffleBlock + 7}
+
+// 每种重排、后端和是否 xor 差分的组合都能按位还原，压缩不修改 src
+func TestCodecs(t *testing.T) {
+	for _, c := range Codecs() {
+		for _, n := range lengths {
+			data := shuffleData(n)
+			saved := append([]float64(nil), data...)
+			codectest.RoundTripFloats(t, c.Name(), c.CompressFloat, c.DecompressFloat, data)
+			codectest.EqualFloats(t, c.Name()+" input", data, saved)
+		}
+	}
+}
+
+// 重排是可逆的置换：逆变换还原每个值，正变换不改变值个数
+func TestTransforms(t *testing.T) {
+	for _, tr := range transforms {
+		for _, n := range lengths {
+			data := shuffleData(n)
+			forward := tr.Forward(append([]float64(nil), data...))
+			if len(forward) != n {
+				t.Fatalf("%s: %d values after the forward transform, expected %d", tr.Name, len(forward), n)
+			}
+			codectest.EqualFloats(t, tr.Name, tr.Inverse(forward), data)
+		}
+	}
+}
+
+// 压缩结果就是后端对重排后的值的输出，可以直接用同一个后端解压
+func TestBackendStream(t *testing.T) {
+	for _, c := range Codecs() {
+		for _, n := range lengths[1:] {
+			data := shuffleData(n)
+			want := append([]float64(nil), data...)
+			if c.XorDelta {
+				want = common.XorDeltaArr(want)
+			}
+			want = c.Transform.Forward(want)
+			words, err := c.Backend.Decompress(nil, c.CompressFloat(nil, data))
+			if err != nil {
+				t.Fatalf("%s: %v", c.Name(), err)
+			}
+			got := make([]float64, len(words))
+			for i, w := range words {
+				got[i] = math.Float64frombits(w)
+			}
+			codectest.EqualFloats(t, c.Name(), got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range Codecs() {
+		parsed, err := Parse(c.Name())
+		if err != nil || parsed.Name() != c.Name() || parsed.XorDelta != c.XorDelta {
+			t.Errorf("Parse(%q) = %q, %v", c.Name(), parsed.Name(), err)
+		}
+	}
+	for _, name := range []string{"bss", "zstd+bss", "xor+zstd", "delta+bss+zstd", "bss+zstd+xor", "bss+gzip"} {
+		if _, err := Parse(name); err == nil {
+			t.Errorf("Parse(%q): expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package shuffle
+
+import "myalgo/common"
+
+// 字节和位的转置复用 common 中按位可逆的变换，都原地修改并返回 src
+
+// bitshuffleBlock bitshuffle 每块的值个数（8KB），与 Blosc 的默认块大小一致
+const bitshuffleBlock = 1024
+
+// BitShuffle Blosc 式的 bitshuffle：按块用 common.BitTransposeArr 转置位矩阵，块内依次输出所有值的同一位
+func BitShuffle(src []float64) []float64 {
+	return forEachBlock(src, common.BitTransposeArr)
+}
+
+// BitUnshuffle 还原 BitShuffle
+func BitUnshuffle(src []float64) []float64 {
+	return forEachBlock(src, common.BitTransposeRecover)
+}
+
+// forEachBlock 对每 bitshuffleBlock 个值调用 f，最后一块可以不满
+func forEachBlock(src []float64, f func([]float64) []float64) []float64 {
+	for start := 0; start < len(src); start += bitshuffleBlock {
+		f(src[start:min(start+bitshuffleBlock, len(src))])
+	}
+	return src
+}
@@ -1,80 +1,192 @@
+// Package simple8b 原生实现的 Simple-8b 整数编码。
+//
+// 每个 64 位字的高 4 位是选择子，低 60 位是数据：
+//   - 选择子 1~14：把若干个等宽的值打包进 60 位（60 个 1 位值 …… 1 个 60 位值）；
+//   - 选择子 0：游程，20 位重复次数加 40 位值；
+//   - 选择子 15：转义，低 60 位是紧随其后的原始 64 位字个数，用于 2^60 及以上的值。
 package simple8b
 
 import (
 	"encoding/binary"
 	"fmt"
+	"math/bits"
+)
+
+const (
+	selectorRLE    = 0
+	selectorEscape = 15
 
-	"github.com/influxdata/influxdb/pkg/encoding/simple8b"
+	payloadBits  = 60
+	rleCountBits = 20
+	rleValueBits = payloadBits - rleCountBits
+	maxRLECount  = 1<<rleCountBits - 1
 )
 
-// func Compress(dst []byte, src []uint64) []byte {
-// 	simple8bdst, _ := simple8b.EncodeAll(src)
-// 	fmt.Printf("simple8b.EncodeAll returned %d words\n", len(simple8bdst))
-// 	for _, val := range simple8bdst {
-// 		dst = common.Append64(dst, val)
-// 	}
-// 	return dst
-// }
+// packing 选择子 1~14 每个值的位宽和个数
+var packing = [15]struct{ width, count int }{
+	{},
+	{1, 60}, {2, 30}, {3, 20}, {4, 15}, {5, 12}, {6, 10}, {7, 8},
+	{8, 7}, {10, 6}, {12, 5}, {15, 4}, {20, 3}, {30, 2}, {60, 1},
+}
 
-// func Decompress(dst []uint64, src []byte) ([]uint64, error) {
+// Compress 不修改 src，编码结果按小端追加到 dst
+func Compress(dst []byte, src []uint64) []byte {
+	for i := 0; i < len(src); {
+		if src[i] >= 1<<payloadBits {
+			var n int
+			dst, n = appendEscape(dst, src[i:])
+			i += n
+			continue
+		}
+		// 游程比最窄的打包方式一个字能装下的还多时使用游程字
+		if src[i] < 1<<rleValueBits {
+			n := runLength(src[i:min(i+maxRLECount, len(src))])
+			if n > packing[selectorFor(src[i])].count {
+				dst = binary.LittleEndian.AppendUint64(dst, selectorRLE<<payloadBits|uint64(n)<<rleValueBits|src[i])
+				i += n
+				continue
+			}
+		}
+		word, n := pack(src[i:])
+		dst = binary.LittleEndian.AppendUint64(dst, word)
+		i += n
+	}
+	return dst
+}
 
-// 	var compressedByte []uint64
-// 	for i := 0; i < len(src); i += 8 {
-// 		compressedByte = append(compressedByte, binary.LittleEndian.Uint64(src[i:i+8]))
-// 	}
+// selectorFor 能容纳 v 的最小位宽对应的选择子
+func selectorFor(v uint64) int {
+	w := bits.Len64(v)
+	for s := 1; s < len(packing); s++ {
+		if packing[s].width >= w {
+			return s
+		}
+	}
+	return len(packing) - 1
+}
 
-//		num, err := simple8b.DecodeAll(dst, compressedByte)
-//		fmt.Println("simple8b.Decompress:", num)
-//		if err != nil {
-//			fmt.Println("simple8b Decompress failed:", err)
-//			return dst, err
-//		}
-//		return dst, nil
-//	}
+// runLength src 开头相同值的个数
+func runLength(src []uint64) int {
+	n := 1
+	for n < len(src) && src[n] == src[0] {
+		n++
+	}
+	return n
+}
 
-// 安全版本的压缩函数 - 不修改输入数组
-func Compress(dst []byte, src []uint64) []byte {
-	// 创建输入数组的副本，避免修改原始数组
-	srcCopy := make([]uint64, len(src))
-	copy(srcCopy, src)
+// pack 选出能装下 src 开头最多个值的选择子。值个数不足时改用容量更小的选择子，保证不需要填充
+func pack(src []uint64) (uint64, int) {
+	for s := 1; s < len(packing); s++ {
+		p := packing[s]
+		if p.count > len(src) {
+			continue
+		}
+		word, ok := uint64(s)<<payloadBits, true
+		for j, v := range src[:p.count] {
+			if bits.Len64(v) > p.width {
+				ok = false
+				break
+			}
+			word |= v << (j * p.width)
+		}
+		if ok {
+			return word, p.count
+		}
+	}
+	panic("simple8b: value does not fit in 60 bits")
+}
 
-	words, err := simple8b.EncodeAll(srcCopy)
-	if err != nil {
-		panic(fmt.Sprintf("simple8b.EncodeAll failed: %v", err))
+// appendEscape 写入转义字和之后连续的大值
+func appendEscape(dst []byte, src []uint64) ([]byte, int) {
+	n := 0
+	for n < len(src) && src[n] >= 1<<payloadBits {
+		n++
 	}
-	// fmt.Printf("simple8b.Compress: encoded %d values into %d words\n",
-	// 	len(src), len(words))
-	for _, w := range words {
-		buf := make([]byte, 8)
-		binary.LittleEndian.PutUint64(buf, w)
-		dst = append(dst, buf...)
+	dst = binary.LittleEndian.AppendUint64(dst, selectorEscape<<payloadBits|uint64(n))
+	for _, v := range src[:n] {
+		dst = binary.LittleEndian.AppendUint64(dst, v)
 	}
-	return dst
+	return dst, n
 }
 
+// Decompress 把解码结果追加到 dst，容量不足时自动扩容
 func Decompress(dst []uint64, src []byte) ([]uint64, error) {
 	if len(src)%8 != 0 {
-		return dst, fmt.Errorf("invalid src length: %d", len(src))
+		return dst, fmt.Errorf("simple8b: invalid src length: %d", len(src))
 	}
-
-	words := make([]uint64, len(src)/8)
 	for i := 0; i < len(src); i += 8 {
-		words[i/8] = binary.LittleEndian.Uint64(src[i : i+8])
+		word := binary.LittleEndian.Uint64(src[i:])
+		payload := word & (1<<payloadBits - 1)
+		switch s := word >> payloadBits; s {
+		case selectorRLE:
+			v := payload & (1<<rleValueBits - 1)
+			for n := payload >> rleValueBits; n > 0; n-- {
+				dst = append(dst, v)
+			}
+		case selectorEscape:
+			if payload > uint64(len(src)-i-8)/8 {
+				return dst, fmt.Errorf("simple8b: escape at byte %d needs %d words", i, payload)
+			}
+			for ; payload > 0; payload-- {
+				i += 8
+				dst = append(dst, binary.LittleEndian.Uint64(src[i:]))
+			}
+		default:
+			p := packing[s]
+			mask := uint64(1)<<p.width - 1
+			for j := 0; j < p.count; j++ {
+				dst = append(dst, payload>>(j*p.width)&mask)
+			}
+		}
 	}
+	return dst, nil
+}
 
-	// 使用临时缓冲区，避免直接修改dst
-	temp := make([]uint64, len(dst))
-	n, err := simple8b.DecodeAll(temp, words)
-	if err != nil {
-		return dst, fmt.Errorf("decode failed: %w", err)
+func zigzag(v int64) uint64   { return uint64(v<<1) ^ uint64(v>>63) }
+func unzigzag(u uint64) int64 { return int64(u>>1) ^ -int64(u&1) }
+
+// CompressSigned zigzag 编码后压缩有符号整数
+func CompressSigned(dst []byte, src []int64) []byte {
+	values := make([]uint64, len(src))
+	for i, v := range src {
+		values[i] = zigzag(v)
 	}
+	return Compress(dst, values)
+}
 
-	if n > len(dst) {
-		n = len(dst)
+// DecompressSigned 还原 CompressSigned
+func DecompressSigned(dst []int64, src []byte) ([]int64, error) {
+	values, err := Decompress(nil, src)
+	if err != nil {
+		return dst, err
 	}
-	copy(dst, temp[:n])
+	for _, u := range values {
+		dst = append(dst, unzigzag(u))
+	}
+	return dst, nil
+}
 
-	// fmt.Printf("simple8b.Decompress: decoded %d values\n", n)
+// CompressDelta 压缩相邻值之差的 zigzag 编码，适合单调或缓慢变化的序列（如时间戳）
+func CompressDelta(dst []byte, src []uint64) []byte {
+	deltas := make([]uint64, len(src))
+	prev := uint64(0)
+	for i, v := range src {
+		deltas[i] = zigzag(int64(v - prev))
+		prev = v
+	}
+	return Compress(dst, deltas)
+}
 
-	return dst[:n], nil
+// DecompressDelta 还原 CompressDelta
+func DecompressDelta(dst []uint64, src []byte) ([]uint64, error) {
+	deltas, err := Decompress(nil, src)
+	if err != nil {
+		return dst, err
+	}
+	prev := uint64(0)
+	for _, d := range deltas {
+		prev += uint64(unzigzag(d))
+		dst = append(dst, prev)
+	}
+	return dst, nil
 }
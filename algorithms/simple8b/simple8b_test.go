@@ -0,0 +1,172 @@
+package simple8b
+
+import (
+	"encoding/binary"
+	"math"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+// selectors 每个字的选择子，转义之后的原始字记为 -1
+func selectors(t *testing.T, out []byte) []int {
+	t.Helper()
+	var s []int
+	for i := 0; i < len(out); i += 8 {
+		word := binary.LittleEndian.Uint64(out[i:])
+		s = append(s, int(word>>payloadBits))
+		if word>>payloadBits == selectorEscape {
+			for n := word & (1<<payloadBits - 1); n > 0; n-- {
+				s = append(s, -1)
+				i += 8
+			}
+		}
+	}
+	return s
+}
+
+func equalSelectors(t *testing.T, name string, got []int, want ...int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: selectors %v, expected %v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: selectors %v, expected %v", name, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	for _, c := range codectest.Uint64s() {
+		codectest.RoundTripUint64s(t, c.Name, Compress, Decompress, c.Data)
+	}
+}
+
+// 每个选择子恰好装满 count 个最大宽度的值；再多 1 位就要换成更宽的选择子
+func TestPackingSelectors(t *testing.T) {
+	for s := 1; s < len(packing); s++ {
+		p := packing[s]
+		data := make([]uint64, p.count)
+		for i := range data {
+			data[i] = 1<<p.width - 1 - uint64(i%2)
+		}
+		out := codectest.RoundTripUint64s(t, "full width", Compress, Decompress, data)
+		equalSelectors(t, "full width", selectors(t, out), s)
+		if s == len(packing)-1 {
+			continue
+		}
+		data[p.count-1] = 1 << p.width
+		out = codectest.RoundTripUint64s(t, "one bit wider", Compress, Decompress, data)
+		if got := selectors(t, out); len(got) < 2 {
+			t.Errorf("selector %d: a %d-bit value still fits in one word: %v", s, p.width+1, got)
+		}
+	}
+}
+
+// 2^60-1 还能打包，2^60 及以上的连续值共用一个转义字
+func TestEscape(t *testing.T) {
+	data := []uint64{1<<payloadBits - 1, 1 << payloadBits, math.MaxUint64, 1<<payloadBits + 1, 5}
+	out := codectest.RoundTripUint64s(t, "escape", Compress, Decompress, data)
+	equalSelectors(t, "escape", selectors(t, out), 14, selectorEscape, -1, -1, -1, 14)
+
+	// 只有转义值，且位于末尾
+	out = codectest.RoundTripUint64s(t, "trailing escape", Compress, Decompress, []uint64{math.MaxUint64})
+	equalSelectors(t, "trailing escape", selectors(t, out), selectorEscape, -1)
+
+	// 转义字声明的原始字个数超出输入
+	corrupt := binary.LittleEndian.AppendUint64(nil, selectorEscape<<payloadBits|2)
+	corrupt = binary.LittleEndian.AppendUint64(corrupt, math.MaxUint64)
+	if _, err := Decompress(nil, corrupt); err == nil {
+		t.Error("expected an error for an escape past the end")
+	}
+}
+
+// 游程比最窄的打包方式多 1 个值时才用游程字，值必须小于 2^40，次数不超过 maxRLECount
+func TestRLEBoundaries(t *testing.T) {
+	repeat := func(v uint64, n int) []uint64 {
+		data := make([]uint64, n)
+		for i := range data {
+			data[i] = v
+		}
+		return data
+	}
+	cases := []struct {
+		name string
+		data []uint64
+		want []int
+	}{
+		{"60 zeros", repeat(0, 60), []int{1}},
+		{"61 zeros", repeat(0, 61), []int{selectorRLE}},
+		{"1 wide value", repeat(1<<rleValueBits-1, 1), []int{14}},
+		{"2 wide values", repeat(1<<rleValueBits-1, 2), []int{selectorRLE}},
+		{"value too wide for a run", repeat(1<<rleValueBits, 3), []int{14, 14, 14}},
+		{"longest run", repeat(7, maxRLECount), []int{selectorRLE}},
+		{"longest run plus one", repeat(7, maxRLECount+1), []int{selectorRLE, 14}},
+		{"two full runs", repeat(7, 2*maxRLECount), []int{selectorRLE, selectorRLE}},
+	}
+	for _, c := range cases {
+		out := codectest.RoundTripUint64s(t, c.name, Compress, Decompress, c.data)
+		equalSelectors(t, c.name, selectors(t, out), c.want...)
+	}
+}
+
+// 游程超过一个游程字的上限时拆成多个字
+func TestCompressRuns(t *testing.T) {
+	data := make([]uint64, 2*maxRLECount+1)
+	if out := Compress(nil, data); len(out) != 3*8 {
+		t.Errorf("%d zeros compressed into %d bytes, expected 3 words", len(data), len(out))
+	}
+}
+
+// dst 容量不足时扩容，已有内容保留
+func TestDecompressGrowsDst(t *testing.T) {
+	got, err := Decompress([]uint64{7}, Compress(nil, []uint64{1, 2, 3, 1 << 62}))
+	if err != nil || len(got) != 5 || got[0] != 7 || got[4] != 1<<62 {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
+
+// zigzag 后 ±2^29 附近跨过 30 位选择子，±2^59 附近跨过转义
+func TestCompressSigned(t *testing.T) {
+	cases := []struct {
+		name string
+		data []int64
+		want []int
+	}{
+		{"30 bits", []int64{-1 << 29, 1<<29 - 1}, []int{13}},
+		{"31 bits", []int64{1 << 29, 0}, []int{14, 14}},
+		{"60 bits", []int64{-1 << 59}, []int{14}},
+		{"61 bits", []int64{1 << 59}, []int{selectorEscape, -1}},
+		{"extremes", []int64{math.MinInt64, math.MaxInt64}, []int{selectorEscape, -1, -1}},
+		{"small", []int64{0, -1, 1, -2, 2, -3}, []int{9}},
+	}
+	for _, c := range cases {
+		out := CompressSigned(nil, c.data)
+		equalSelectors(t, c.name, selectors(t, out), c.want...)
+		got, err := DecompressSigned(nil, out)
+		if err != nil || len(got) != len(c.data) {
+			t.Fatalf("%s: got %v, %v", c.name, got, err)
+		}
+		for i := range c.data {
+			if got[i] != c.data[i] {
+				t.Errorf("%s: value %d: got %d, expected %d", c.name, i, got[i], c.data[i])
+			}
+		}
+	}
+}
+
+func TestCompressDelta(t *testing.T) {
+	cases := codectest.Uint64s()
+	// 回绕的计数器：差值恒为 3，越过 MaxUint64 也不变
+	counter := make([]uint64, 200)
+	for i := range counter {
+		counter[i] = math.MaxUint64 - 300 + uint64(i)*3
+	}
+	cases = append(cases, codectest.Uint64Case{Name: "wrapping counter", Data: counter})
+	for _, c := range cases {
+		codectest.RoundTripUint64s(t, c.Name, CompressDelta, DecompressDelta, c.Data)
+	}
+	if out := CompressDelta(nil, counter); len(out) != 2*8 {
+		t.Errorf("wrapping counter compressed into %d bytes, expected 2 words", len(out))
+	}
+}
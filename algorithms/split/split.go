@@ -0,0 +1,191 @@
+// Package split 用位运算把浮点数拆成三个整数流分别压缩，每个流从 registry 的整数算法中选输出最小的，选择按名称记录在头部。
+// split 依赖 registry，所以不在 registry 中注册。
+package split
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"myalgo/algorithms/registry"
+)
+
+// Mode 拆分方式
+type Mode byte
+
+const (
+	// IEEE 按 IEEE-754 字段拆成符号、指数和尾数
+	IEEE Mode = iota
+	// IntFrac 拆成符号和指数、整数部分（含隐含的 1）、小数部分的尾数位，分界由指数决定；
+	// 绝对值小于 1 或没有小数位的值整数部分为 0，整个尾数放在小数部分
+	IntFrac
+	// Auto 两种方式都试，取较小的
+	Auto
+)
+
+const mantissaMask = 1<<52 - 1
+
+// layouts 每种方式的拆分和合并，下标是 Mode
+var layouts = []struct {
+	split func(u uint64) [3]uint64
+	join  func(s [3]uint64) uint64
+}{
+	IEEE: {
+		func(u uint64) [3]uint64 { return [3]uint64{u >> 63, u >> 52 & 0x7ff, u & mantissaMask} },
+		func(s [3]uint64) uint64 { return s[0]&1<<63 | s[1]&0x7ff<<52 | s[2]&mantissaMask },
+	},
+	IntFrac: {
+		func(u uint64) [3]uint64 {
+			head, m := u>>52, u&mantissaMask
+			if e := int(head&0x7ff) - 1023; e >= 0 && e < 52 {
+				return [3]uint64{head, (1<<52 | m) >> (52 - e), m & (1<<(52-e) - 1)}
+			}
+			return [3]uint64{head, 0, m}
+		},
+		func(s [3]uint64) uint64 {
+			head := s[0] & 0xfff
+			if e := int(head&0x7ff) - 1023; e >= 0 && e < 52 {
+				return head<<52 | (s[1]&(1<<e-1))<<(52-e) | s[2]&(1<<(52-e)-1)
+			}
+			return head<<52 | s[2]&mantissaMask
+		},
+	},
+}
+
+// DefaultCodecs 默认的候选整数算法，跳过了大数据上很慢的算法
+var DefaultCodecs = []string{"simple8b", "pfor", "rle", "gorilla", "chimp", "zstd", "lz77", "rangeCodingAdaptive"}
+
+// Decomposer 拆分方式和每个流的候选算法
+type Decomposer struct {
+	Mode Mode
+	// Codecs 为空时使用 DefaultCodecs
+	Codecs []string
+}
+
+// CompressFloat 格式：uvarint 值个数，1 字节拆分方式，之后三个流依次为 uvarint 算法名长度、算法名、uvarint 数据长度、数据。
+// 没有值时只有值个数，Mode 无效时返回错误
+func (d Decomposer) CompressFloat(dst []byte, src []float64) ([]byte, error) {
+	if d.Mode > Auto {
+		return nil, fmt.Errorf("split: invalid mode %d", d.Mode)
+	}
+	if len(src) == 0 {
+		return binary.AppendUvarint(dst, 0), nil
+	}
+	if d.Mode == Auto {
+		ieee, _ := Decomposer{IEEE, d.Codecs}.CompressFloat(nil, src)
+		if intFrac, _ := (Decomposer{IntFrac, d.Codecs}).CompressFloat(nil, src); len(intFrac) < len(ieee) {
+			return append(dst, intFrac...), nil
+		}
+		return append(dst, ieee...), nil
+	}
+	codecs := d.Codecs
+	if len(codecs) == 0 {
+		codecs = DefaultCodecs
+	}
+	var streams [3][]uint64
+	for i := range streams {
+		streams[i] = make([]uint64, len(src))
+	}
+	for i, v := range src {
+		parts := layouts[d.Mode].split(math.Float64bits(v))
+		for k := range streams {
+			streams[k][i] = parts[k]
+		}
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	dst = append(dst, byte(d.Mode))
+	for _, s := range streams {
+		dst = appendStream(dst, s, codecs)
+	}
+	return dst, nil
+}
+
+// DecompressFloat 还原 CompressFloat，拆分方式和算法都从数据中读取，结果追加到 dst
+func (d Decomposer) DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	n, k := binary.Uvarint(src)
+	if k > 0 && n == 0 {
+		return dst, nil
+	}
+	if k <= 0 || len(src) <= k {
+		return nil, fmt.Errorf("split: invalid header")
+	}
+	mode := Mode(src[k])
+	if int(mode) >= len(layouts) {
+		return nil, fmt.Errorf("split: invalid mode %d", mode)
+	}
+	src = src[k+1:]
+	var streams [3][]uint64
+	for i := range streams {
+		var err error
+		if streams[i], src, err = readStream(src, n); err != nil {
+			return nil, fmt.Errorf("split: stream %d: %w", i, err)
+		}
+	}
+	for i := uint64(0); i < n; i++ {
+		dst = append(dst, math.Float64frombits(layouts[mode].join([3]uint64{streams[0][i], streams[1][i], streams[2][i]})))
+	}
+	return dst, nil
+}
+
+// appendStream 在 codecs 中选择能还原且输出最小的算法，都不可用时退回 zstd
+func appendStream(dst []byte, values []uint64, codecs []string) []byte {
+	var bestName string
+	var best []byte
+	for _, name := range codecs {
+		codec, ok := registry.Integer(name)
+		if !ok || !codec.Supports(values) {
+			continue
+		}
+		if out, ok := codec.RoundTrip(values); ok && (best == nil || len(out) < len(best)) {
+			bestName, best = name, out
+		}
+	}
+	if best == nil {
+		codec, _ := registry.Integer("zstd")
+		bestName, best = codec.Name, codec.Compress(nil, values)
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(bestName)))
+	dst = append(dst, bestName...)
+	dst = binary.AppendUvarint(dst, uint64(len(best)))
+	return append(dst, best...)
+}
+
+// readStream 读取一个流，返回解压的值和剩余数据
+func readStream(src []byte, n uint64) ([]uint64, []byte, error) {
+	size, k := binary.Uvarint(src)
+	if k <= 0 || size > uint64(len(src)-k) {
+		return nil, nil, fmt.Errorf("invalid codec name")
+	}
+	name := string(src[k : k+int(size)])
+	src = src[k+int(size):]
+	size, k = binary.Uvarint(src)
+	if k <= 0 || size > uint64(len(src)-k) {
+		return nil, nil, fmt.Errorf("invalid %s payload length", name)
+	}
+	payload := src[k : k+int(size)]
+	codec, ok := registry.Integer(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown integer codec %q", name)
+	}
+	// gorilla 等算法解压时会改写输入，解压副本
+	values, err := codec.Decompress(nil, append([]byte(nil), payload...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if uint64(len(values)) != n {
+		return nil, nil, fmt.Errorf("%s decoded %d values, expected %d", name, len(values), n)
+	}
+	return values, src[k+int(size):], nil
+}
+
+// CompressFloat 以 Auto 方式压缩，Auto 总是有效的
+func CompressFloat(dst []byte, src []float64) []byte {
+	out, err := Decomposer{Mode: Auto}.CompressFloat(dst, src)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
+	return Decomposer{}.DecompressFloat(dst, src)
+}
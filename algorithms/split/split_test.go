@@ -0,0 +1,105 @@
+package split
+
+import (
+	"math"
+	"math/rand"
+	"myalgo/internal/codectest"
+	"testing"
+)
+
+func TestCompressFloat(t *testing.T) {
+	for _, mode := range []Mode{IEEE, IntFrac, Auto} {
+		d := Decomposer{Mode: mode}
+		compress := func(dst []byte, src []float64) []byte {
+			out, err := d.CompressFloat(dst, src)
+			if err != nil {
+				t.Fatalf("mode %d: %v", mode, err)
+			}
+			return out
+		}
+		for _, c := range codectest.Floats() {
+			codectest.RoundTripFloats(t, c.Name, compress, DecompressFloat, c.Data)
+		}
+	}
+}
+
+// withExponent 指数为 e、尾数随机、符号交替的值
+func withExponent(rng *rand.Rand, e, n int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		u := uint64(e+1023)<<52 | rng.Uint64()&mantissaMask | uint64(i%2)<<63
+		data[i] = math.Float64frombits(u)
+	}
+	return data
+}
+
+// IntFrac 的分界：指数 0 时整数部分只有隐含的 1，51 时小数部分只剩 1 位，52 及以上没有小数位，整个尾数放在小数部分
+func TestIntFracExponents(t *testing.T) {
+	cases := []struct {
+		v    float64
+		want [3]uint64
+	}{
+		{1.5, [3]uint64{1023, 1, 1 << 51}},
+		{-1, [3]uint64{1<<11 | 1023, 1, 0}},
+		{1<<51 + 0.5, [3]uint64{1023 + 51, 1 << 51, 1}},
+		{1<<52 - 0.5, [3]uint64{1023 + 51, 1<<52 - 1, 1}},
+		{1 << 52, [3]uint64{1023 + 52, 0, 0}},
+		{1<<52 + 1, [3]uint64{1023 + 52, 0, 1}},
+		{0.75, [3]uint64{1022, 0, 1 << 51}},
+	}
+	intFrac := layouts[IntFrac]
+	for _, c := range cases {
+		u := math.Float64bits(c.v)
+		if got := intFrac.split(u); got != c.want {
+			t.Errorf("split(%v) = %#x, expected %#x", c.v, got, c.want)
+		}
+		if got := intFrac.join(intFrac.split(u)); got != u {
+			t.Errorf("join(split(%v)) = %#x, expected %#x", c.v, got, u)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	d := Decomposer{Mode: IntFrac}
+	for _, e := range []int{-1, 0, 1, 50, 51, 52, 53} {
+		data := withExponent(rng, e, 500)
+		for i := range data {
+			if got := intFrac.join(intFrac.split(math.Float64bits(data[i]))); got != math.Float64bits(data[i]) {
+				t.Fatalf("exponent %d: join(split(%#x)) = %#x", e, math.Float64bits(data[i]), got)
+			}
+		}
+		out, err := d.CompressFloat(nil, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecompressFloat(nil, out)
+		if err != nil {
+			t.Fatalf("exponent %d: %v", e, err)
+		}
+		codectest.EqualFloats(t, "exponent", got, data)
+	}
+}
+
+// 整数值的小数部分全为 0，IntFrac 比 IEEE 的尾数流小
+func TestIntFracIntegers(t *testing.T) {
+	data := make([]float64, 1000)
+	for i := range data {
+		data[i] = float64(i*7919%100000 + 1)
+	}
+	ieee, err := Decomposer{Mode: IEEE}.CompressFloat(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intFrac, err := Decomposer{Mode: IntFrac}.CompressFloat(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intFrac) >= len(ieee) {
+		t.Errorf("IntFrac %d bytes, IEEE %d bytes", len(intFrac), len(ieee))
+	}
+}
+
+func TestInvalidMode(t *testing.T) {
+	if _, err := (Decomposer{Mode: Auto + 1}).CompressFloat(nil, []float64{1.5}); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+}
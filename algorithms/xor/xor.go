@@ -12,37 +12,14 @@ import (
 // 例如: 3.25 = 11.01(二进制) = 0.1101 * 2^2
 // 返回: 尾数部分(0.8125), 指数部分(2)
 func splitFloat(f float64) (mantissa float64, exponent uint64) {
-	// 处理特殊情况
-	if f == 0 {
-		return 0, 0
+	// 小于1的数字（包括0）不需要移位
+	if math.Abs(f) < 1.0 {
+		return f, 0
 	}
 
-	// 获取符号
-	sign := 1.0
-	if f < 0 {
-		sign = -1.0
-		f = -f
-	}
-
-	// 如果数字已经小于1，不需要移位
-	if f < 1.0 {
-		return sign * f, 0
-	}
-
-	// 计算需要右移的位数（即指数）
-	exponent = 0
-	mantissa = f
-
-	// 将数字归一化到[0.5, 1)区间
-	for mantissa >= 1.0 {
-		mantissa /= 2.0
-		exponent++
-	}
-
-	// 恢复符号
-	mantissa *= sign
-
-	return mantissa, exponent
+	// 直接从位模式取出指数，尾数归一化到[0.5, 1)区间，符号保留在尾数上
+	m, e := math.Frexp(f)
+	return m, uint64(e)
 }
 
 // splitFloatArray 对数组中的每个浮点数进行分解
@@ -169,7 +146,7 @@ func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
 	}
 
 	// 为simple8b解压缩预分配空间
-	exponentUint64 := make([]uint64, originalLength+240)
+	exponentUint64 := make([]uint64, 0, originalLength)
 	exponentUint64, err = simple8b.Decompress(exponentUint64, exponentData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompress exponents: %w", err)
@@ -185,11 +162,7 @@ func DecompressFloat(dst []float64, src []byte) ([]float64, error) {
 	result := make([]float64, originalLength)
 	for i := 0; i < int(originalLength); i++ {
 		// 重建: 原始值 = mantissa * 2^exponent
-		if exponentUint64[i] == 0 {
-			result[i] = mantissas[i]
-		} else {
-			result[i] = mantissas[i] * math.Pow(2, float64(exponentUint64[i]))
-		}
+		result[i] = math.Ldexp(mantissas[i], int(exponentUint64[i]))
 	}
 
 	return result, nil
@@ -346,14 +346,9 @@ func AnalyzeTimeSeries(src []float64) *TimeSeriesStats {
 
 	// 熵计算 - 填充直方图
 	if histMin != histMax && validCount > 0 {
-		binWidth := (histMax - histMin) / float64(bins)
 		for _, v := range src {
 			if !math.IsNaN(v) && !math.IsInf(v, 0) {
-				binIdx := int((v - histMin) / binWidth)
-				if binIdx >= bins {
-					binIdx = bins - 1
-				}
-				hist[binIdx]++
+				hist[histBin(v, histMin, histMax, bins)]++
 			}
 		}
 
@@ -785,15 +780,10 @@ func computeEntropy(src []float64) float64 {
 
 	// 创建直方图
 	hist := make([]int, bins)
-	binWidth := (max - min) / float64(bins)
 
 	for _, v := range src {
 		if !math.IsNaN(v) && !math.IsInf(v, 0) {
-			binIdx := int((v - min) / binWidth)
-			if binIdx >= bins {
-				binIdx = bins - 1
-			}
-			hist[binIdx]++
+			hist[histBin(v, min, max, bins)]++
 		}
 	}
 
@@ -947,3 +937,16 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// histBin 返回 v 在 [lo, hi] 等分为 bins 个区间后所在的下标。
+// 先减半再相减，值域超过 MaxFloat64 时不会溢出；值域小到减半后下溢时归入两端
+func histBin(v, lo, hi float64, bins int) int {
+	frac := (v/2 - lo/2) / (hi/2 - lo/2)
+	if !(frac > 0) {
+		return 0
+	}
+	if frac >= 1 {
+		return bins - 1
+	}
+	return int(frac * float64(bins))
+}
@@ -0,0 +1,285 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 本文件中的变换都按位可逆：恢复后的值与原值的 Float64bits 完全一致（包括 -0、NaN 和 Inf）。
+// 无法精确表示的值作为异常单独记录在 side 数据中。
+
+// maxExactInt float64 可以精确表示的最大整数
+const maxExactInt = 1 << 53
+
+// maxDecimalExp 十进制缩放尝试的最大指数，10^22 以内的 10 的幂都能被 float64 精确表示
+const maxDecimalExp = 15
+
+var pow10 = func() []float64 {
+	p := make([]float64, maxDecimalExp+1)
+	p[0] = 1
+	for i := 1; i < len(p); i++ {
+		p[i] = p[i-1] * 10
+	}
+	return p
+}()
+
+// isExactInt 判断 v 是否为可精确表示的整数（-0 不算，因为转换为 int64 会丢失符号）
+func isExactInt(v float64) bool {
+	return v == math.Trunc(v) && math.Abs(v) <= maxExactInt && !(v == 0 && math.Signbit(v))
+}
+
+// appendExceptions 写入异常个数，以及每个异常的下标增量和原始位模式
+func appendExceptions(dst []byte, idx []int, bits []uint64) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(idx)))
+	prev := 0
+	for i, k := range idx {
+		dst = binary.AppendUvarint(dst, uint64(k-prev))
+		dst = binary.LittleEndian.AppendUint64(dst, bits[i])
+		prev = k
+	}
+	return dst
+}
+
+// readExceptions 读取 appendExceptions 写入的异常，n 为数据长度
+func readExceptions(side []byte, n int) ([]int, []uint64, error) {
+	count, off := binary.Uvarint(side)
+	if off <= 0 || count > uint64(n) {
+		return nil, nil, fmt.Errorf("invalid exception count")
+	}
+	idx := make([]int, count)
+	bits := make([]uint64, count)
+	prev := uint64(0)
+	for i := range idx {
+		gap, m := binary.Uvarint(side[off:])
+		if m <= 0 || len(side) < off+m+8 {
+			return nil, nil, fmt.Errorf("exception %d is truncated", i)
+		}
+		off += m
+		prev += gap
+		if prev >= uint64(n) {
+			return nil, nil, fmt.Errorf("exception index %d out of range", prev)
+		}
+		idx[i] = int(prev)
+		bits[i] = binary.LittleEndian.Uint64(side[off:])
+		off += 8
+	}
+	return idx, bits, nil
+}
+
+// decimalValue 以 10^e 缩放 v，能精确还原时返回整数值
+func decimalValue(v float64, e int) (float64, bool) {
+	k := math.Round(v * pow10[e])
+	if math.IsNaN(k) || math.Abs(k) > maxExactInt || math.Float64bits(k/pow10[e]) != math.Float64bits(v) {
+		return 0, false
+	}
+	return k, true
+}
+
+// DecimalScaleInt 以 10^e 缩放 v，DecimalUnscaleInt(k, e) 能按位还原 v 时返回整数 k
+func DecimalScaleInt(v float64, e int) (int64, bool) {
+	if e < 0 || e > maxDecimalExp {
+		return 0, false
+	}
+	k, ok := decimalValue(v, e)
+	if !ok || (k == 0 && math.Signbit(k)) {
+		return 0, false
+	}
+	return int64(k), true
+}
+
+// DecimalUnscaleInt 还原 DecimalScaleInt
+func DecimalUnscaleInt(k int64, e int) float64 {
+	return float64(k) / pow10[e]
+}
+
+// DecimalScaleArr 选择异常最少的十进制指数 e，将数据精确缩放为整数 v*10^e。
+// side 记录 e 和无法精确缩放的值
+func DecimalScaleArr(src []float64) ([]float64, []byte) {
+	bestExp, bestMiss := 0, len(src)+1
+	for e := 0; e <= maxDecimalExp && bestMiss > 0; e++ {
+		miss := 0
+		for _, v := range src {
+			if _, ok := decimalValue(v, e); !ok {
+				miss++
+				if miss >= bestMiss {
+					break
+				}
+			}
+		}
+		if miss < bestMiss {
+			bestExp, bestMiss = e, miss
+		}
+	}
+	var idx []int
+	var bits []uint64
+	for i, v := range src {
+		k, ok := decimalValue(v, bestExp)
+		if !ok {
+			idx = append(idx, i)
+			bits = append(bits, math.Float64bits(v))
+		}
+		src[i] = k
+	}
+	side := appendExceptions([]byte{byte(bestExp)}, idx, bits)
+	return src, side
+}
+
+// DecimalScaleRecover 还原 DecimalScaleArr
+func DecimalScaleRecover(src []float64, side []byte) ([]float64, error) {
+	if len(side) < 1 || int(side[0]) > maxDecimalExp {
+		return nil, fmt.Errorf("decimal: invalid side data")
+	}
+	idx, bits, err := readExceptions(side[1:], len(src))
+	if err != nil {
+		return nil, fmt.Errorf("decimal: %w", err)
+	}
+	p := pow10[side[0]]
+	for i := range src {
+		src[i] /= p
+	}
+	for i, k := range idx {
+		src[k] = math.Float64frombits(bits[i])
+	}
+	return src, nil
+}
+
+// IntDeltaArr 对整数值做精确差分（通常在 DecimalScaleArr 之后使用），
+// 非整数值或差值超出 float64 精确范围时作为异常，输出 0
+func IntDeltaArr(src []float64) ([]float64, []byte) {
+	var idx []int
+	var bits []uint64
+	prev := int64(0)
+	for i, v := range src {
+		if !isExactInt(v) {
+			idx = append(idx, i)
+			bits = append(bits, math.Float64bits(v))
+			src[i] = 0
+			prev = 0
+			continue
+		}
+		cur := int64(v)
+		d := cur - prev
+		prev = cur
+		if d > maxExactInt || d < -maxExactInt {
+			idx = append(idx, i)
+			bits = append(bits, math.Float64bits(v))
+			src[i] = 0
+			continue
+		}
+		src[i] = float64(d)
+	}
+	return src, appendExceptions(nil, idx, bits)
+}
+
+// IntDeltaRecover 还原 IntDeltaArr
+func IntDeltaRecover(src []float64, side []byte) ([]float64, error) {
+	idx, bits, err := readExceptions(side, len(src))
+	if err != nil {
+		return nil, fmt.Errorf("intDelta: %w", err)
+	}
+	prev := int64(0)
+	next := 0
+	for i, d := range src {
+		if next < len(idx) && idx[next] == i {
+			src[i] = math.Float64frombits(bits[next])
+			next++
+		} else {
+			src[i] = float64(prev + int64(d))
+		}
+		if isExactInt(src[i]) {
+			prev = int64(src[i])
+		} else {
+			prev = 0
+		}
+	}
+	return src, nil
+}
+
+// XorDeltaArr 相邻值的位模式做异或，输出为任意位模式（可能是 NaN），只能配合按位精确的压缩器
+func XorDeltaArr(src []float64) []float64 {
+	for i := len(src) - 1; i > 0; i-- {
+		src[i] = math.Float64frombits(math.Float64bits(src[i]) ^ math.Float64bits(src[i-1]))
+	}
+	return src
+}
+
+// XorDeltaRecover 还原 XorDeltaArr
+func XorDeltaRecover(src []float64) []float64 {
+	for i := 1; i < len(src); i++ {
+		src[i] = math.Float64frombits(math.Float64bits(src[i]) ^ math.Float64bits(src[i-1]))
+	}
+	return src
+}
+
+// ByteTransposeArr 按字节转置：先输出所有值的第 7 字节（符号和指数），再输出第 6 字节，依此类推
+func ByteTransposeArr(src []float64) []float64 {
+	n := len(src)
+	buf := make([]byte, 8*n)
+	for i, v := range src {
+		bits := math.Float64bits(v)
+		for b := 0; b < 8; b++ {
+			buf[(7-b)*n+i] = byte(bits >> (8 * b))
+		}
+	}
+	for i := range src {
+		src[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:]))
+	}
+	return src
+}
+
+// ByteTransposeRecover 还原 ByteTransposeArr
+func ByteTransposeRecover(src []float64) []float64 {
+	n := len(src)
+	buf := make([]byte, 8*n)
+	for i, v := range src {
+		binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+	}
+	for i := range src {
+		var bits uint64
+		for b := 0; b < 8; b++ {
+			bits |= uint64(buf[(7-b)*n+i]) << (8 * b)
+		}
+		src[i] = math.Float64frombits(bits)
+	}
+	return src
+}
+
+// BitTransposeArr 按位转置：从最高位开始，依次输出所有值的同一位
+func BitTransposeArr(src []float64) []float64 {
+	n := len(src)
+	out := make([]uint64, n)
+	for i, v := range src {
+		bits := math.Float64bits(v)
+		for p := 0; p < 64; p++ {
+			if bits>>(63-p)&1 == 1 {
+				pos := p*n + i
+				out[pos/64] |= 1 << (pos % 64)
+			}
+		}
+	}
+	for i := range src {
+		src[i] = math.Float64frombits(out[i])
+	}
+	return src
+}
+
+// BitTransposeRecover 还原 BitTransposeArr
+func BitTransposeRecover(src []float64) []float64 {
+	n := len(src)
+	in := make([]uint64, n)
+	for i, v := range src {
+		in[i] = math.Float64bits(v)
+	}
+	for i := range src {
+		var bits uint64
+		for p := 0; p < 64; p++ {
+			pos := p*n + i
+			if in[pos/64]>>(pos%64)&1 == 1 {
+				bits |= 1 << (63 - p)
+			}
+		}
+		src[i] = math.Float64frombits(bits)
+	}
+	return src
+}
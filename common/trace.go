@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// 压缩流水线阶段名称
+const (
+	StageConstraints = "constraints" // 约束检测/使用
+	StageTransform   = "transform"   // 预处理变换
+	StageBackend     = "backend"     // 后端压缩器
+	StageSelect      = "select"      // 参数/算法选择
+)
+
+// TraceEvent 流水线中一个阶段的执行记录
+type TraceEvent struct {
+	Stage    string // 阶段，取值见 Stage* 常量
+	Name     string // 具体步骤名称，如 "precision"、"zstd"
+	Detail   string // 附加说明（选择原因、参数等）
+	InBytes  int    // 阶段输入字节数，未知时为 0
+	OutBytes int    // 阶段输出字节数，未知时为 0
+}
+
+// Tracer 接收流水线各阶段的事件，默认不输出任何内容
+type Tracer interface {
+	Trace(ev TraceEvent)
+}
+
+// TracerFunc 允许普通函数作为 Tracer 使用
+type TracerFunc func(ev TraceEvent)
+
+func (f TracerFunc) Trace(ev TraceEvent) { f(ev) }
+
+type nopTracer struct{}
+
+func (nopTracer) Trace(TraceEvent) {}
+
+// NopTracer 静默 Tracer
+var NopTracer Tracer = nopTracer{}
+
+// OrNop 在 t 为 nil 时返回 NopTracer，方便调用方直接传入可选的 Tracer
+func OrNop(t Tracer) Tracer {
+	if t == nil {
+		return NopTracer
+	}
+	return t
+}
+
+// WriterTracer 将事件逐行写入 io.Writer，用于 CLI 的 explain 输出
+type WriterTracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterTracer 创建写入 w 的 Tracer
+func NewWriterTracer(w io.Writer) *WriterTracer {
+	return &WriterTracer{w: w}
+}
+
+func (wt *WriterTracer) Trace(ev TraceEvent) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	fmt.Fprintf(wt.w, "[%-11s] %-14s", ev.Stage, ev.Name)
+	if ev.InBytes > 0 || ev.OutBytes > 0 {
+		fmt.Fprintf(wt.w, " %10d -> %-10d", ev.InBytes, ev.OutBytes)
+		if ev.InBytes > 0 && ev.OutBytes > 0 {
+			fmt.Fprintf(wt.w, " (%.3fx)", float64(ev.InBytes)/float64(ev.OutBytes))
+		}
+	}
+	if ev.Detail != "" {
+		fmt.Fprintf(wt.w, " %s", ev.Detail)
+	}
+	fmt.Fprintln(wt.w)
+}
+
+// TraceRecorder 在内存中收集事件，便于测试或事后汇总
+type TraceRecorder struct {
+	mu     sync.Mutex
+	Events []TraceEvent
+}
+
+func (r *TraceRecorder) Trace(ev TraceEvent) {
+	r.mu.Lock()
+	r.Events = append(r.Events, ev)
+	r.mu.Unlock()
+}
+
+// PackedBytes 把 values 按其中最大的有效位宽打包后的字节数，变换阶段用它报告输出实际携带的信息量
+func PackedBytes(values []uint64) int {
+	var or uint64
+	for _, v := range values {
+		or |= v
+	}
+	return (len(values)*bits.Len64(or) + 7) / 8
+}
+
+// PackedFloatBytes 按位模式计算的 PackedBytes
+func PackedFloatBytes(values []float64) int {
+	var or uint64
+	for _, v := range values {
+		or |= math.Float64bits(v)
+	}
+	return (len(values)*bits.Len64(or) + 7) / 8
+}
@@ -3,9 +3,12 @@ module myalgo
 go 1.23.0
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/bkaradzic/go-lz4 v1.0.0
+	github.com/icza/huffman v0.0.0-20230330133829-d543610fbdd2
 	github.com/jwilder/encoding v0.0.0-20170811194829-b4e1701a28ef
 	github.com/klauspost/compress v1.17.8
+	github.com/ulikunitz/xz v0.5.15
 	github.com/valyala/gozstd v1.23.0
 	gonum.org/v1/plot v0.16.0
 )
@@ -16,17 +19,13 @@ require (
 	codeberg.org/go-pdf/fpdf v0.10.0 // indirect
 	git.sr.ht/~sbinet/gg v0.6.0 // indirect
 	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
-	github.com/andybalholm/brotli v1.2.0 // indirect
 	github.com/campoy/embedmd v1.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/icza/bitio v1.1.0 // indirect
-	github.com/icza/huffman v0.0.0-20230330133829-d543610fbdd2 // indirect
-	github.com/influxdata/influxdb v1.12.2 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spenczar/fpc v1.0.0 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
-	github.com/ulikunitz/xz v0.5.15 // indirect
 	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
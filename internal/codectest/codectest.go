@@ -0,0 +1,122 @@
+// Package codectest 各压缩算法测试共用的通用数据集和按位还原校验，算法自己的边界情况留在各包的测试中
+package codectest
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// FloatCase 一组测试数据
+type FloatCase struct {
+	Name string
+	Data []float64
+}
+
+// Floats 通用数据集：空输入、单个值、特殊值、非规格化数、随机位模式和两位小数的平滑数据
+func Floats() []FloatCase {
+	rng := rand.New(rand.NewSource(1))
+	subnormal := make([]float64, 200)
+	for i := range subnormal {
+		subnormal[i] = math.Float64frombits(uint64(rng.Int63n(1 << 52)))
+	}
+	random := make([]float64, 1000)
+	for i := range random {
+		random[i] = math.Float64frombits(rng.Uint64())
+	}
+	smooth := make([]float64, 1000)
+	for i := range smooth {
+		smooth[i] = math.Round((20+math.Sin(float64(i)/10))*100) / 100
+	}
+	return []FloatCase{
+		{"empty", nil},
+		{"one", []float64{3.14}},
+		{"special", []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1), 0, 1.5, math.MaxFloat64, -math.SmallestNonzeroFloat64}},
+		{"subnormal", subnormal},
+		{"random", random},
+		{"smooth", smooth},
+	}
+}
+
+// Uint64Case 一组整数测试数据
+type Uint64Case struct {
+	Name string
+	Data []uint64
+}
+
+// Uint64s Floats 的位模式，再加上 0 和最大值
+func Uint64s() []Uint64Case {
+	var cases []Uint64Case
+	for _, c := range Floats() {
+		data := make([]uint64, len(c.Data))
+		for i, v := range c.Data {
+			data[i] = math.Float64bits(v)
+		}
+		cases = append(cases, Uint64Case{c.Name, data})
+	}
+	return append(cases, Uint64Case{"extremes", []uint64{0, math.MaxUint64, 0, 1, math.MaxUint64 - 1}})
+}
+
+// EqualFloats 按位比较，NaN 的载荷和 -0 也必须一致
+func EqualFloats(t testing.TB, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d values, expected %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.Float64bits(got[i]) != math.Float64bits(want[i]) {
+			t.Fatalf("%s: value %d: got %v (%#x), expected %v (%#x)", name, i, got[i], math.Float64bits(got[i]), want[i], math.Float64bits(want[i]))
+		}
+	}
+}
+
+// EqualUint64s 逐个比较
+func EqualUint64s(t testing.TB, name string, got, want []uint64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d values, expected %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: value %d: got %#x, expected %#x", name, i, got[i], want[i])
+		}
+	}
+}
+
+// RoundTripFloats 压缩 data 并校验能按位还原，返回压缩结果。解压两次，第二次检查解压没有改写输入
+func RoundTripFloats(t testing.TB, name string, compress func([]byte, []float64) []byte,
+	decompress func([]float64, []byte) ([]float64, error), data []float64) []byte {
+	t.Helper()
+	out := compress(nil, data)
+	saved := append([]byte(nil), out...)
+	for pass := 0; pass < 2; pass++ {
+		got, err := decompress(nil, out)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		EqualFloats(t, name, got, data)
+	}
+	if string(out) != string(saved) {
+		t.Fatalf("%s: decompression modified its input", name)
+	}
+	return out
+}
+
+// RoundTripUint64s 整数版本的 RoundTripFloats
+func RoundTripUint64s(t testing.TB, name string, compress func([]byte, []uint64) []byte,
+	decompress func([]uint64, []byte) ([]uint64, error), data []uint64) []byte {
+	t.Helper()
+	out := compress(nil, data)
+	saved := append([]byte(nil), out...)
+	for pass := 0; pass < 2; pass++ {
+		got, err := decompress(nil, out)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		EqualUint64s(t, name, got, data)
+	}
+	if string(out) != string(saved) {
+		t.Fatalf("%s: decompression modified its input", name)
+	}
+	return out
+}
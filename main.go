@@ -1,16 +1,76 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"myalgo/algorithms/model"
+	"myalgo/algorithms/myal"
 	"myalgo/algorithms/numerical"
 	"myalgo/common"
 )
 
 func main() {
 	// values, strings, _ := common.ReadDataFromFileWithStrings("./dataset/test/POI-lat.csv", 100, 0, 0) //16
-	values, strings, _ := common.ReadDataFromFileWithStrings("./dataset/test/Stocks-DE.csv", 100, 0, 0) //3
 	// values, strings, _ := common.ReadDataFromFileWithStrings("./dataset/test/SSD-bench.csv", 100, 0, 0) // 1
 	// values, strings, _ := common.ReadDataFromFileWithStrings("./dataset/test/Air-pressure.csv", 100, 0, 0) //5
 	// values, strings, _ := common.ReadDataFromFileWithStrings("./dataset/test/Basel-temp.csv", 100, 0, 0) //10
+	file := flag.String("file", "./dataset/test/Stocks-DE.csv", "CSV 数据文件")
+	limit := flag.Int("n", 100, "读取的数值个数")
+	column := flag.Int("column", 0, "数值所在列")
+	explain := flag.Bool("explain", false, "输出压缩流水线每个阶段的约束、变换、字节数和后端")
+	backend := flag.String("backend", numerical.BackendZstd, "explain 模式使用的后端: zstd|lz4|snappy|brotli|xz|pfor")
+	train := flag.String("train", "", "用读取的数据训练选择器并导出到该路径（如 selector.json）")
+	version := flag.Uint("version", 1, "训练导出的模型版本号")
+	pareto := flag.Bool("pareto", false, "实测所有参数组合，输出 Pareto 前沿和满足解压速度约束的最优组合")
+	minDecompress := flag.Float64("min-decompress", 0, "pareto 模式下的最低解压速度 (MB/s)")
+	eval := flag.String("eval", "", "在读取的数据上评估选择器，并将报告写入该目录")
+	selectorPath := flag.String("selector", "", "选择器模型文件，eval 模式必须指定，其他模式下作为 model 的默认选择器")
+	infer := flag.Bool("infer", false, "推断 myal 语义信息并输出压缩结果")
+	flag.Parse()
+	// 在转换为 uint16 之前检查，避免 65537 这样的值被截断成合法版本
+	if *train != "" && (*version < 1 || *version > uint(model.SamplingSelectorVersion-1)) {
+		log.Fatalf("-version 必须在 1-%d 之间，%d 留给采样选择器", model.SamplingSelectorVersion-1, model.SamplingSelectorVersion)
+	}
+	if *selectorPath != "" && *eval == "" {
+		if err := model.SetDefaultSelector(*selectorPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// 训练至少需要若干 segmentLen 长度的段，没有显式指定 -n 时读取足够的数据
+	limitSet := false
+	flag.Visit(func(f *flag.Flag) { limitSet = limitSet || f.Name == "n" })
+	if *train != "" && !limitSet {
+		*limit = model.DefaultTrainingValues(model.DefaultTrainOptions())
+	}
+	values, strings, err := common.ReadDataFromFileWithStrings(*file, *limit, 0, *column)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *explain {
+		explainCompression(values, *backend)
+		return
+	}
+	if *pareto {
+		reportPareto(values, model.Objective{MinDecompressSpeed: *minDecompress})
+		return
+	}
+	if *eval != "" {
+		evaluateSelector(values, *selectorPath, *eval)
+		return
+	}
+	if *infer {
+		inferSemantics(values)
+		return
+	}
+	if *train != "" {
+		trainSelector(values, *train, uint16(*version))
+		return
+	}
 
 	// 检测约束
 	nc := numerical.DetectConstraintsWithStrings(values, strings)
@@ -20,3 +80,91 @@ func main() {
 	count, anomalies := nc.ValidateConstraints(values, strings)
 	numerical.PrintAnomalies(count, anomalies)
 }
+
+// explainCompression 以检测到的约束压缩并解压数据，打印每个阶段的跟踪信息
+func explainCompression(values []float64, backend string) {
+	tracer := common.NewWriterTracer(os.Stdout)
+	opts := numerical.Options{
+		Backend:     backend,
+		Constraints: numerical.DetectConstraints(values),
+		Tracer:      tracer,
+	}
+	fmt.Println("=== compress ===")
+	compressed, err := numerical.CompressFloatWithOptions(nil, values, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("total: %d -> %d bytes (%.3fx)\n", len(values)*8, len(compressed),
+		float64(len(values)*8)/float64(len(compressed)))
+
+	fmt.Println("=== decompress ===")
+	decompressed, err := numerical.DecompressFloatWithOptions(nil, compressed, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mismatch := 0
+	for i := range values {
+		if i >= len(decompressed) || decompressed[i] != values[i] {
+			mismatch++
+		}
+	}
+	fmt.Printf("recovered %d values, %d mismatches\n", len(decompressed), mismatch)
+}
+
+// trainSelector 切分数据、打标签、交叉验证并导出随机森林选择器
+func trainSelector(values []float64, out string, version uint16) {
+	opts := model.DefaultTrainOptions()
+	opts.Version = version
+	m, acc, err := model.TrainSelector(values, opts, out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mean := 0.0
+	for i, a := range acc {
+		fmt.Printf("fold %d: accuracy %.4f\n", i, a)
+		mean += a
+	}
+	if len(acc) > 0 {
+		fmt.Printf("cross validation: mean accuracy %.4f\n", mean/float64(len(acc)))
+	}
+	fmt.Printf("✅ 已导出选择器 v%d (%d trees, %d classes): %s\n", m.ModelVersion, len(m.Trees), len(m.Classes), out)
+}
+
+// reportPareto 输出所有参数组合中的 Pareto 前沿以及按目标选出的组合
+func reportPareto(values []float64, obj model.Objective) {
+	best, front := model.FindBestComboWithObjective(values, obj)
+	for _, m := range front {
+		fmt.Println(m)
+	}
+	fmt.Printf("best: %v\n", best)
+}
+
+// evaluateSelector 对比选择器与穷举搜索的结果并写出评估报告
+func evaluateSelector(values []float64, selectorPath, dir string) {
+	if selectorPath == "" {
+		log.Fatal("eval 模式需要用 -selector 指定模型文件")
+	}
+	m, err := model.LoadSelectorModel(selectorPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := model.EvaluateSelector(m, values, 114514)
+	if err := report.WriteReport(dir); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("segments %d, accuracy %.4f, regret %.4f\n", report.Segments, report.Accuracy, report.Regret)
+	fmt.Println("✅ 已写入评估报告:", dir)
+}
+
+// inferSemantics 输出推断出的语义信息，并用它压缩数据
+func inferSemantics(values []float64) {
+	compressed, err := myal.CompressInferred(nil, values, func(inf *myal.Inference) error {
+		fmt.Print(inf)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("total: %d -> %d bytes (%.3fx)\n", len(values)*8, len(compressed),
+		float64(len(values)*8)/float64(len(compressed)))
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"myalgo/algorithms/fpc"
 	"myalgo/algorithms/lz4"
 	"myalgo/algorithms/lzw"
+	"myalgo/algorithms/pfor"
 	"myalgo/algorithms/snappy"
 	"testing"
 	"time"
@@ -19,11 +21,12 @@ var testcases = []struct {
 
 	// {"chimp", chimp.Compress, chimp.Decompress},
 	// {"myalgo", myal.Compress, myal.Decompress},
-	// {"fpc", fpc.Compress, fpc.Decompress},
+	{"fpc", fpc.Compress, fpc.Decompress},
 	// {"gorillaz", gorillaz.Compress, gorillaz.Decompress},
 	{"lz4", lz4.Compress, lz4.Decompress},
 	{"lzw", lzw.Compress, lzw.Decompress},
 	{"snappy", snappy.Compress, snappy.Decompress},
+	{"pfor", pfor.Compress, pfor.Decompress},
 	// {"tsxor", tsxor.Compress, tsxor.Decompress},
 }
 
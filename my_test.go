@@ -5,11 +5,27 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"myalgo/algorithms/alp"
 	"myalgo/algorithms/brotli"
+	"myalgo/algorithms/chimp"
+	"myalgo/algorithms/chimp128"
+	"myalgo/algorithms/elf"
+	"myalgo/algorithms/fpc"
+	"myalgo/algorithms/gorillaz"
+	"myalgo/algorithms/huffman"
 	"myalgo/algorithms/lz4"
+	"myalgo/algorithms/lz77"
+	"myalgo/algorithms/model"
+	"myalgo/algorithms/myal"
 	"myalgo/algorithms/numerical"
+	"myalgo/algorithms/pongo"
+	"myalgo/algorithms/predict"
+	"myalgo/algorithms/rangeCoding"
+	"myalgo/algorithms/rle"
+	"myalgo/algorithms/shuffle"
 	"myalgo/algorithms/simple8b"
 	"myalgo/algorithms/snappy"
+	"myalgo/algorithms/split"
 	"myalgo/algorithms/xz"
 	"myalgo/algorithms/zstd"
 	"myalgo/common"
@@ -58,18 +74,43 @@ var testcase = []struct {
 	{"snappy", snappy.CompressFloat, snappy.DecompressFloat},
 	{"brotli", brotli.CompressFloat, brotli.DecompressFloat},
 	{"xz", xz.CompressFloat, xz.DecompressFloat},
+	{"lz77", lz77.CompressFloat, lz77.DecompressFloat},
+	{"lz77(aligned)", lz77.CompressFloatAligned, lz77.DecompressFloat},
 	{"numerical(zstd)", numerical.CompressFloat, numerical.DecompressFloat},
 	{"numerical(lz4)", numerical.CompressFloatLZ4, numerical.DecompressFloatLZ4},
 	{"numerical(snappy)", numerical.CompressFloatSnappy, numerical.DecompressFloatSnappy},
 	{"numerical(brotli)", numerical.CompressFloatBrotli, numerical.DecompressFloatBrotli},
 	{"numerical(xz)", numerical.CompressFloatXZ, numerical.DecompressFloatXZ},
-	// {"huffman", huffman.CompressFloat, huffman.DecompressFloat},
+	{"numerical(pfor)", numerical.CompressFloatPFOR, numerical.DecompressFloatPFOR},
+	{"model(auto)", model.CompressFloatAuto, model.DecompressFloat},
+	{"model(adaptive)", model.CompressFloatAdaptive, model.DecompressFloatAdaptive},
+	{"myal(infer)", myal.CompressFloat, myal.DecompressFloat},
+	{"alp", alp.CompressFloat, alp.DecompressFloat},
+	{"rangeCoding(adaptive)", rangeCoding.CompressFloatAdaptive, rangeCoding.DecompressFloatAdaptive},
+	{"rle+alp", rle.Hybrid{Compress: alp.CompressFloat, Decompress: alp.DecompressFloat}.CompressFloat, rle.Hybrid{Compress: alp.CompressFloat, Decompress: alp.DecompressFloat}.DecompressFloat},
+	{"rle+chimp128(entropy)", rle.Hybrid{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy}.CompressFloat, rle.Hybrid{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy}.DecompressFloat},
+	{"elf+gorilla", elf.Stage{Compress: gorillaz.CompressFloat, Decompress: gorillaz.DecompressFloat}.CompressFloat, elf.Stage{Compress: gorillaz.CompressFloat, Decompress: gorillaz.DecompressFloat}.DecompressFloat},
+	{"elf+chimp128(entropy)", elf.Stage{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy}.CompressFloat, elf.Stage{Compress: chimp128.CompressFloatEntropy, Decompress: chimp128.DecompressFloatEntropy}.DecompressFloat},
+	{"pongo", pongo.CompressFloat, pongo.DecompressFloat},
+	{"split", split.CompressFloat, split.DecompressFloat},
+	{"fcm+xor", predict.MustParse("fcm+xor").CompressFloat, predict.MustParse("fcm+xor").DecompressFloat},
+	{"linear+sub", predict.MustParse("linear+sub").CompressFloat, predict.MustParse("linear+sub").DecompressFloat},
+	{"seasonal24+xor", predict.MustParse("seasonal24+xor").CompressFloat, predict.MustParse("seasonal24+xor").DecompressFloat},
+	{"bss+zstd", shuffle.MustParse("bss+zstd").CompressFloat, shuffle.MustParse("bss+zstd").DecompressFloat},
+	{"bitshuffle+lz4", shuffle.MustParse("bitshuffle+lz4").CompressFloat, shuffle.MustParse("bitshuffle+lz4").DecompressFloat},
+	{"xor+bss+zstd", shuffle.MustParse("xor+bss+zstd").CompressFloat, shuffle.MustParse("xor+bss+zstd").DecompressFloat},
+	{"xor+bitshuffle+zstd", shuffle.MustParse("xor+bitshuffle+zstd").CompressFloat, shuffle.MustParse("xor+bitshuffle+zstd").DecompressFloat},
+	// 熵编码控制码的变体与对应的定长字段版本对比
+	{"gorilla", gorillaz.CompressFloat, gorillaz.DecompressFloat},
+	{"gorilla(entropy)", gorillaz.CompressFloatEntropy, gorillaz.DecompressFloatEntropy},
+	{"chimp", chimp.CompressFloat, chimp.DecompressFloat},
+	{"chimp(entropy)", chimp.CompressFloatEntropy, chimp.DecompressFloatEntropy},
+	{"chimp128", chimp128.CompressFloat, chimp128.DecompressFloat},
+	{"chimp128(entropy)", chimp128.CompressFloatEntropy, chimp128.DecompressFloatEntropy},
+	{"huffman", huffman.CompressFloat, huffman.DecompressFloat},
 	// {"elf", elf.CompressFloat, elf.DecompressFloat},
-	// {"chimp128", chimp128.CompressFloat, chimp128.DecompressFloat},
-	// {"chimp", chimp.CompressFloat, chimp.DecompressFloat},
-	// {"gorilla", gorillaz.CompressFloat, gorillaz.DecompressFloat},
 	// {"gorillaSub", gorillaz.CompressFloatSub, gorillaz.DecompressFloatSub},
-	// {"fpc", fpc.CompressFloat, fpc.DecompressFloat},
+	{"fpc", fpc.CompressFloat, fpc.DecompressFloat},
 	// {"model", model.CompressFloat, model.DecompressFloat},
 	// {"rule", rule.CompressFloat, rule.DecompressFloat},
 	// {"xor", xor.CompressFloat, xor.DecompressFloat},
@@ -337,7 +378,7 @@ func TestSimple8bDebug(t *testing.T) {
 	}
 
 	fmt.Println("\n=== 解压过程 ===")
-	decompressBuffer := make([]uint64, len(arr))
+	decompressBuffer := make([]uint64, 0, len(arr))
 	recovered, err := simple8b.Decompress(decompressBuffer, compressBytes)
 	if err != nil {
 		t.Fatal(err)